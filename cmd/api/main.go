@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/y-mitsuyoshi/go-face-blur-detector/internal/facedetector"
@@ -22,6 +26,14 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// 顔検出器を作成（モデルのプールはDetectorごとに保持される）
+	// ROTATION_RETRY=true の場合、顔が見つからない画像を回転して再検出する
+	var opts []facedetector.Option
+	if rotationRetry, _ := strconv.ParseBool(os.Getenv("ROTATION_RETRY")); rotationRetry {
+		opts = append(opts, facedetector.WithRotationRetry(true))
+	}
+	detector := facedetector.New(opts...)
+
 	// Ginルーターを作成
 	r := gin.Default()
 
@@ -61,10 +73,10 @@ func main() {
 			return
 		}
 
-		// 鮮明度を計算
-		result, err := facedetector.CalculateSharpness(imgData)
+		// 鮮明度を計算（クライアント切断時はリクエストのcontextで処理を打ち切る）
+		result, err := detector.CalculateSharpnessContext(c.Request.Context(), imgData, analysisOptions(c)...)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "鮮明度の計算に失敗しました: " + err.Error()})
+			c.JSON(errorStatus(err), gin.H{"error": "鮮明度の計算に失敗しました: " + err.Error()})
 			return
 		}
 
@@ -90,9 +102,9 @@ func main() {
 		}
 
 		// 顔の鮮明度を計算
-		result, err := facedetector.CalculateFaceSharpness(imgData)
+		result, err := detector.CalculateFaceSharpnessContext(c.Request.Context(), imgData, analysisOptions(c)...)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "鮮明度の計算に失敗しました: " + err.Error()})
+			c.JSON(errorStatus(err), gin.H{"error": "鮮明度の計算に失敗しました: " + err.Error()})
 			return
 		}
 
@@ -100,6 +112,101 @@ func main() {
 		c.JSON(http.StatusOK, result)
 	})
 
+	// 顔画像の総合品質スコアエンドポイント（鮮明度・露出・姿勢・大きさ・目の間隔・遮蔽・照明）
+	r.POST("/quality", func(c *gin.Context) {
+		// multipart/form-dataから画像ファイルを取得
+		file, _, err := c.Request.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "画像ファイルの取得に失敗しました: " + err.Error()})
+			return
+		}
+		defer file.Close()
+
+		// ファイルの内容を読み込む
+		imgData, err := io.ReadAll(file)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "画像の読み込みに失敗しました: " + err.Error()})
+			return
+		}
+
+		// 品質スコアを計算
+		result, err := detector.AssessFaceQualityContext(c.Request.Context(), imgData, analysisOptions(c)...)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": "品質スコアの計算に失敗しました: " + err.Error()})
+			return
+		}
+
+		// 結果を返す
+		c.JSON(http.StatusOK, result)
+	})
+
+	// 複数顔の鮮明度エンドポイント（顔ごとの結果と集計値）
+	r.POST("/detect/faces", func(c *gin.Context) {
+		// multipart/form-dataから画像ファイルを取得
+		file, _, err := c.Request.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "画像ファイルの取得に失敗しました: " + err.Error()})
+			return
+		}
+		defer file.Close()
+
+		// ファイルの内容を読み込む
+		imgData, err := io.ReadAll(file)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "画像の読み込みに失敗しました: " + err.Error()})
+			return
+		}
+
+		// 全ての顔の鮮明度を計算
+		result, err := detector.CalculateAllFacesSharpnessContext(c.Request.Context(), imgData, analysisOptions(c)...)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": "鮮明度の計算に失敗しました: " + err.Error()})
+			return
+		}
+
+		// 結果を返す
+		c.JSON(http.StatusOK, result)
+	})
+
+	// 鮮明度ヒートマップのエンドポイント（タイルごとの鮮明度）
+	r.POST("/detect/heatmap", func(c *gin.Context) {
+		outputType := c.DefaultQuery("output", "json") // "json" or "png"
+
+		file, _, err := c.Request.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "画像ファイルの取得に失敗しました: " + err.Error()})
+			return
+		}
+		defer file.Close()
+
+		imgData, err := io.ReadAll(file)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "画像の読み込みに失敗しました: " + err.Error()})
+			return
+		}
+
+		switch outputType {
+		case "json":
+			heatmap, err := detector.CalculateSharpnessHeatmapContext(c.Request.Context(), imgData)
+			if err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": "ヒートマップの計算に失敗しました: " + err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, heatmap)
+		case "png":
+			resultImage, err := detector.DrawSharpnessHeatmapContext(c.Request.Context(), imgData)
+			if err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": "ヒートマップの計算に失敗しました: " + err.Error()})
+				return
+			}
+			// 出力画像は元画像のEXIFの向きを適用済みのため、適用した向きをヘッダーで返す
+			c.Header("X-Image-Orientation", strconv.Itoa(int(facedetector.ReadOrientation(imgData))))
+			c.Data(http.StatusOK, "image/png", resultImage)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無効なoutputタイプが指定されました。'json' または 'png' を使用してください。"})
+		}
+	})
+
 	// 顔検出の可視化エンドポイント
 	r.POST("/detect/face/visualize", func(c *gin.Context) {
 		outputType := c.DefaultQuery("output", "box") // "box" or "crop"
@@ -122,19 +229,21 @@ func main() {
 
 		switch outputType {
 		case "box":
-			resultImage, procErr = facedetector.DrawFaceRects(imgData)
+			resultImage, procErr = detector.DrawFaceRectsContext(c.Request.Context(), imgData)
 		case "crop":
-			resultImage, procErr = facedetector.CropFace(imgData)
+			resultImage, procErr = detector.CropFaceContext(c.Request.Context(), imgData)
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": "無効なoutputタイプが指定されました。'box' または 'crop' を使用してください。"})
 			return
 		}
 
 		if procErr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "顔検出または画像処理に失敗しました: " + procErr.Error()})
+			c.JSON(errorStatus(procErr), gin.H{"error": "顔検出または画像処理に失敗しました: " + procErr.Error()})
 			return
 		}
 
+		// 出力画像は元画像のEXIFの向きを適用済みのため、適用した向きをヘッダーで返す
+		c.Header("X-Image-Orientation", strconv.Itoa(int(facedetector.ReadOrientation(imgData))))
 		c.Data(http.StatusOK, "image/png", resultImage)
 	})
 
@@ -143,3 +252,61 @@ func main() {
 		log.Fatal("サーバーの起動に失敗しました:", err)
 	}
 }
+
+// analysisOptions はクエリパラメータから解析オプションを組み立てます。
+//   - metrics: カンマ区切りの鮮明度指標名（例: ?metrics=edge_decay,tenengrad）
+//   - basis: normalized_score の算出に使用する鮮明度指標名（例: ?basis=frequency）
+//   - score_region: eyes を指定すると normalized_score を目の領域で判定（例: ?score_region=eyes）
+//   - frontal_only: true を指定すると正面を向いていない顔を除外（例: ?frontal_only=true）
+func analysisOptions(c *gin.Context) []facedetector.AnalysisOption {
+	var opts []facedetector.AnalysisOption
+	if metrics := splitQuery(c.Query("metrics")); len(metrics) > 0 {
+		opts = append(opts, facedetector.WithMetrics(metrics...))
+	}
+	if basis := strings.TrimSpace(c.Query("basis")); basis != "" {
+		opts = append(opts, facedetector.WithScoreBasis(basis))
+	}
+	if strings.TrimSpace(c.Query("score_region")) == string(facedetector.ScoreRegionEyes) {
+		opts = append(opts, facedetector.WithEyeRegionScore())
+	}
+	if frontalOnly, _ := strconv.ParseBool(c.Query("frontal_only")); frontalOnly {
+		opts = append(opts, facedetector.WithFrontalOnly())
+	}
+	return opts
+}
+
+// splitQuery はカンマ区切りのクエリパラメータを分割し、空の要素を除いて返します。
+func splitQuery(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// statusClientClosedRequest はクライアントが応答前に切断したことを示すステータスコード（nginx互換）
+const statusClientClosedRequest = 499
+
+// errorStatus はfacedetectorのエラーをHTTPステータスコードに対応付けます。
+//   - 入力画像・パラメータの問題（空・デコード失敗・不明な指標）: 400
+//   - 画像は正しいが顔が処理できない（未検出・小さすぎる・正面を向いていない）: 422
+//   - クライアント切断: 499、タイムアウト: 504
+//   - モデル未配置などサーバー側の問題: 500
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, facedetector.ErrEmptyImage), errors.Is(err, facedetector.ErrDecode),
+		errors.Is(err, facedetector.ErrUnknownMetric):
+		return http.StatusBadRequest
+	case errors.Is(err, facedetector.ErrNoFace), errors.Is(err, facedetector.ErrFaceTooSmall),
+		errors.Is(err, facedetector.ErrNotFrontal):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
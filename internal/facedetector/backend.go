@@ -0,0 +1,426 @@
+package facedetector
+
+import (
+	"context"
+	"image"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================================
+// 顔検出バックエンド
+// ============================================================================
+
+// FaceDetector は顔検出バックエンドのインタフェースです。
+// DetectorはWithBackendsで指定された順にバックエンドを試行し、
+// 最初に顔を検出したバックエンドの結果を採用します。
+//
+// Detectには前処理済み画像・元画像・シャープ化画像などBGRのカラー画像が渡されます。
+// 返す矩形は渡された画像の座標系とし、Rect・Confidence・Sourceを設定してください。
+// Phaseを空にした場合はDetectorが検出段階を設定します。
+type FaceDetector interface {
+	Detect(mat gocv.Mat) []Face
+}
+
+// ContextFaceDetector はctxのキャンセルに対応したFaceDetectorです。
+// 実装している場合、DetectorはDetectの代わりにDetectContextを呼び出します。
+type ContextFaceDetector interface {
+	FaceDetector
+	DetectContext(ctx context.Context, mat gocv.Mat) []Face
+}
+
+// availabler はモデルが読み込み可能かを報告できるバックエンドです。
+// 実装していないバックエンドは常に利用可能とみなします。
+type availabler interface {
+	Available() bool
+}
+
+// rawRetrier は前処理済み画像で見つからない場合に元画像でも試行するかを報告できるバックエンドです。
+// 実装していないバックエンドは元画像でも試行します。
+type rawRetrier interface {
+	retryOnRaw() bool
+}
+
+// sharpenedDetector はシャープ化画像での再検出に専用の検出パスを持つバックエンドです。
+// 実装していないバックエンドはシャープ化画像にも通常のDetectを使用します。
+type sharpenedDetector interface {
+	detectSharpened(ctx context.Context, mat gocv.Mat) []Face
+}
+
+// retriesOnRaw はバックエンドを元画像でも試行するかを返します。
+func retriesOnRaw(backend FaceDetector) bool {
+	if r, ok := backend.(rawRetrier); ok {
+		return r.retryOnRaw()
+	}
+	return true
+}
+
+// detectSharpenedWith はシャープ化画像での再検出を実行します。
+// バックエンドがsharpenedDetectorを実装していれば専用の検出パスを、そうでなければdetectWithを使用します。
+func detectSharpenedWith(ctx context.Context, backend FaceDetector, mat gocv.Mat) []Face {
+	if sd, ok := backend.(sharpenedDetector); ok {
+		return sd.detectSharpened(ctx, mat)
+	}
+	return detectWith(ctx, backend, mat)
+}
+
+// detectWith はバックエンドがContextFaceDetectorを実装していればDetectContextを、
+// そうでなければDetectを呼び出します。
+func detectWith(ctx context.Context, backend FaceDetector, mat gocv.Mat) []Face {
+	if cd, ok := backend.(ContextFaceDetector); ok {
+		return cd.DetectContext(ctx, mat)
+	}
+	return backend.Detect(mat)
+}
+
+// ============================================================================
+// DNN ベースの顔検出
+// ============================================================================
+
+// DNNDetector はSSD ResNet-10（Caffe）モデルによる顔検出バックエンドです。
+// ネットワークはsync.Poolで再利用されるため、複数のゴルーチンから同時に使用できます。
+type DNNDetector struct {
+	protoPath     string
+	modelPath     string
+	minConfidence float32
+	minFaceSize   int
+
+	netPool     sync.Pool
+	initLogOnce sync.Once
+}
+
+// NewDNNDetector はprototxtとcaffemodelのパスを指定してDNNDetectorを生成します。
+// minConfidence以下の検出とminFaceSize未満の矩形は除外されます。
+// パスが空またはファイルが存在しない場合、Availableはfalseを返し、Detectは常に空を返します。
+func NewDNNDetector(protoPath, modelPath string, minConfidence float32, minFaceSize int) *DNNDetector {
+	d := &DNNDetector{
+		minConfidence: minConfidence,
+		minFaceSize:   minFaceSize,
+	}
+	if protoPath != "" && modelPath != "" && fileExists(protoPath) && fileExists(modelPath) {
+		d.protoPath, d.modelPath = protoPath, modelPath
+	}
+
+	d.netPool = sync.Pool{
+		New: func() interface{} {
+			if d.protoPath == "" {
+				return nil
+			}
+			net := gocv.ReadNetFromCaffe(d.protoPath, d.modelPath)
+			if net.Empty() {
+				net.Close()
+				return nil
+			}
+			return &net
+		},
+	}
+	return d
+}
+
+// Available はDNNモデルファイルが見つかっているかを返します。
+func (d *DNNDetector) Available() bool {
+	return d.protoPath != ""
+}
+
+// Detect はSSD DNN モデルを使用して顔を検出します。
+func (d *DNNDetector) Detect(mat gocv.Mat) []Face {
+	// 初回実行時に診断ログを出力
+	d.initLogOnce.Do(func() {
+		if d.protoPath != "" {
+			log.Printf("[FaceDetector] DNN models found. Using SSD ResNet-10 (Caffe). Proto: %s, Model: %s\n", d.protoPath, d.modelPath)
+		} else {
+			log.Println("[FaceDetector] WARNING: DNN models NOT found. Falling back to Haar Cascades.")
+		}
+	})
+
+	netVal := d.netPool.Get()
+	if netVal == nil {
+		return nil
+	}
+	netPtr := netVal.(*gocv.Net)
+	if netPtr.Empty() {
+		return nil
+	}
+	defer d.netPool.Put(netPtr)
+
+	return runDNNInference(*netPtr, mat, d.minConfidence, d.minFaceSize)
+}
+
+// runDNNInference は単一の画像に対してDNN推論を実行します。
+func runDNNInference(net gocv.Net, mat gocv.Mat, minConfidence float32, minFaceSize int) []Face {
+	// SSD モデルの入力サイズは300x300
+	blob := gocv.BlobFromImage(mat, 1.0, image.Point{X: 300, Y: 300},
+		gocv.NewScalar(104, 177, 123, 0), false, false)
+	defer blob.Close()
+
+	net.SetInput(blob, "")
+	prob := net.Forward("")
+	defer prob.Close()
+
+	detections := gocv.GetBlobChannel(prob, 0, 0)
+	defer detections.Close()
+
+	var results []Face
+	for i := 0; i < detections.Rows(); i++ {
+		confidence := detections.GetFloatAt(i, 2)
+		if confidence > minConfidence {
+			left := float64(detections.GetFloatAt(i, 3)) * float64(mat.Cols())
+			top := float64(detections.GetFloatAt(i, 4)) * float64(mat.Rows())
+			right := float64(detections.GetFloatAt(i, 5)) * float64(mat.Cols())
+			bottom := float64(detections.GetFloatAt(i, 6)) * float64(mat.Rows())
+
+			rect := image.Rect(int(left), int(top), int(right), int(bottom))
+			// 画像境界内にクリップ
+			rect = clipRect(rect, image.Rect(0, 0, mat.Cols(), mat.Rows()))
+
+			if rect.Dx() >= minFaceSize && rect.Dy() >= minFaceSize {
+				results = append(results, Face{
+					Rect:       rect,
+					Confidence: confidence,
+					Source:     SourceDNN,
+				})
+			}
+		}
+	}
+
+	return results
+}
+
+// ============================================================================
+// Haar Cascade ベースの顔検出
+// ============================================================================
+
+// cascadePoolSet はカスケードファイルごとの分類器プールを保持します。
+type cascadePoolSet struct {
+	mu    sync.RWMutex
+	pools map[string]*sync.Pool
+}
+
+func newCascadePoolSet() *cascadePoolSet {
+	return &cascadePoolSet{pools: make(map[string]*sync.Pool)}
+}
+
+func (s *cascadePoolSet) get(cascadeFile string) *sync.Pool {
+	s.mu.RLock()
+	pool, ok := s.pools[cascadeFile]
+	s.mu.RUnlock()
+	if ok {
+		return pool
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// ダブルチェック
+	pool, ok = s.pools[cascadeFile]
+	if ok {
+		return pool
+	}
+
+	pool = &sync.Pool{
+		New: func() interface{} {
+			classifier := gocv.NewCascadeClassifier()
+			if !classifier.Load(cascadeFile) {
+				classifier.Close()
+				return nil
+			}
+			return &classifier
+		},
+	}
+	s.pools[cascadeFile] = pool
+	return pool
+}
+
+// haarUpscaleFactors はHaarDetectorが見つからない場合に試行する多スケール検出の拡大率（昇順）です。
+// 拡大画像でもminSizeはminFaceSizeのため、元の画像ではminFaceSize/拡大率の大きさの顔まで検出されます。
+var haarUpscaleFactors = []int{2, 3}
+
+// HaarDetector はHaar Cascade分類器による顔検出バックエンドです。
+// 複数のカスケードファイルを先頭から順に試行し、顔が見つかった分類器の結果を返します。
+// 通常のパラメータで見つからない場合は、minNeighborsの緩和と2倍/3倍の多スケール検出
+// （低解像度画像対応）を順に試みます。
+type HaarDetector struct {
+	cascadeFiles []string
+	minNeighbors int
+	minFaceSize  int
+
+	pools *cascadePoolSet
+}
+
+// NewHaarDetector はカスケードファイルのリストを指定してHaarDetectorを生成します。
+// minFaceSize未満の矩形は検出されません。
+func NewHaarDetector(cascadeFiles []string, minFaceSize int) *HaarDetector {
+	return &HaarDetector{
+		cascadeFiles: append([]string(nil), cascadeFiles...),
+		minNeighbors: 4,
+		minFaceSize:  minFaceSize,
+		pools:        newCascadePoolSet(),
+	}
+}
+
+// Available はいずれかのカスケードファイルが読み込み可能かを返します。
+func (h *HaarDetector) Available() bool {
+	for _, cascadeFile := range h.cascadeFiles {
+		pool := h.pools.get(cascadeFile)
+		if classVal := pool.Get(); classVal != nil {
+			pool.Put(classVal)
+			return true
+		}
+	}
+	return false
+}
+
+// Detect はHaar Cascade分類器で顔を検出します。
+func (h *HaarDetector) Detect(mat gocv.Mat) []Face {
+	return h.DetectContext(context.Background(), mat)
+}
+
+// DetectContext はctxのキャンセルに対応したDetectです。
+// 検出パスの間とカスケード分類器の切り替え時にキャンセルを確認します。
+func (h *HaarDetector) DetectContext(ctx context.Context, mat gocv.Mat) []Face {
+	// グレースケールに変換
+	grayMat := gocv.NewMat()
+	defer grayMat.Close()
+	if mat.Channels() == 1 {
+		mat.CopyTo(&grayMat)
+	} else {
+		gocv.CvtColor(mat, &grayMat, gocv.ColorBGRToGray)
+	}
+
+	// ガウシアンブラーでノイズを軽減
+	blurredMat := gocv.NewMat()
+	defer blurredMat.Close()
+	gocv.GaussianBlur(grayMat, &blurredMat, image.Point{X: 5, Y: 5}, 0, 0, gocv.BorderDefault)
+
+	// 通常パラメータで検出
+	faces := h.detectWithCascades(ctx, blurredMat, h.minNeighbors)
+	if len(faces) > 0 || ctx.Err() != nil {
+		return faces
+	}
+
+	// 見つからなければパラメータを緩和して再試行
+	relaxed := h.relaxedNeighbors()
+	faces = h.detectWithCascades(ctx, blurredMat, relaxed)
+	if len(faces) > 0 || ctx.Err() != nil {
+		return faces
+	}
+
+	// 多スケール検出（低解像度画像対応）
+	for _, scale := range haarUpscaleFactors {
+		upscaled := gocv.NewMat()
+		gocv.Resize(blurredMat, &upscaled, image.Point{
+			X: blurredMat.Cols() * scale,
+			Y: blurredMat.Rows() * scale,
+		}, 0, 0, gocv.InterpolationCubic) // Cubicで高品質アップスケール
+
+		upFaces := h.detectWithCascades(ctx, upscaled, relaxed)
+		upscaled.Close()
+
+		// 座標を元のスケールに戻す
+		for i := range upFaces {
+			upFaces[i].Rect.Min.X /= scale
+			upFaces[i].Rect.Min.Y /= scale
+			upFaces[i].Rect.Max.X /= scale
+			upFaces[i].Rect.Max.Y /= scale
+			upFaces[i].Phase = PhaseUpscaled
+		}
+
+		if len(upFaces) > 0 || ctx.Err() != nil {
+			return upFaces
+		}
+	}
+
+	return nil
+}
+
+// retryOnRaw はfalseを返します。
+// 前処理済み画像で見つからない場合は、DetectContextの中で緩和・多スケール検出を行うため元画像では再試行しません。
+func (h *HaarDetector) retryOnRaw() bool {
+	return false
+}
+
+// detectSharpened はシャープ化画像に対して、緩和したminNeighborsでの検出を1回だけ実行します。
+// シャープ化で強調したエッジを残すため、ガウシアンブラーは適用しません。
+func (h *HaarDetector) detectSharpened(ctx context.Context, mat gocv.Mat) []Face {
+	grayMat := gocv.NewMat()
+	defer grayMat.Close()
+	if mat.Channels() == 1 {
+		mat.CopyTo(&grayMat)
+	} else {
+		gocv.CvtColor(mat, &grayMat, gocv.ColorBGRToGray)
+	}
+	return h.detectWithCascades(ctx, grayMat, h.relaxedNeighbors())
+}
+
+// relaxedNeighbors は再試行時に使用する緩和したminNeighbors（1以上）を返します。
+func (h *HaarDetector) relaxedNeighbors() int {
+	if h.minNeighbors > 1 {
+		return h.minNeighbors - 1
+	}
+	return 1
+}
+
+// detectWithCascades は複数のカスケード分類器で顔検出を実行します。
+// ctxがキャンセルされた場合は、次の分類器に進まずにその時点までの結果を返します。
+func (h *HaarDetector) detectWithCascades(ctx context.Context, mat gocv.Mat, minNeighbors int) []Face {
+	var allFaces []Face
+
+	for _, cascadeFile := range h.cascadeFiles {
+		if ctx.Err() != nil {
+			break
+		}
+
+		source := cascadeSource(cascadeFile)
+		found := false
+		_ = func() error {
+			pool := h.pools.get(cascadeFile)
+			classVal := pool.Get()
+			if classVal == nil {
+				return nil
+			}
+			classifierPtr := classVal.(*gocv.CascadeClassifier)
+			defer pool.Put(classifierPtr)
+
+			rects := classifierPtr.DetectMultiScaleWithParams(
+				mat,
+				1.05,         // scaleFactor: 小さい値ほど検出漏れが減るが遅くなる
+				minNeighbors, // minNeighbors: 低いほど検出しやすいが誤検出が増える
+				0,            // flags
+				image.Point{X: h.minFaceSize, Y: h.minFaceSize}, // minSize
+				image.Point{}, // maxSize: 制限なし
+			)
+
+			for _, r := range rects {
+				allFaces = append(allFaces, Face{
+					Rect:       r,
+					Confidence: 0, // Haar Cascadeは信頼度スコアを返さない
+					Source:     source,
+				})
+			}
+
+			if len(rects) > 0 {
+				found = true
+			}
+			return nil
+		}()
+
+		// 何か見つかったらそのカスケードで十分
+		if found {
+			break
+		}
+	}
+
+	return allFaces
+}
+
+// cascadeSource はカスケードファイルで検出した顔のSourceを返します。
+// 横顔用の分類器（ファイル名に profileface を含む）はSourceProfileCascade、それ以外はSourceCascadeです。
+func cascadeSource(cascadeFile string) DetectorSource {
+	if strings.Contains(filepath.Base(cascadeFile), "profileface") {
+		return SourceProfileCascade
+	}
+	return SourceCascade
+}
@@ -0,0 +1,228 @@
+package facedetector
+
+import (
+	"context"
+	"image"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+// stubDetector は固定の矩形を返すテスト用のバックエンドです。
+type stubDetector struct {
+	rects []image.Rectangle
+	calls int32
+}
+
+func (s *stubDetector) Detect(mat gocv.Mat) []Face {
+	atomic.AddInt32(&s.calls, 1)
+	var faces []Face
+	for _, r := range s.rects {
+		faces = append(faces, Face{Rect: r, Confidence: 0.9, Source: "stub"})
+	}
+	return faces
+}
+
+func TestNew_DefaultBackends(t *testing.T) {
+	d := New()
+	if len(d.backends) != 2 {
+		t.Fatalf("Expected 2 default backends, got %d", len(d.backends))
+	}
+	if _, ok := d.backends[0].(*DNNDetector); !ok {
+		t.Errorf("Expected first backend to be *DNNDetector, got %T", d.backends[0])
+	}
+	if _, ok := d.backends[1].(*HaarDetector); !ok {
+		t.Errorf("Expected second backend to be *HaarDetector, got %T", d.backends[1])
+	}
+}
+
+func TestNew_WithoutCascadeFallback(t *testing.T) {
+	d := New(WithCascadeFallback(false))
+	if len(d.backends) != 1 {
+		t.Fatalf("Expected 1 backend, got %d", len(d.backends))
+	}
+	if _, ok := d.backends[0].(*DNNDetector); !ok {
+		t.Errorf("Expected *DNNDetector, got %T", d.backends[0])
+	}
+}
+
+func TestDetectFaces_CustomBackend(t *testing.T) {
+	stub := &stubDetector{rects: []image.Rectangle{image.Rect(40, 40, 140, 150)}}
+	d := New(WithBackends(stub))
+
+	faces, err := d.DetectFaces(uniformPNG(t, 200, 200))
+	if err != nil {
+		t.Fatalf("DetectFaces failed: %v", err)
+	}
+	if len(faces) != 1 {
+		t.Fatalf("Expected 1 face, got %d", len(faces))
+	}
+
+	f := faces[0]
+	if f.Rect != stub.rects[0] {
+		t.Errorf("Rect = %v, want %v", f.Rect, stub.rects[0])
+	}
+	if f.Source != "stub" {
+		t.Errorf("Source = %q, want %q", f.Source, "stub")
+	}
+	if f.Phase != PhasePreprocessed {
+		t.Errorf("Phase = %q, want %q", f.Phase, PhasePreprocessed)
+	}
+}
+
+func TestDetectFaces_BackendChainOrder(t *testing.T) {
+	empty := &stubDetector{}
+	second := &stubDetector{rects: []image.Rectangle{image.Rect(40, 40, 140, 150)}}
+	third := &stubDetector{rects: []image.Rectangle{image.Rect(10, 10, 60, 60)}}
+	d := New(WithBackends(empty, second, third))
+
+	faces, err := d.DetectFaces(uniformPNG(t, 200, 200))
+	if err != nil {
+		t.Fatalf("DetectFaces failed: %v", err)
+	}
+	if len(faces) != 1 || faces[0].Rect != second.rects[0] {
+		t.Fatalf("Expected the second backend's face, got %+v", faces)
+	}
+
+	// 先頭バックエンドは前処理済み画像・元画像・交差検証で呼ばれる
+	if calls := atomic.LoadInt32(&empty.calls); calls != 3 {
+		t.Errorf("Expected first backend to be called 3 times, got %d", calls)
+	}
+	if calls := atomic.LoadInt32(&third.calls); calls != 0 {
+		t.Errorf("Expected third backend not to be called, got %d calls", calls)
+	}
+}
+
+func TestDetectFaces_UnavailableBackends(t *testing.T) {
+	d := New(WithBackends(
+		NewDNNDetector("testdata/missing.prototxt", "testdata/missing.caffemodel", 0.25, 20),
+		NewHaarDetector([]string{"testdata/missing.xml"}, 20),
+	))
+
+	if d.modelsAvailable() {
+		t.Error("Expected no available backend")
+	}
+}
+
+// passStub は元画像での再試行とシャープ化画像の専用パスを持つテスト用のバックエンドです（HaarDetectorの代わり）。
+type passStub struct {
+	detectCalls    int32
+	sharpenedCalls int32
+	order          *[]string
+}
+
+func (p *passStub) Detect(mat gocv.Mat) []Face {
+	atomic.AddInt32(&p.detectCalls, 1)
+	return nil
+}
+
+func (p *passStub) retryOnRaw() bool { return false }
+
+func (p *passStub) detectSharpened(ctx context.Context, mat gocv.Mat) []Face {
+	atomic.AddInt32(&p.sharpenedCalls, 1)
+	*p.order = append(*p.order, "dedicated")
+	return nil
+}
+
+// orderStub はシャープ化画像で呼ばれた順序を記録するテスト用のバックエンドです。
+type orderStub struct {
+	calls int32
+	order *[]string
+}
+
+func (o *orderStub) Detect(mat gocv.Mat) []Face {
+	if atomic.AddInt32(&o.calls, 1) == 3 {
+		*o.order = append(*o.order, "generic")
+	}
+	return nil
+}
+
+func TestDetectFaces_BackendPasses(t *testing.T) {
+	var order []string
+	generic := &orderStub{order: &order}
+	dedicated := &passStub{order: &order}
+	d := New(WithBackends(generic, dedicated))
+
+	// 一様な画像は顔が見つからず、ブレが大きいためシャープ化画像で再検出される
+	faces, err := d.DetectFaces(uniformPNG(t, 200, 200))
+	if err != nil {
+		t.Fatalf("DetectFaces failed: %v", err)
+	}
+	if len(faces) != 0 {
+		t.Fatalf("Expected no faces, got %+v", faces)
+	}
+
+	// 元画像で再試行しないバックエンドは前処理済み画像で1回のみ、シャープ化画像では専用パスのみ
+	if calls := atomic.LoadInt32(&dedicated.detectCalls); calls != 1 {
+		t.Errorf("Expected Detect to be called once, got %d", calls)
+	}
+	if calls := atomic.LoadInt32(&dedicated.sharpenedCalls); calls != 1 {
+		t.Errorf("Expected detectSharpened to be called once, got %d", calls)
+	}
+	// 通常のバックエンドは前処理済み画像・元画像・シャープ化画像の3回
+	if calls := atomic.LoadInt32(&generic.calls); calls != 3 {
+		t.Errorf("Expected generic backend to be called 3 times, got %d", calls)
+	}
+	if want := []string{"dedicated", "generic"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Sharpened pass order = %v, want %v", order, want)
+	}
+}
+
+// upscaledDetector はHaarDetectorの多スケール検出と同じく、拡大画像で見つけて元の大きさに戻した
+// 最小サイズ未満の顔を返すテスト用のバックエンドです。
+type upscaledDetector struct {
+	rect image.Rectangle
+}
+
+func (u *upscaledDetector) Detect(mat gocv.Mat) []Face {
+	return []Face{{Rect: u.rect, Source: SourceCascade, Phase: PhaseUpscaled}}
+}
+
+func TestDetectFaces_UpscaledSmallFace(t *testing.T) {
+	// 拡大率3の検出で見つかる、minFaceSize（20）未満の顔
+	small := image.Rect(40, 40, 54, 54)
+	d := New(WithBackends(&upscaledDetector{rect: small}))
+
+	faces, err := d.DetectFaces(uniformPNG(t, 200, 200))
+	if err != nil {
+		t.Fatalf("DetectFaces failed: %v", err)
+	}
+	if len(faces) != 1 || faces[0].Rect != small || faces[0].Phase != PhaseUpscaled {
+		t.Fatalf("Expected the upscaled small face %v, got %+v", small, faces)
+	}
+}
+
+func TestHaarDetector_RelaxedNeighbors(t *testing.T) {
+	h := NewHaarDetector(nil, 20)
+	if got := h.relaxedNeighbors(); got != 3 {
+		t.Errorf("relaxedNeighbors = %d, want 3", got)
+	}
+	h.minNeighbors = 1
+	if got := h.relaxedNeighbors(); got != 1 {
+		t.Errorf("relaxedNeighbors = %d, want 1", got)
+	}
+	if retriesOnRaw(h) {
+		t.Error("Expected HaarDetector not to retry on the raw image")
+	}
+	if !retriesOnRaw(&stubDetector{}) {
+		t.Error("Expected backends without retryOnRaw to retry on the raw image")
+	}
+}
+
+func TestCascadeSource(t *testing.T) {
+	tests := []struct {
+		file string
+		want DetectorSource
+	}{
+		{"/usr/share/opencv4/haarcascades/haarcascade_frontalface_alt2.xml", SourceCascade},
+		{"/usr/share/opencv4/haarcascades/haarcascade_profileface.xml", SourceProfileCascade},
+		{"cascade/haarcascade_frontalface_alt2.xml", SourceCascade},
+	}
+	for _, tt := range tests {
+		if got := cascadeSource(tt.file); got != tt.want {
+			t.Errorf("cascadeSource(%q) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
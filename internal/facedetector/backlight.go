@@ -0,0 +1,96 @@
+package facedetector
+
+import (
+	"image"
+	"math"
+)
+
+// ============================================================================
+// 逆光の判定
+// ============================================================================
+
+// backlightRingMargin は顔の周囲の輝度を測るリングの幅（顔矩形に対する割合）
+const backlightRingMargin = 0.5
+
+// Backlight は顔・顔の周囲・画像全体の輝度の比較による逆光の判定結果です。
+// 検出前のガンマ補正は逆光でも顔を見つけられるように行われますが、その結果は呼び出し側に返らないため、
+// 逆光を「向きを変えてください」と案内するために使用します。
+type Backlight struct {
+	// Backlit は逆光かどうか。顔の周囲が顔よりContrastRatio倍以上明るく、
+	// かつ顔が画像全体の平均より暗い場合にtrueになります。
+	Backlit bool `json:"backlit"`
+
+	// FaceLuminance は顔の中心領域の平均輝度（0〜255）。
+	FaceLuminance float64 `json:"face_luminance"`
+
+	// SurroundLuminance は顔の周囲（顔矩形を50%広げた範囲から顔矩形を除いたリング）の平均輝度。
+	SurroundLuminance float64 `json:"surround_luminance"`
+
+	// FrameLuminance は画像全体の平均輝度。
+	FrameLuminance float64 `json:"frame_luminance"`
+
+	// ContrastRatio は顔の周囲と顔の輝度の比（SurroundLuminance / FaceLuminance）。
+	ContrastRatio float64 `json:"contrast_ratio"`
+}
+
+// analyzeBacklight は顔の中心領域・顔の周囲のリング・画像全体の平均輝度を比較して逆光を判定します。
+// gray は顔と周囲のリングを含むグレースケール画像（uprightFaceFrame）、frameLuminance は画像全体の平均輝度です。
+func (d *Detector) analyzeBacklight(gray [][]float64, frameLuminance float64, faceRect image.Rectangle) Backlight {
+	bounds := image.Rect(0, 0, 0, 0)
+	if len(gray) > 0 {
+		bounds = image.Rect(0, 0, len(gray[0]), len(gray))
+	}
+
+	faceRect = clipRect(faceRect, bounds)
+	center := shrinkRect(faceRect, d.cfg.faceCenterRatio)
+	outer := clipRect(addMargin(faceRect, backlightRingMargin), bounds)
+
+	faceSum, faceCount := regionSum(gray, center)
+	faceRectSum, faceRectCount := regionSum(gray, faceRect)
+	outerSum, outerCount := regionSum(gray, outer)
+
+	b := Backlight{FrameLuminance: math.Round(frameLuminance*10) / 10}
+	if faceCount == 0 {
+		return b
+	}
+	face := faceSum / float64(faceCount)
+	b.FaceLuminance = math.Round(face*10) / 10
+
+	// 顔が画像の端にあり周囲のリングがない場合は、画像全体と比較する
+	surround := frameLuminance
+	if ringCount := outerCount - faceRectCount; ringCount > 0 {
+		surround = (outerSum - faceRectSum) / float64(ringCount)
+	}
+	b.SurroundLuminance = math.Round(surround*10) / 10
+
+	// 真っ黒な顔でも比が発散しないよう、輝度に1を加えて比を取る
+	ratio := (surround + 1) / (face + 1)
+	b.ContrastRatio = math.Round(ratio*100) / 100
+	b.Backlit = ratio >= d.cfg.backlightRatio && face < frameLuminance
+	return b
+}
+
+// shrinkRect は矩形を中心を保ったまま幅・高さを ratio 倍に縮小します。
+// 画像外にクリップされて空になった矩形は空の矩形を返します。
+func shrinkRect(rect image.Rectangle, ratio float64) image.Rectangle {
+	if rect.Empty() {
+		return image.Rectangle{}
+	}
+	mx := int(float64(rect.Dx()) * (1 - ratio) / 2)
+	my := int(float64(rect.Dy()) * (1 - ratio) / 2)
+	return image.Rect(rect.Min.X+mx, rect.Min.Y+my, rect.Max.X-mx, rect.Max.Y-my)
+}
+
+// regionSum は2D配列の矩形領域の輝度の合計と画素数を返します。
+func regionSum(gray [][]float64, rect image.Rectangle) (float64, int) {
+	if rect.Empty() {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, row := range subRegion(gray, rect) {
+		for _, v := range row {
+			sum += v
+		}
+	}
+	return sum, rect.Dx() * rect.Dy()
+}
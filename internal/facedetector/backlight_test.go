@@ -0,0 +1,74 @@
+package facedetector
+
+import (
+	"image"
+	"testing"
+)
+
+// faceOnBackground は背景の輝度 background の中に、輝度 face の顔矩形を置いた画像を返します。
+func faceOnBackground(w, h int, faceRect image.Rectangle, face, background float64) [][]float64 {
+	gray := constantGray(w, h, background)
+	for y := faceRect.Min.Y; y < faceRect.Max.Y; y++ {
+		for x := faceRect.Min.X; x < faceRect.Max.X; x++ {
+			gray[y][x] = face
+		}
+	}
+	return gray
+}
+
+func TestAnalyzeBacklight_Backlit(t *testing.T) {
+	faceRect := image.Rect(80, 60, 160, 160)
+	gray := faceOnBackground(240, 220, faceRect, 50, 230)
+
+	b := New().analyzeBacklight(gray, calculateMeanBrightnessFromGray(gray), faceRect)
+	t.Logf("backlight=%+v", b)
+
+	if !b.Backlit {
+		t.Errorf("Expected backlit, got %+v", b)
+	}
+	if b.FaceLuminance != 50 || b.SurroundLuminance != 230 {
+		t.Errorf("Luminance face=%.1f surround=%.1f, want 50 / 230", b.FaceLuminance, b.SurroundLuminance)
+	}
+	if b.ContrastRatio < 4 {
+		t.Errorf("Expected contrast ratio >= 4, got %.2f", b.ContrastRatio)
+	}
+}
+
+func TestAnalyzeBacklight_EvenLighting(t *testing.T) {
+	faceRect := image.Rect(80, 60, 160, 160)
+	gray := faceOnBackground(240, 220, faceRect, 140, 120)
+
+	b := New().analyzeBacklight(gray, calculateMeanBrightnessFromGray(gray), faceRect)
+	if b.Backlit {
+		t.Errorf("Expected not backlit, got %+v", b)
+	}
+}
+
+func TestAnalyzeBacklight_Ratio(t *testing.T) {
+	// 周囲が顔の約1.5倍の明るさ: デフォルト（2.0）では逆光ではなく、1.4では逆光
+	faceRect := image.Rect(80, 60, 160, 160)
+	gray := faceOnBackground(240, 220, faceRect, 100, 150)
+	frame := calculateMeanBrightnessFromGray(gray)
+
+	if b := New().analyzeBacklight(gray, frame, faceRect); b.Backlit {
+		t.Errorf("Expected not backlit with default ratio, got %+v", b)
+	}
+	if b := New(WithBacklightRatio(1.4)).analyzeBacklight(gray, frame, faceRect); !b.Backlit {
+		t.Errorf("Expected backlit with ratio 1.4, got %+v", b)
+	}
+}
+
+func TestAnalyzeBacklight_FaceFillsFrame(t *testing.T) {
+	// 顔が画像全体を覆いリングがない場合は画像全体と比較する
+	gray := constantGray(100, 100, 90)
+	b := New().analyzeBacklight(gray, 90, image.Rect(-20, -20, 120, 120))
+	if b.Backlit || b.SurroundLuminance != 90 || b.ContrastRatio != 1 {
+		t.Errorf("Unexpected result for face filling the frame: %+v", b)
+	}
+}
+
+func TestShrinkRect(t *testing.T) {
+	if got, want := shrinkRect(image.Rect(0, 0, 100, 50), 0.6), image.Rect(20, 10, 80, 40); got != want {
+		t.Errorf("shrinkRect = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+package facedetector
+
+import "math"
+
+// ============================================================================
+// ブレの種類の分類（手ブレ / ピンボケ）
+// ============================================================================
+
+// BlurType はブレの種類です。
+type BlurType string
+
+const (
+	// BlurTypeNone はブレなし（NormalizedScoreがブレ判定閾値以上）
+	BlurTypeNone BlurType = "none"
+	// BlurTypeMotion は手ブレ・被写体ブレ（一方向に流れるブレ）。「動かないでください」の案内に対応
+	BlurTypeMotion BlurType = "motion"
+	// BlurTypeDefocus はピンボケ（方向性のないブレ）。「タップしてピントを合わせてください」の案内に対応
+	BlurTypeDefocus BlurType = "defocus"
+)
+
+// motionDipDepth はスペクトルのプロファイルで、手ブレのPSF（線分）によるsincの零点と
+// みなす谷の深さ（対数パワー）
+const motionDipDepth = 0.3
+
+// blurTypeEstimate はブレの種類の推定結果です。
+type blurTypeEstimate struct {
+	blurType BlurType
+	angleDeg float64 // 手ブレの方向（度、0〜180）
+	lengthPx float64 // 手ブレの長さ（元の解像度のピクセル）
+}
+
+// classifyBlurType はブレの種類を推定します。
+//
+// 構造テンソルのコヒーレンス（勾配方向の偏り）で手ブレとピンボケを区別します。
+// 手ブレはブレの方向の勾配を打ち消すため、勾配がブレと直交する方向に偏ります。
+// 手ブレの長さは、ブレの方向に沿ったパワースペクトルのプロファイルに現れる
+// sincの最初の零点（周期 N/L）から推定します。
+//
+// region は正規化前の解析領域、normalized / denoised は基準サイズに正規化された画像で、
+// 方向と長さはregionの座標系に換算して返します。blurred がfalseの場合はBlurTypeNoneを返します。
+func classifyBlurType(region, normalized, denoised [][]float64, blurred bool, coherenceThreshold float64) blurTypeEstimate {
+	if !blurred {
+		return blurTypeEstimate{blurType: BlurTypeNone}
+	}
+
+	coherence, gradAngle := structureTensorOrientation(denoised)
+	if coherence < coherenceThreshold {
+		return blurTypeEstimate{blurType: BlurTypeDefocus}
+	}
+
+	// ブレの方向は勾配の主方向と直交する（正規化画像の座標系、yは下向き）
+	mx, my := -math.Sin(gradAngle), math.Cos(gradAngle)
+
+	// 元の解像度の座標系に換算（正規化で縦横の倍率が異なる場合があるため）
+	if len(region) == 0 || len(region[0]) == 0 || len(normalized) == 0 || len(normalized[0]) == 0 {
+		return blurTypeEstimate{blurType: BlurTypeMotion}
+	}
+	sx := float64(len(region[0])) / float64(len(normalized[0]))
+	sy := float64(len(region)) / float64(len(normalized))
+	ox, oy := mx*sx, my*sy
+
+	// 画面上で反時計回りの角度（yを反転）を0〜180度に正規化
+	angle := math.Atan2(-oy, ox) * 180 / math.Pi
+	if angle < 0 {
+		angle += 180
+	}
+	if angle >= 180 {
+		angle -= 180
+	}
+
+	length := 0.0
+	if l := motionLengthFromSpectrum(normalized, mx, my); l > 0 {
+		length = l * math.Hypot(ox, oy)
+	}
+
+	return blurTypeEstimate{
+		blurType: BlurTypeMotion,
+		angleDeg: math.Round(angle*10) / 10,
+		lengthPx: math.Round(length*10) / 10,
+	}
+}
+
+// structureTensorOrientation は画像全体の構造テンソルから、コヒーレンス（0.0〜1.0）と
+// 勾配の主方向（ラジアン、画像座標系）を計算します。
+// コヒーレンスが1に近いほど勾配が一方向に揃っています。
+func structureTensorOrientation(gray [][]float64) (coherence, angle float64) {
+	h := len(gray)
+	if h < 3 {
+		return 0, 0
+	}
+	w := len(gray[0])
+	if w < 3 {
+		return 0, 0
+	}
+
+	var jxx, jyy, jxy float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			gx := (gray[y-1][x+1] + 2*gray[y][x+1] + gray[y+1][x+1]) -
+				(gray[y-1][x-1] + 2*gray[y][x-1] + gray[y+1][x-1])
+			gy := (gray[y+1][x-1] + 2*gray[y+1][x] + gray[y+1][x+1]) -
+				(gray[y-1][x-1] + 2*gray[y-1][x] + gray[y-1][x+1])
+			jxx += gx * gx
+			jyy += gy * gy
+			jxy += gx * gy
+		}
+	}
+
+	trace := jxx + jyy
+	if trace <= 0 {
+		return 0, 0
+	}
+	// 固有値の差 λ1 - λ2
+	diff := math.Sqrt((jxx-jyy)*(jxx-jyy) + 4*jxy*jxy)
+	return diff / trace, 0.5 * math.Atan2(2*jxy, jxx-jyy)
+}
+
+// motionLengthFromSpectrum は方向 (mx, my) に沿ったパワースペクトルのプロファイルから
+// 最初の谷（sincの零点）を探し、手ブレの長さ（正規化画像のピクセル）を返します。
+// 谷が見つからない場合は0を返します。
+func motionLengthFromSpectrum(gray [][]float64, mx, my float64) float64 {
+	h := len(gray)
+	if h < 8 {
+		return 0
+	}
+	w := len(gray[0])
+	if w < 8 {
+		return 0
+	}
+
+	n := nextPowerOfTwo(max(w, h))
+	spectrum := make([][]complex128, n)
+	mean := 0.0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			mean += gray[y][x]
+		}
+	}
+	mean /= float64(w * h)
+	for y := 0; y < n; y++ {
+		spectrum[y] = make([]complex128, n)
+		if y >= h {
+			continue
+		}
+		wy := hannWindow(y, h)
+		for x := 0; x < w; x++ {
+			spectrum[y][x] = complex((gray[y][x]-mean)*wy*hannWindow(x, w), 0)
+		}
+	}
+	fft2D(spectrum)
+
+	logPower := func(fx, fy float64) float64 {
+		u := (int(math.Round(fx)) + n) % n
+		v := (int(math.Round(fy)) + n) % n
+		c := spectrum[v][u]
+		return math.Log(1 + real(c)*real(c) + imag(c)*imag(c))
+	}
+
+	// 方向に沿ったプロファイル。線分のPSFのスペクトルは直交方向に一定なので、
+	// 直交方向の直線全体で平均してテクスチャによる揺らぎを抑える
+	half := n / 2
+	profile := make([]float64, half)
+	for r := 1; r < half; r++ {
+		fx, fy := float64(r)*mx, float64(r)*my
+		sum := 0.0
+		for t := -half; t < half; t++ {
+			sum += logPower(fx-float64(t)*my, fy+float64(t)*mx)
+		}
+		profile[r] = sum / float64(n)
+	}
+
+	// 3タップの平滑化
+	smoothed := make([]float64, half)
+	for r := 2; r < half-1; r++ {
+		smoothed[r] = (profile[r-1] + profile[r] + profile[r+1]) / 3
+	}
+
+	for r := 3; r < half-2; r++ {
+		if smoothed[r] <= smoothed[r-1] && smoothed[r] <= smoothed[r+1] {
+			// 谷の深さ: 両側の近傍の最大値との差
+			peak := math.Max(smoothed[r-2], smoothed[r+2])
+			if peak-smoothed[r] >= motionDipDepth {
+				return float64(n) / float64(r)
+			}
+		}
+	}
+	return 0
+}
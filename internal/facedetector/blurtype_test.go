@@ -0,0 +1,142 @@
+package facedetector
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// randomTexture は再現可能な乱数テクスチャ（全ての周波数成分を含む）を生成します。
+func randomTexture(size int) [][]float64 {
+	r := rand.New(rand.NewSource(1))
+	img := make([][]float64, size)
+	for y := range img {
+		img[y] = make([]float64, size)
+		for x := range img[y] {
+			img[y][x] = r.Float64() * 255
+		}
+	}
+	return img
+}
+
+// applyMotionBlur は方向 (dx, dy) に長さ length の線分PSFで画像を畳み込みます（端は周期境界）。
+func applyMotionBlur(img [][]float64, length, dx, dy int) [][]float64 {
+	n := len(img)
+	out := make([][]float64, n)
+	for y := range out {
+		out[y] = make([]float64, n)
+		for x := range out[y] {
+			sum := 0.0
+			for k := 0; k < length; k++ {
+				xx := (x + dx*(k-length/2) + n) % n
+				yy := (y + dy*(k-length/2) + n) % n
+				sum += img[yy][xx]
+			}
+			out[y][x] = sum / float64(length)
+		}
+	}
+	return out
+}
+
+// angleDiff は0〜180度の方向の差（0〜90度）を返します。
+func angleDiff(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 180)
+	return math.Min(d, 180-d)
+}
+
+func TestClassifyBlurType_Motion(t *testing.T) {
+	texture := randomTexture(128)
+	tests := []struct {
+		name       string
+		length     int
+		dx, dy     int
+		wantAngle  float64
+		wantLength float64
+	}{
+		{"horizontal", 9, 1, 0, 0, 9},
+		{"vertical", 9, 0, 1, 90, 9},
+		{"diagonal down-right", 6, 1, 1, 135, 6 * math.Sqrt2},
+		{"diagonal up-right", 5, 1, -1, 45, 5 * math.Sqrt2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blurred := applyMotionBlur(texture, tt.length, tt.dx, tt.dy)
+			got := classifyBlurType(blurred, blurred, blurred, true, 0.4)
+
+			if got.blurType != BlurTypeMotion {
+				t.Fatalf("blurType = %q, want %q", got.blurType, BlurTypeMotion)
+			}
+			if d := angleDiff(got.angleDeg, tt.wantAngle); d > 5 {
+				t.Errorf("angleDeg = %.1f, want %.1f (±5)", got.angleDeg, tt.wantAngle)
+			}
+			if math.Abs(got.lengthPx-tt.wantLength) > tt.wantLength*0.2 {
+				t.Errorf("lengthPx = %.1f, want %.1f (±20%%)", got.lengthPx, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestClassifyBlurType_RegionScale(t *testing.T) {
+	// 正規化前の領域が2倍の大きさなら、長さも2倍で報告される
+	blurred := applyMotionBlur(randomTexture(128), 9, 1, 0)
+	region := make([][]float64, 256)
+	for y := range region {
+		region[y] = make([]float64, 256)
+	}
+
+	got := classifyBlurType(region, blurred, blurred, true, 0.4)
+	if math.Abs(got.lengthPx-18) > 18*0.2 {
+		t.Errorf("lengthPx = %.1f, want 18 (±20%%)", got.lengthPx)
+	}
+}
+
+func TestClassifyBlurType_Defocus(t *testing.T) {
+	blurred := applyGaussianBlur2D(randomTexture(128), 9, 2.5)
+	got := classifyBlurType(blurred, blurred, blurred, true, 0.4)
+	if got.blurType != BlurTypeDefocus {
+		t.Errorf("blurType = %q, want %q", got.blurType, BlurTypeDefocus)
+	}
+	if got.angleDeg != 0 || got.lengthPx != 0 {
+		t.Errorf("Expected no motion parameters, got angle=%.1f length=%.1f", got.angleDeg, got.lengthPx)
+	}
+}
+
+func TestClassifyBlurType_NotBlurred(t *testing.T) {
+	blurred := applyMotionBlur(randomTexture(64), 9, 1, 0)
+	if got := classifyBlurType(blurred, blurred, blurred, false, 0.4); got.blurType != BlurTypeNone {
+		t.Errorf("blurType = %q, want %q", got.blurType, BlurTypeNone)
+	}
+}
+
+func TestCalculateFaceSharpness_BlurType(t *testing.T) {
+	for _, file := range []string{"testdata/face.jpg", "testdata/face_blurred.jpg"} {
+		t.Run(file, func(t *testing.T) {
+			imageData, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read test image: %v", err)
+			}
+
+			result, err := CalculateFaceSharpness(imageData)
+			if err != nil {
+				t.Fatalf("CalculateFaceSharpness failed: %v", err)
+			}
+
+			switch result.BlurType {
+			case BlurTypeNone:
+				if result.NormalizedScore < 50 {
+					t.Errorf("BlurType none with score %.1f below threshold", result.NormalizedScore)
+				}
+			case BlurTypeMotion, BlurTypeDefocus:
+				if result.NormalizedScore >= 50 {
+					t.Errorf("BlurType %q with score %.1f above threshold", result.BlurType, result.NormalizedScore)
+				}
+			default:
+				t.Errorf("Unexpected BlurType %q", result.BlurType)
+			}
+			t.Logf("%s: score=%.1f type=%s angle=%.1f length=%.1f", file, result.NormalizedScore,
+				result.BlurType, result.MotionAngleDeg, result.MotionLengthPx)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package facedetector
+
+import (
+	"image"
+	"math"
+)
+
+// ============================================================================
+// 知覚的ブレ指標（CPBD: Cumulative Probability of Blur Detection）
+// ============================================================================
+//
+// Narvekar & Karam (2011) のJNB（Just Noticeable Blur）モデルに基づく無参照の鮮明度指標です。
+// 各エッジの幅を、人間がブレに気付き始めるエッジ幅（JNB幅）と比較してブレ検出確率を求め、
+// 「ブレに気付かれない」エッジの割合を返します。エッジ減衰率のシグモイド変換と異なり、
+// 閾値が人間の知覚実験に基づくため、レビュアーの判断と対応させやすい指標です。
+
+const (
+	// cpbdBlockSize はエッジブロックの判定とコントラスト計算に使用するブロックサイズ（ピクセル）
+	cpbdBlockSize = 64
+	// cpbdEdgeBlockRatio はブロックを「エッジブロック」とみなすエッジ画素の最低割合
+	cpbdEdgeBlockRatio = 0.002
+	// cpbdMinEdgeGradient はエッジとみなす水平方向のSobel勾配の最小値（ノイズの除外）
+	cpbdMinEdgeGradient = 20.0
+	// cpbdEdgeGradientRatio はエッジとみなす勾配の最大勾配に対する割合
+	cpbdEdgeGradientRatio = 0.1
+	// cpbdBeta はブレ検出確率の心理測定関数の傾き
+	cpbdBeta = 3.6
+	// cpbdJNBProbability はブレに気付く確率の閾値（1 - e^-1 ≒ 63%）
+	cpbdJNBProbability = 0.63
+	// cpbdLowContrast 以下のコントラストのブロックはJNB幅 cpbdJNBWidthLow を使用
+	cpbdLowContrast   = 50.0
+	cpbdJNBWidthLow   = 5.0
+	cpbdJNBWidthHigh  = 3.0
+	cpbdHistogramBins = 101
+	// cpbdMaxPixels はCPBDを計算する画像の画素数の上限（超える場合は整数倍で縮小）
+	cpbdMaxPixels = 1 << 20
+)
+
+// perceptualMetric はCPBDを指標とします（Valueは0.0〜1.0、Scoreは0〜100）。
+type perceptualMetric struct{}
+
+func (perceptualMetric) Name() string { return MetricPerceptual }
+
+func (perceptualMetric) Measure(in MetricInput) MetricResult {
+	v := calculateCPBD(in.Region)
+	return MetricResult{Value: v, Score: v * 100}
+}
+
+// calculateCPBD はグレースケール画像のCPBD（0.0〜1.0、高いほど鮮明）を計算します。
+// エッジ幅はピクセル単位で比較するため、リサイズ前の元の解像度の画像を渡します。
+// 画素数がcpbdMaxPixelsを超える画像は、勾配のメモリを抑えるため整数倍で縮小してから計算します。
+// エッジが見つからない場合（均一な画像など）は0を返します。
+func calculateCPBD(gray [][]float64) float64 {
+	h := len(gray)
+	if h < 3 {
+		return 0
+	}
+	w := len(gray[0])
+	if w < 3 {
+		return 0
+	}
+	if w*h > cpbdMaxPixels {
+		gray = downsampleBox(gray, int(math.Ceil(math.Sqrt(float64(w*h)/cpbdMaxPixels))))
+		h, w = len(gray), len(gray[0])
+	}
+
+	// 水平方向のSobel勾配（垂直エッジを検出）
+	gx := make([][]float64, h)
+	maxGrad := 0.0
+	for y := 1; y < h-1; y++ {
+		gx[y] = make([]float64, w)
+		for x := 1; x < w-1; x++ {
+			g := (gray[y-1][x+1] + 2*gray[y][x+1] + gray[y+1][x+1]) -
+				(gray[y-1][x-1] + 2*gray[y][x-1] + gray[y+1][x-1])
+			gx[y][x] = g
+			if math.Abs(g) > maxGrad {
+				maxGrad = math.Abs(g)
+			}
+		}
+	}
+
+	threshold := math.Max(cpbdMinEdgeGradient, maxGrad*cpbdEdgeGradientRatio)
+
+	var histogram [cpbdHistogramBins]int
+	totalEdges := 0
+
+	// 画像端のブロックは画像内に収まる大きさに切り詰める（小さな顔では画像全体が1ブロック）
+	for by := 0; by < h; by += cpbdBlockSize {
+		ey := min(by+cpbdBlockSize, h)
+		for bx := 0; bx < w; bx += cpbdBlockSize {
+			ex := min(bx+cpbdBlockSize, w)
+
+			// ブロック内のエッジ画素（水平方向の勾配の極大点）を収集
+			var edges []image.Point
+			minVal, maxVal := math.MaxFloat64, -math.MaxFloat64
+			for y := by; y < ey; y++ {
+				for x := bx; x < ex; x++ {
+					v := gray[y][x]
+					minVal = math.Min(minVal, v)
+					maxVal = math.Max(maxVal, v)
+
+					if y == 0 || y == h-1 || x == 0 || x == w-1 {
+						continue
+					}
+					g := math.Abs(gx[y][x])
+					if g >= threshold && g >= math.Abs(gx[y][x-1]) && g > math.Abs(gx[y][x+1]) {
+						edges = append(edges, image.Point{X: x, Y: y})
+					}
+				}
+			}
+
+			if float64(len(edges)) <= cpbdEdgeBlockRatio*float64((ex-bx)*(ey-by)) {
+				continue
+			}
+
+			// ブロックのコントラストからJNB幅を決定
+			jnbWidth := cpbdJNBWidthHigh
+			if maxVal-minVal <= cpbdLowContrast {
+				jnbWidth = cpbdJNBWidthLow
+			}
+
+			for _, p := range edges {
+				width := measureEdgeWidth(gray[p.Y], p.X, gx[p.Y][p.X] > 0)
+				prob := 1 - math.Exp(-math.Pow(width/jnbWidth, cpbdBeta))
+				histogram[int(math.Round(prob*100))]++
+				totalEdges++
+			}
+		}
+	}
+
+	if totalEdges == 0 {
+		return 0
+	}
+
+	// ブレ検出確率がJNB確率以下のエッジの累積割合
+	cumulative := 0
+	for i := 0; i <= int(cpbdJNBProbability*100); i++ {
+		cumulative += histogram[i]
+	}
+	return float64(cumulative) / float64(totalEdges)
+}
+
+// downsampleBox は画像を factor×factor 画素の平均で縮小します（端の余りの画素は捨てます）。
+func downsampleBox(gray [][]float64, factor int) [][]float64 {
+	h, w := len(gray)/factor, len(gray[0])/factor
+	area := float64(factor * factor)
+	result := make([][]float64, h)
+	for y := range result {
+		result[y] = make([]float64, w)
+		for x := range result[y] {
+			sum := 0.0
+			for dy := 0; dy < factor; dy++ {
+				for _, v := range gray[y*factor+dy][x*factor : (x+1)*factor] {
+					sum += v
+				}
+			}
+			result[y][x] = sum / area
+		}
+	}
+	return result
+}
+
+// measureEdgeWidth は行 row の位置 x にあるエッジの幅を、エッジの両側で輝度の単調な
+// 変化が終わる位置（極大・極小）の間の距離として計算します。
+// rising は左から右に向かって明るくなるエッジかどうかです。
+func measureEdgeWidth(row []float64, x int, rising bool) float64 {
+	left, right := x, x
+	if rising {
+		for right+1 < len(row) && row[right+1] > row[right] {
+			right++
+		}
+		for left-1 >= 0 && row[left-1] < row[left] {
+			left--
+		}
+	} else {
+		for right+1 < len(row) && row[right+1] < row[right] {
+			right++
+		}
+		for left-1 >= 0 && row[left-1] > row[left] {
+			left--
+		}
+	}
+
+	if right-left < 1 {
+		return 1
+	}
+	return float64(right - left)
+}
@@ -0,0 +1,113 @@
+package facedetector
+
+import (
+	"os"
+	"testing"
+)
+
+// stepEdgeImage は縦縞のステップエッジを並べたテスト画像を生成します。
+func stepEdgeImage(w, h, period int) [][]float64 {
+	img := make([][]float64, h)
+	for y := range img {
+		img[y] = make([]float64, w)
+		for x := range img[y] {
+			if (x/period)%2 == 1 {
+				img[y][x] = 220
+			} else {
+				img[y][x] = 30
+			}
+		}
+	}
+	return img
+}
+
+func TestCalculateCPBD_SharpVsBlurred(t *testing.T) {
+	sharp := stepEdgeImage(128, 128, 16)
+	blurred := applyGaussianBlur2D(sharp, 15, 4.0)
+
+	sharpCPBD := calculateCPBD(sharp)
+	blurredCPBD := calculateCPBD(blurred)
+	t.Logf("sharp=%.3f blurred=%.3f", sharpCPBD, blurredCPBD)
+
+	if sharpCPBD < 0.9 {
+		t.Errorf("Expected step edges to be perceived sharp, got %.3f", sharpCPBD)
+	}
+	if blurredCPBD > 0.1 {
+		t.Errorf("Expected blurred edges to be perceived blurry, got %.3f", blurredCPBD)
+	}
+}
+
+func TestCalculateCPBD_Uniform(t *testing.T) {
+	uniform := make([][]float64, 40)
+	for y := range uniform {
+		uniform[y] = make([]float64, 40)
+		for x := range uniform[y] {
+			uniform[y][x] = 128
+		}
+	}
+	if got := calculateCPBD(uniform); got != 0 {
+		t.Errorf("Expected 0 for uniform image, got %.3f", got)
+	}
+}
+
+func TestCalculateCPBD_LargeImage(t *testing.T) {
+	// 上限を超える画像は縮小して計算するため、縞の周期を縮小率に合わせて広げる
+	large := stepEdgeImage(2048, 1024, 32)
+	if got := calculateCPBD(large); got < 0.9 {
+		t.Errorf("Expected downsampled step edges to be perceived sharp, got %.3f", got)
+	}
+}
+
+func TestDownsampleBox(t *testing.T) {
+	gray := [][]float64{
+		{0, 2, 10, 10, 99},
+		{4, 6, 10, 10, 99},
+		{99, 99, 99, 99, 99},
+	}
+	got := downsampleBox(gray, 2)
+	if len(got) != 1 || len(got[0]) != 2 {
+		t.Fatalf("downsampleBox size = %dx%d, want 2x1", len(got[0]), len(got))
+	}
+	if got[0][0] != 3 || got[0][1] != 10 {
+		t.Errorf("downsampleBox = %v, want [[3 10]]", got)
+	}
+}
+
+func TestMeasureEdgeWidth(t *testing.T) {
+	tests := []struct {
+		name   string
+		row    []float64
+		x      int
+		rising bool
+		want   float64
+	}{
+		{"step", []float64{0, 0, 0, 255, 255, 255}, 3, true, 1},
+		{"ramp", []float64{0, 0, 50, 100, 150, 200, 200}, 3, true, 4},
+		{"falling ramp", []float64{200, 200, 150, 100, 50, 0, 0}, 3, false, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := measureEdgeWidth(tt.row, tt.x, tt.rising); got != tt.want {
+				t.Errorf("measureEdgeWidth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateFaceSharpness_PerceptualBlurScore(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/face.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := CalculateFaceSharpness(imageData, WithMetrics(MetricPerceptual))
+	if err != nil {
+		t.Fatalf("CalculateFaceSharpness failed: %v", err)
+	}
+	if result.PerceptualBlurScore < 0 || result.PerceptualBlurScore > 100 {
+		t.Errorf("PerceptualBlurScore out of range [0, 100]: got %.1f", result.PerceptualBlurScore)
+	}
+	if got := result.Metrics[MetricPerceptual].Score; got != result.PerceptualBlurScore {
+		t.Errorf("perceptual metric score = %.1f, want %.1f", got, result.PerceptualBlurScore)
+	}
+}
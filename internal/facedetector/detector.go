@@ -2,13 +2,15 @@ package facedetector
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	_ "image/jpeg" // image.Decodeでjpeg形式をサポートするために必要
 	"image/png"
-	"log"
 	"math"
 	"os"
 	"path/filepath"
@@ -20,117 +22,106 @@ import (
 )
 
 // ============================================================================
-// 定数・設定
+// 定数
 // ============================================================================
 
 const (
 	// DNN モデルファイル名
 	dnnModelFileName = "res10_300x300_ssd_iter_140000.caffemodel"
 	dnnProtoFileName = "deploy.prototxt"
+)
 
-	// DNN検出の信頼度閾値
-	// 商用環境では 0.5 を標準とし、多段階検出で閾値を下げて補完する
-	dnnConfidenceHigh = 0.5  // 高信頼度（単独で採用）
-	dnnConfidenceLow  = 0.25 // 低信頼度（交差検証用）
+// ============================================================================
+// Detector
+// ============================================================================
 
-	// NMS（Non-Maximum Suppression）のIoU閾値
-	nmsIOUThreshold = 0.3
+// Detector は顔検出と鮮明度計算の設定、およびモデルのオブジェクトプールを保持します。
+// 異なる設定のDetectorを同一プロセス内で並行して使用できます。
+// Detectorは複数のゴルーチンから同時に使用しても安全です。
+type Detector struct {
+	cfg config
 
-	// 肌色検出: 肌色ピクセルの最低割合
-	skinColorMinRatio = 0.10
+	// 顔検出バックエンドのチェーン（先頭から順に試行）
+	backends []FaceDetector
 
-	// アスペクト比の許容範囲（顔として妥当な範囲）
-	aspectRatioMin = 0.5
-	aspectRatioMax = 1.8
+	// 名前で選択できる鮮明度指標のレジストリ
+	metrics map[string]SharpnessMetric
 
-	// 顔矩形の最小サイズ（ピクセル）
-	minFaceSize = 20
+	// 顔の特徴点の検出器（WithLandmarks(false)の場合はnil）
+	landmarker *LandmarkDetector
 
-	// CLAHE パラメータ
-	claheClipLimit = 3.0
-	claheTileSize  = 8
+	// 目のHaar Cascade分類器のプール（特徴点が無い場合の目の位置の検出用）
+	eyeCascades *cascadePoolSet
+}
 
-	// ガンマ補正パラメータ
-	gammaForDark  = 1.8 // 暗い画像用（明るくする）
-	gammaForBright = 0.6 // 明るすぎる画像用（暗くする）
+// New は指定されたオプションでDetectorを生成します。
+// オプションを指定しない場合は実運用でチューニング済みのデフォルト設定を使用します。
+func New(opts ...Option) *Detector {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 
-	// 画像の明るさ判定閾値
-	darkThreshold   = 80.0  // この値以下なら「暗い」と判定
-	brightThreshold = 180.0 // この値以上なら「明るすぎる」と判定
-)
+	d := &Detector{
+		cfg:         cfg,
+		metrics:     make(map[string]SharpnessMetric),
+		eyeCascades: newCascadePoolSet(),
+	}
+	for _, m := range append(builtinMetrics(cfg), cfg.customMetrics...) {
+		d.metrics[m.Name()] = m
+	}
 
-// ============================================================================
-// オブジェクトプール (sync.Pool) による商用キャッシュ化
-// ============================================================================
+	if cfg.landmarks {
+		landmarkPath := cfg.landmarkModelPath
+		if landmarkPath == "" {
+			landmarkPath, _ = findLandmarkModelFile()
+		}
+		d.landmarker = NewLandmarkDetector(landmarkPath)
+	}
 
-var (
-	dnnNetPool  sync.Pool
-	initLogOnce sync.Once
+	if cfg.backends != nil {
+		d.backends = cfg.backends
+		return d
+	}
 
-	cascadePools   map[string]*sync.Pool
-	cascadePoolsMu sync.RWMutex
-)
+	// DNNモデルのパスを解決（明示的な指定がなければ検索パスから探す）
+	protoPath, modelPath := cfg.dnnProtoPath, cfg.dnnModelPath
+	if protoPath == "" || modelPath == "" {
+		protoPath, modelPath, _ = findDNNModelFiles()
+	}
 
-func init() {
-	// dnnNetPool の初期化
-	dnnNetPool = sync.Pool{
-		New: func() interface{} {
-			protoPath, modelPath, found := findDNNModelFiles()
-			if !found {
-				return nil
-			}
-			net := gocv.ReadNetFromCaffe(protoPath, modelPath)
-			if net.Empty() {
-				net.Close()
-				return nil
-			}
-			return &net
-		},
+	d.backends = []FaceDetector{
+		NewDNNDetector(protoPath, modelPath, cfg.dnnConfidenceLow, cfg.minFaceSize),
+	}
+	if cfg.cascadeFallback {
+		d.backends = append(d.backends, NewHaarDetector(cfg.cascadeFiles, cfg.minFaceSize))
 	}
 
-	// cascadePools の初期化
-	cascadePools = make(map[string]*sync.Pool)
+	return d
 }
 
-func getCascadePool(cascadeFile string) *sync.Pool {
-	cascadePoolsMu.RLock()
-	pool, ok := cascadePools[cascadeFile]
-	cascadePoolsMu.RUnlock()
-	if ok {
-		return pool
-	}
-
-	cascadePoolsMu.Lock()
-	defer cascadePoolsMu.Unlock()
-	// ダブルチェック
-	pool, ok = cascadePools[cascadeFile]
-	if ok {
-		return pool
-	}
+var (
+	defaultDetector     *Detector
+	defaultDetectorOnce sync.Once
+)
 
-	pool = &sync.Pool{
-		New: func() interface{} {
-			classifier := gocv.NewCascadeClassifier()
-			if !classifier.Load(cascadeFile) {
-				classifier.Close()
-				return nil
-			}
-			return &classifier
-		},
-	}
-	cascadePools[cascadeFile] = pool
-	return pool
+// getDefaultDetector はパッケージレベルのAPI関数が使用するデフォルト設定のDetectorを返します。
+func getDefaultDetector() *Detector {
+	defaultDetectorOnce.Do(func() {
+		defaultDetector = New()
+	})
+	return defaultDetector
 }
 
-// cascadeFiles はHaar Cascade分類器のファイルパスリスト
-var cascadeFiles = []string{
-	"cascade/haarcascade_frontalface_alt2.xml",
-	"/usr/share/opencv4/haarcascades/haarcascade_frontalface_default.xml",
-	"/usr/share/opencv4/haarcascades/haarcascade_frontalface_alt.xml",
-	"/usr/share/opencv4/haarcascades/haarcascade_frontalface_alt2.xml",
-	"/usr/share/opencv4/haarcascades/haarcascade_profileface.xml",
-	"/usr/local/share/opencv4/haarcascades/haarcascade_frontalface_default.xml",
-	"/usr/local/share/opencv4/haarcascades/haarcascade_frontalface_alt2.xml",
+// modelsAvailable はいずれかの顔検出バックエンドが利用可能かを返します。
+func (d *Detector) modelsAvailable() bool {
+	for _, backend := range d.backends {
+		if a, ok := backend.(availabler); ok && !a.Available() {
+			continue
+		}
+		return true
+	}
+	return false
 }
 
 // ============================================================================
@@ -189,20 +180,93 @@ type subImager interface {
 	SubImage(r image.Rectangle) image.Image
 }
 
-// Detection はpigoライブラリの検出結果を模倣した構造体です。
-// GoCVとの互換性のために残しますが、pigo特有のフィールドは使われません。
-type Detection struct {
-	Row   int
-	Col   int
-	Scale int
-	Q     float32 // 信頼度スコア
+// DetectorSource は顔を検出した検出器の種類を表します。
+type DetectorSource string
+
+const (
+	// SourceDNN はSSD ResNet-10（DNN）による検出です。
+	SourceDNN DetectorSource = "dnn"
+	// SourceCascade はHaar Cascade分類器による検出です。
+	SourceCascade DetectorSource = "cascade"
+	// SourceProfileCascade は横顔用のHaar Cascade分類器（haarcascade_profileface.xml）による検出です。
+	SourceProfileCascade DetectorSource = "profile_cascade"
+)
+
+// DetectionPhase は顔が見つかった検出パイプラインの段階（入力画像の種類）を表します。
+// Sourceと組み合わせることで、どの経路で検出されたかを特定できます
+// （例: Phase=preprocessed かつ Source=cascade はHaar Cascadeのフォールバック検出）。
+type DetectionPhase string
+
+const (
+	// PhasePreprocessed は適応的前処理（ガンマ補正 + CLAHE）済み画像での検出です。
+	PhasePreprocessed DetectionPhase = "preprocessed"
+	// PhaseRaw は前処理なしの元画像での検出です。
+	PhaseRaw DetectionPhase = "raw"
+	// PhaseUpscaled は低解像度対策として2倍/3倍に拡大した画像での検出です。
+	PhaseUpscaled DetectionPhase = "upscaled"
+	// PhaseSharpened はブレ画像対策としてシャープ化した画像での再検出です。
+	PhaseSharpened DetectionPhase = "sharpened"
+)
+
+// Face は検出された1つの顔を表します。
+// Rectは検出器が返した実際の矩形（元画像の座標系）で、正方形に補正されていません。
+type Face struct {
+	// Rect は顔の矩形（元画像の座標系）。JSONでは bounding_box として出力されます。
+	Rect image.Rectangle `json:"-"`
+
+	// Confidence は検出の信頼度（0〜1）。
+	// Haar Cascadeのみで検出された場合は0、DNNで交差検証された場合はDNNの信頼度です。
+	Confidence float32 `json:"confidence"`
+
+	// Source は顔を検出した検出器の種類です。
+	Source DetectorSource `json:"source"`
+
+	// Phase は顔が見つかった検出パイプラインの段階です。
+	Phase DetectionPhase `json:"phase"`
+
+	// Landmarks は顔の68点の特徴点（目・眉・鼻・口・輪郭）。
+	// LBF Facemarkモデル（lbfmodel.yaml）が見つからない場合は省略されます。
+	Landmarks Landmarks `json:"landmarks,omitempty"`
+
+	// Rotation は顔を検出するために画像を回転した角度（時計回り、度）。
+	// WithRotationRetryで回転した画像から見つかった場合のみ90・180・270になり、JSONでは0の場合は省略されます。
+	// 画像をこの角度だけ時計回りに回転すると顔が正立します。Rectなどの座標は回転前の画像の座標系です。
+	Rotation int `json:"rotation,omitempty"`
+}
+
+// BoundingBox はJSON出力用の矩形表現です。
+type BoundingBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// newBoundingBox はimage.RectangleからBoundingBoxを生成します。
+func newBoundingBox(r image.Rectangle) BoundingBox {
+	return BoundingBox{X: r.Min.X, Y: r.Min.Y, Width: r.Dx(), Height: r.Dy()}
+}
+
+// rect はBoundingBoxをimage.Rectangleに変換します。
+func (b BoundingBox) rect() image.Rectangle {
+	return image.Rect(b.X, b.Y, b.X+b.Width, b.Y+b.Height)
+}
+
+// MarshalJSON はRectを bounding_box（x, y, width, height）としてJSONに出力します。
+func (f Face) MarshalJSON() ([]byte, error) {
+	type faceJSON Face
+	return json.Marshal(struct {
+		BoundingBox BoundingBox `json:"bounding_box"`
+		faceJSON
+	}{newBoundingBox(f.Rect), faceJSON(f)})
 }
 
 // detectionWithConfidence は内部処理用の検出結果（矩形+信頼度付き）
 type detectionWithConfidence struct {
 	rect       image.Rectangle
 	confidence float32
-	source     string // "dnn" or "cascade"
+	source     DetectorSource
+	phase      DetectionPhase
 }
 
 // SharpnessResult は鮮明度の分析結果を構造化して返します。
@@ -213,6 +277,9 @@ type SharpnessResult struct {
 	// 80以上: 鮮明、50〜80: 許容範囲、50未満: ブレ・ボケあり
 	NormalizedScore float64 `json:"normalized_score"`
 
+	// ScoreBasis はNormalizedScoreの算出に使用した鮮明度指標の名前（デフォルト: edge_decay）。
+	ScoreBasis string `json:"score_basis"`
+
 	// RawLaplacianVariance はラプラシアン分散の生値。
 	// 画像サイズや被写体に依存しますが、参考指標として提供します。
 	RawLaplacianVariance float64 `json:"raw_laplacian_variance"`
@@ -226,18 +293,53 @@ type SharpnessResult struct {
 	// 高いほどピントが合っています（被写体に依存しない相対指標）。
 	EdgeDecayRatio float64 `json:"edge_decay_ratio"`
 
+	// FrequencyEnergyRatio はFFTパワースペクトルのうち高周波成分が占める割合（0.0〜1.0）。
+	// 空間勾配ベースの指標と独立した判定で、高いほど鮮明です。
+	FrequencyEnergyRatio float64 `json:"frequency_energy_ratio"`
+
+	// PerceptualBlurScore はJNB（Just Noticeable Blur）モデルに基づく知覚的鮮明度（0〜100）。
+	// 人間がブレに気付かないエッジの割合（CPBD）で、高いほど鮮明です。
+	// 顔の中心領域（CalculateSharpnessでは画像全体）の元の解像度で計算し、
+	// 約100万画素を超える場合は縮小してから計算します。
+	PerceptualBlurScore float64 `json:"perceptual_blur_score"`
+
+	// BlurType はブレの種類（none: ブレなし、motion: 手ブレ、defocus: ピンボケ）。
+	// NormalizedScoreがブレ判定閾値未満の場合に分類します。
+	BlurType BlurType `json:"blur_type"`
+
+	// MotionAngleDeg は手ブレの方向（度、0〜180。0が水平、90が垂直、画面上で反時計回り）。
+	// BlurTypeがmotionの場合のみ設定されます。
+	MotionAngleDeg float64 `json:"motion_angle_deg"`
+
+	// MotionLengthPx は手ブレの長さの推定値（元の解像度のピクセル）。
+	// BlurTypeがmotionで長さを推定できた場合のみ設定されます。
+	MotionLengthPx float64 `json:"motion_length_px"`
+
 	// MeanBrightness は画像の平均輝度（0〜255）。
 	// 逆光や露出の診断に使用します。
 	MeanBrightness float64 `json:"mean_brightness"`
 
+	// NoiseSigma はノイズ除去の強さとスコアのノイズ補正に使用したノイズの標準偏差
+	// （サイズ・コントラスト正規化後の画像で推定した輝度値）。ノイズ適応が無効な場合は0。
+	NoiseSigma float64 `json:"noise_sigma"`
+
+	// RawNoiseSigma は元の解像度の画像で推定したノイズの標準偏差（輝度値）。
+	// 暗所撮影などで高くなります。撮影条件の診断用の参考値で、スコアの計算には使用しません。
+	// 大きな画像では一定間隔の画素のみで推定します。
+	RawNoiseSigma float64 `json:"raw_noise_sigma"`
+
+	// Exposure は解析領域の露出の診断結果（白飛び・黒つぶれ・ダイナミックレンジ・判定）。
+	// 顔の分析では顔の中心領域、CalculateSharpnessでは画像全体の露出です。
+	Exposure Exposure `json:"exposure"`
+
 	// EstimatedBlurLevel はラプラシアン分散によるブレ推定値。
 	// 低いほどブレが大きいことを示します。
 	EstimatedBlurLevel float64 `json:"estimated_blur_level"`
 
-	// OriginalWidth は入力画像の元の幅（ピクセル）。
+	// OriginalWidth は入力画像の元の幅（ピクセル）。CalculateSharpnessではEXIFの向きを適用した後の幅です。
 	OriginalWidth int `json:"original_width"`
 
-	// OriginalHeight は入力画像の元の高さ（ピクセル）。
+	// OriginalHeight は入力画像の元の高さ（ピクセル）。CalculateSharpnessではEXIFの向きを適用した後の高さです。
 	OriginalHeight int `json:"original_height"`
 
 	// AnalyzedWidth は鮮明度計算に使用した正規化後の幅（ピクセル）。
@@ -245,6 +347,14 @@ type SharpnessResult struct {
 
 	// AnalyzedHeight は鮮明度計算に使用した正規化後の高さ（ピクセル）。
 	AnalyzedHeight int `json:"analyzed_height"`
+
+	// Orientation は解析前に適用したEXIFの向き（1は回転なし）。CalculateSharpnessの結果でのみ設定され、
+	// 顔ごとの結果では省略されます（顔の分析結果の向きは FaceSharpnessResult.Orientation）。
+	Orientation Orientation `json:"orientation,omitempty"`
+
+	// Metrics はWithMetricsで指定された鮮明度指標の結果（指標名がキー）。
+	// 指定されていない場合は省略されます。
+	Metrics map[string]MetricResult `json:"metrics,omitempty"`
 }
 
 // ============================================================================
@@ -287,16 +397,16 @@ func applyGammaCorrection(mat gocv.Mat, gamma float64) gocv.Mat {
 
 // applyAdaptivePreprocessing は画像の状態に応じて適切な前処理を自動選択して適用します。
 // 返り値は前処理済みの画像で、呼び出し側でClose()する必要があります。
-func applyAdaptivePreprocessing(mat gocv.Mat) gocv.Mat {
+func (d *Detector) applyAdaptivePreprocessing(mat gocv.Mat) gocv.Mat {
 	brightness := calculateMeanBrightness(mat)
 
 	var processed gocv.Mat
-	if brightness < darkThreshold {
+	if brightness < d.cfg.darkThreshold {
 		// 暗い画像（逆光等）: ガンマ補正で明るくする
-		processed = applyGammaCorrection(mat, gammaForDark)
-	} else if brightness > brightThreshold {
+		processed = applyGammaCorrection(mat, d.cfg.gammaForDark)
+	} else if brightness > d.cfg.brightThreshold {
 		// 明るすぎる画像: ガンマ補正で抑える
-		processed = applyGammaCorrection(mat, gammaForBright)
+		processed = applyGammaCorrection(mat, d.cfg.gammaForBright)
 	} else {
 		// 通常の明るさ: そのままコピー
 		processed = gocv.NewMat()
@@ -309,7 +419,7 @@ func applyAdaptivePreprocessing(mat gocv.Mat) gocv.Mat {
 	gocv.CvtColor(processed, &labMat, gocv.ColorBGRToLab)
 
 	channels := gocv.Split(labMat)
-	clahe := gocv.NewCLAHEWithParams(claheClipLimit, image.Point{X: claheTileSize, Y: claheTileSize})
+	clahe := gocv.NewCLAHEWithParams(d.cfg.claheClipLimit, image.Point{X: d.cfg.claheTileSize, Y: d.cfg.claheTileSize})
 	defer clahe.Close()
 	clahe.Apply(channels[0], &channels[0])
 
@@ -322,7 +432,7 @@ func applyAdaptivePreprocessing(mat gocv.Mat) gocv.Mat {
 	// 古い processed を解放し、上書きのリークを避けるために新しいMatに変換
 	result := gocv.NewMat()
 	gocv.CvtColor(enhanced, &result, gocv.ColorLabToBGR)
-	
+
 	processed.Close()
 	enhanced.Close()
 
@@ -371,134 +481,6 @@ func estimateBlurLevel(mat gocv.Mat) float64 {
 	return std * std
 }
 
-// ============================================================================
-// DNN ベースの顔検出
-// ============================================================================
-
-// detectWithDNN はSSD DNN モデルを使用して顔を検出します。
-// 複数の前処理バリエーションで検出を試み、結果を統合します。
-func detectWithDNN(mat gocv.Mat, minConfidence float32) []detectionWithConfidence {
-	// 初回実行時に診断ログを出力
-	initLogOnce.Do(func() {
-		protoPath, modelPath, found := findDNNModelFiles()
-		if found {
-			log.Printf("[FaceDetector] DNN models found. Using SSD ResNet-10 (Caffe). Proto: %s, Model: %s\n", protoPath, modelPath)
-		} else {
-			log.Println("[FaceDetector] WARNING: DNN models NOT found. Falling back to Haar Cascades.")
-		}
-	})
-
-	netVal := dnnNetPool.Get()
-	if netVal == nil {
-		return nil
-	}
-	netPtr := netVal.(*gocv.Net)
-	if netPtr.Empty() {
-		return nil
-	}
-	defer dnnNetPool.Put(netPtr)
-
-	var allDetections []detectionWithConfidence
-
-	// メイン画像での検出
-	dets := runDNNInference(*netPtr, mat, minConfidence)
-	allDetections = append(allDetections, dets...)
-
-	return allDetections
-}
-
-// runDNNInference は単一の画像に対してDNN推論を実行します。
-func runDNNInference(net gocv.Net, mat gocv.Mat, minConfidence float32) []detectionWithConfidence {
-	// SSD モデルの入力サイズは300x300
-	blob := gocv.BlobFromImage(mat, 1.0, image.Point{X: 300, Y: 300},
-		gocv.NewScalar(104, 177, 123, 0), false, false)
-	defer blob.Close()
-
-	net.SetInput(blob, "")
-	prob := net.Forward("")
-	defer prob.Close()
-
-	detections := gocv.GetBlobChannel(prob, 0, 0)
-	defer detections.Close()
-
-	var results []detectionWithConfidence
-	for i := 0; i < detections.Rows(); i++ {
-		confidence := detections.GetFloatAt(i, 2)
-		if confidence > minConfidence {
-			left := float64(detections.GetFloatAt(i, 3)) * float64(mat.Cols())
-			top := float64(detections.GetFloatAt(i, 4)) * float64(mat.Rows())
-			right := float64(detections.GetFloatAt(i, 5)) * float64(mat.Cols())
-			bottom := float64(detections.GetFloatAt(i, 6)) * float64(mat.Rows())
-
-			rect := image.Rect(int(left), int(top), int(right), int(bottom))
-			// 画像境界内にクリップ
-			rect = clipRect(rect, image.Rect(0, 0, mat.Cols(), mat.Rows()))
-
-			if rect.Dx() >= minFaceSize && rect.Dy() >= minFaceSize {
-				results = append(results, detectionWithConfidence{
-					rect:       rect,
-					confidence: confidence,
-					source:     "dnn",
-				})
-			}
-		}
-	}
-
-	return results
-}
-
-// ============================================================================
-// Haar Cascade ベースの顔検出
-// ============================================================================
-
-// detectWithCascades は複数のカスケード分類器で顔検出を実行します。
-// 各分類器の結果を統合し、重複を除去して返します。
-func detectWithCascades(mat gocv.Mat, minNeighbors int) []detectionWithConfidence {
-	var allDetections []detectionWithConfidence
-
-	for _, cascadeFile := range cascadeFiles {
-		found := false
-		_ = func() error {
-			pool := getCascadePool(cascadeFile)
-			classVal := pool.Get()
-			if classVal == nil {
-				return nil
-			}
-			classifierPtr := classVal.(*gocv.CascadeClassifier)
-			defer pool.Put(classifierPtr)
-
-			rects := classifierPtr.DetectMultiScaleWithParams(
-				mat,
-				1.05,             // scaleFactor: 小さい値ほど検出漏れが減るが遅くなる
-				minNeighbors,     // minNeighbors: 低いほど検出しやすいが誤検出が増える
-				0,                // flags
-				image.Point{X: minFaceSize, Y: minFaceSize}, // minSize
-				image.Point{},    // maxSize: 制限なし
-			)
-
-			for _, r := range rects {
-				allDetections = append(allDetections, detectionWithConfidence{
-					rect:       r,
-					confidence: 0, // Haar Cascadeは信頼度スコアを返さない
-					source:     "cascade",
-				})
-			}
-
-			if len(rects) > 0 {
-				found = true
-			}
-			return nil
-		}()
-
-		// 何か見つかったらそのカスケードで十分
-		if found {
-			break
-		}
-	}
-
-	return allDetections
-}
-
 // ============================================================================
 // 後処理・フィルタリング
 // ============================================================================
@@ -553,15 +535,29 @@ func calculateIoU(a, b image.Rectangle) float64 {
 	return interArea / unionArea
 }
 
+// HSV色空間（OpenCVの8ビット表現: H 0〜180、S・V 0〜255）での肌色範囲。
+// 多様な肌色をカバーするため彩度・明度の下限のみを設け、色相は2つの範囲で判定します。
+const (
+	skinHueMax     = 25  // 肌色範囲1: 赤〜黄色系（H: 0〜25）
+	skinHueWrapMin = 160 // 肌色範囲2: 赤色系の折り返し（H: 160〜180）
+	skinSatMin     = 15
+	skinValMin     = 50
+)
+
 // isSkinColor は検出領域がHSV色空間で肌色範囲に入っているか検証します。
 // 多様な肌色をカバーする広い範囲と、2つの色相範囲（0〜25, 160〜180）で判定します。
-func isSkinColor(mat gocv.Mat, rect image.Rectangle) bool {
+func (d *Detector) isSkinColor(mat gocv.Mat, rect image.Rectangle) bool {
 	bounds := image.Rect(0, 0, mat.Cols(), mat.Rows())
 	rect = rect.Intersect(bounds)
 	if rect.Empty() {
 		return false
 	}
+	return skinRatio(mat, rect) >= d.cfg.skinColorMinRatio
+}
 
+// skinRatio はBGRのMatの矩形の中で、HSV色空間の肌色範囲に入っている画素の割合を返します。
+// 検出時の偽陽性フィルタ（isSkinColor）と遮蔽の判定（zoneOcclusion）で共通の判定です。
+func skinRatio(mat gocv.Mat, rect image.Rectangle) float64 {
 	roi := mat.Region(rect)
 	defer roi.Close()
 
@@ -570,9 +566,9 @@ func isSkinColor(mat gocv.Mat, rect image.Rectangle) bool {
 	gocv.CvtColor(roi, &hsvMat, gocv.ColorBGRToHSV)
 
 	// 肌色範囲1: 赤〜黄色系（H: 0〜25）
-	lowerBound1 := gocv.NewMatFromScalar(gocv.NewScalar(0, 15, 50, 0), gocv.MatTypeCV8UC3)
+	lowerBound1 := gocv.NewMatFromScalar(gocv.NewScalar(0, skinSatMin, skinValMin, 0), gocv.MatTypeCV8UC3)
 	defer lowerBound1.Close()
-	upperBound1 := gocv.NewMatFromScalar(gocv.NewScalar(25, 255, 255, 0), gocv.MatTypeCV8UC3)
+	upperBound1 := gocv.NewMatFromScalar(gocv.NewScalar(skinHueMax, 255, 255, 0), gocv.MatTypeCV8UC3)
 	defer upperBound1.Close()
 
 	mask1 := gocv.NewMat()
@@ -580,7 +576,7 @@ func isSkinColor(mat gocv.Mat, rect image.Rectangle) bool {
 	gocv.InRange(hsvMat, lowerBound1, upperBound1, &mask1)
 
 	// 肌色範囲2: 赤色系の折り返し（H: 160〜180）
-	lowerBound2 := gocv.NewMatFromScalar(gocv.NewScalar(160, 15, 50, 0), gocv.MatTypeCV8UC3)
+	lowerBound2 := gocv.NewMatFromScalar(gocv.NewScalar(skinHueWrapMin, skinSatMin, skinValMin, 0), gocv.MatTypeCV8UC3)
 	defer lowerBound2.Close()
 	upperBound2 := gocv.NewMatFromScalar(gocv.NewScalar(180, 255, 255, 0), gocv.MatTypeCV8UC3)
 	defer upperBound2.Close()
@@ -596,51 +592,60 @@ func isSkinColor(mat gocv.Mat, rect image.Rectangle) bool {
 
 	totalPixels := combinedMask.Rows() * combinedMask.Cols()
 	if totalPixels == 0 {
-		return false
+		return 0
 	}
 	skinPixels := gocv.CountNonZero(combinedMask)
-	ratio := float64(skinPixels) / float64(totalPixels)
-
-	return ratio >= skinColorMinRatio
+	return float64(skinPixels) / float64(totalPixels)
 }
 
 // hasValidAspectRatio は検出矩形のアスペクト比が顔として妥当かチェックします。
-func hasValidAspectRatio(rect image.Rectangle) bool {
+func (d *Detector) hasValidAspectRatio(rect image.Rectangle) bool {
 	w := float64(rect.Dx())
 	h := float64(rect.Dy())
 	if w == 0 || h == 0 {
 		return false
 	}
 	ratio := w / h
-	return ratio >= aspectRatioMin && ratio <= aspectRatioMax
+	return ratio >= d.cfg.aspectRatioMin && ratio <= d.cfg.aspectRatioMax
 }
 
 // hasMinimumSize は検出矩形が最小サイズ以上かチェックします。
-func hasMinimumSize(rect image.Rectangle) bool {
-	return rect.Dx() >= minFaceSize && rect.Dy() >= minFaceSize
+func (d *Detector) hasMinimumSize(rect image.Rectangle) bool {
+	return rect.Dx() >= d.cfg.minFaceSize && rect.Dy() >= d.cfg.minFaceSize
+}
+
+// meetsMinimumSize は検出結果が検出段階に応じた最小サイズ以上かチェックします。
+// 拡大画像での検出（PhaseUpscaled）はminFaceSize未満の低解像度の顔を見つけるための段階のため、
+// minFaceSizeを最大の拡大率で割った大きさを下限とします。
+func (d *Detector) meetsMinimumSize(det detectionWithConfidence) bool {
+	if det.phase != PhaseUpscaled {
+		return d.hasMinimumSize(det.rect)
+	}
+	minSize := d.cfg.minFaceSize / haarUpscaleFactors[len(haarUpscaleFactors)-1]
+	return det.rect.Dx() >= minSize && det.rect.Dy() >= minSize
 }
 
 // filterFalsePositives は検出結果から偽陽性を除外します。
 // 複数のフィルタ（アスペクト比、最小サイズ、肌色）を段階的に適用します。
-func filterFalsePositives(mat gocv.Mat, detections []detectionWithConfidence) []detectionWithConfidence {
+func (d *Detector) filterFalsePositives(mat gocv.Mat, detections []detectionWithConfidence) []detectionWithConfidence {
 	var filtered []detectionWithConfidence
 	for _, det := range detections {
-		// DNN の高信頼度検出はフィルタリングを緩和
-		if det.source == "dnn" && det.confidence >= dnnConfidenceHigh {
-			if hasMinimumSize(det.rect) {
+		// 高信頼度の検出（DNNなど信頼度を返すバックエンド）はフィルタリングを緩和
+		if det.confidence >= d.cfg.dnnConfidenceHigh {
+			if d.meetsMinimumSize(det) {
 				filtered = append(filtered, det)
 			}
 			continue
 		}
 
 		// それ以外はフルフィルタリング
-		if !hasMinimumSize(det.rect) {
+		if !d.meetsMinimumSize(det) {
 			continue
 		}
-		if !hasValidAspectRatio(det.rect) {
+		if !d.hasValidAspectRatio(det.rect) {
 			continue
 		}
-		if !isSkinColor(mat, det.rect) {
+		if !d.isSkinColor(mat, det.rect) {
 			continue
 		}
 		filtered = append(filtered, det)
@@ -648,11 +653,12 @@ func filterFalsePositives(mat gocv.Mat, detections []detectionWithConfidence) []
 	return filtered
 }
 
-// crossValidateDetections はDNNとHaar Cascadeの検出結果を交差検証します。
-// 両方のソースで検出された領域は高い信頼性を持ちます。
-func crossValidateDetections(mat gocv.Mat, cascadeDets []detectionWithConfidence) []detectionWithConfidence {
-	// DNN が利用できない場合はカスケード結果をそのまま返す
-	dnnDets := detectWithDNN(mat, dnnConfidenceLow)
+// crossValidateDetections はフォールバックのバックエンドの検出結果を、チェーン先頭の
+// バックエンド（デフォルトではDNN）の検出結果と交差検証します。
+// 両方のバックエンドで検出された領域は高い信頼性を持ちます。
+func (d *Detector) crossValidateDetections(ctx context.Context, mat gocv.Mat, primary FaceDetector, cascadeDets []detectionWithConfidence) []detectionWithConfidence {
+	// 先頭のバックエンドで何も見つからない場合はフォールバックの結果をそのまま返す
+	dnnDets := detectWith(ctx, primary, mat)
 	if len(dnnDets) == 0 {
 		return cascadeDets
 	}
@@ -663,9 +669,9 @@ func crossValidateDetections(mat gocv.Mat, cascadeDets []detectionWithConfidence
 	for _, cd := range cascadeDets {
 		matched := false
 		for _, dd := range dnnDets {
-			if calculateIoU(cd.rect, dd.rect) >= 0.15 {
+			if calculateIoU(cd.rect, dd.Rect) >= 0.15 {
 				// DNN の信頼度を引き継ぎ
-				cd.confidence = dd.confidence
+				cd.confidence = dd.Confidence
 				validated = append(validated, cd)
 				matched = true
 				break
@@ -687,30 +693,38 @@ func crossValidateDetections(mat gocv.Mat, cascadeDets []detectionWithConfidence
 // ユーティリティ関数
 // ============================================================================
 
-// largestDetection は検出結果から最も大きい顔を返します。
-func largestDetection(dets []Detection) (Detection, bool) {
-	var largest Detection
-	maxScale := 0
-	for _, det := range dets {
-		if det.Scale > maxScale {
-			maxScale = det.Scale
-			largest = det
+// largestFace は検出結果から最も面積の大きい顔を返します。
+func largestFace(faces []Face) (Face, bool) {
+	var largest Face
+	maxArea := 0
+	for _, f := range faces {
+		if area := f.Rect.Dx() * f.Rect.Dy(); area > maxArea {
+			maxArea = area
+			largest = f
 		}
 	}
-	if maxScale == 0 {
-		return Detection{}, false
+	if maxArea == 0 {
+		return Face{}, false
 	}
 	return largest, true
 }
 
-// detectionRect はDetectionから矩形を生成します。
-func detectionRect(det Detection) image.Rectangle {
-	return image.Rect(
-		det.Col-det.Scale/2,
-		det.Row-det.Scale/2,
-		det.Col+det.Scale/2,
-		det.Row+det.Scale/2,
-	)
+// toDetections はバックエンドの検出結果を後処理用の形式に変換します。
+// バックエンドがPhaseを設定していない結果には、指定された検出段階を記録します。
+func toDetections(faces []Face, phase DetectionPhase) []detectionWithConfidence {
+	dets := make([]detectionWithConfidence, 0, len(faces))
+	for _, f := range faces {
+		if f.Phase == "" {
+			f.Phase = phase
+		}
+		dets = append(dets, detectionWithConfidence{
+			rect:       f.Rect,
+			confidence: f.Confidence,
+			source:     f.Source,
+			phase:      f.Phase,
+		})
+	}
+	return dets
 }
 
 // clipRect は矩形を画像境界内にクリッピングします。
@@ -754,160 +768,179 @@ func rectsOverlap(a, b image.Rectangle) bool {
 // ============================================================================
 
 // detectFaces は画像データから顔を検出し、検出結果と元画像を返します。
-// 商用レベルの多段階検出パイプライン:
-//  1. 適応的な前処理（ガンマ補正 + CLAHE）
-//  2. DNN（SSD ResNet-10）による高精度検出（メイン）
-//  3. Haar Cascade によるフォールバック検出
-//  4. 多スケール検出（低解像度画像対応）
-//  5. シャープネス改善による再検出（ブレ画像対応）
-//  6. NMS + 偽陽性フィルタリング
-//  7. DNN/Cascade 交差検証
-func detectFaces(imageData []byte) (image.Image, []Detection, error) {
-	// バイトスライスから画像をデコード（Go標準ライブラリ、結果返却用）
-	img, _, err := image.Decode(bytes.NewReader(imageData))
-	if err != nil {
-		return nil, nil, fmt.Errorf("画像のデコードに失敗しました: %v", err)
+// EXIFの向きを適用した画像でdetectInMatを実行し、顔が見つからず
+// WithRotationRetryが有効な場合は回転した画像で再試行します（detectRotated）。
+func (d *Detector) detectFaces(ctx context.Context, imageData []byte) (image.Image, []Face, Orientation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, OrientationNormal, err
 	}
 
-	// OpenCVで直接デコード（色空間を正しく扱うため）
-	mat, err := gocv.IMDecode(imageData, gocv.IMReadColor)
+	// 一度だけデコードしてEXIFの向きを適用し、同じ画素から検出用のMatを作る
+	img, mat, orientation, err := decodeOriented(imageData)
 	if err != nil {
-		return nil, nil, fmt.Errorf("OpenCVでの画像デコードに失敗しました: %v", err)
+		return nil, nil, orientation, err
 	}
 	defer mat.Close()
 
-	if mat.Empty() {
-		return nil, nil, fmt.Errorf("画像のデコード結果が空です")
+	// DNNモデルもカスケード分類器も無い場合は検出を実行できない
+	if !d.modelsAvailable() {
+		return nil, nil, orientation, ErrModelUnavailable
+	}
+
+	faces, err := d.detectInMat(ctx, mat)
+	tooSmall := errors.Is(err, ErrFaceTooSmall)
+	if err != nil && !tooSmall {
+		return nil, nil, orientation, err
 	}
 
+	// EXIFが削除された横向きの画像などに対応するため、回転した画像で再検出
+	if len(faces) == 0 && d.cfg.rotationRetry {
+		faces, err = d.detectRotated(ctx, mat)
+		if errors.Is(err, ErrFaceTooSmall) {
+			tooSmall = true
+		} else if err != nil {
+			return nil, nil, orientation, err
+		}
+	}
+
+	// 回転しても最小サイズ以上の顔が見つからない場合
+	if len(faces) == 0 && tooSmall {
+		return nil, nil, orientation, ErrFaceTooSmall
+	}
+
+	return img, faces, orientation, nil
+}
+
+// detectInMat はMatから顔を検出し、Matの座標系の検出結果を返します。
+// 商用レベルの多段階検出パイプライン:
+//  1. 適応的な前処理（ガンマ補正 + CLAHE）
+//  2. バックエンドのチェーンによる検出（デフォルトはDNN → Haar Cascade）
+//     各バックエンドで前処理済み画像・元画像の順に試行し、最初に見つかった結果を採用
+//     （Haar Cascadeは内部で緩和・多スケール検出を行うため前処理済み画像のみ）
+//  3. シャープネス改善による再検出（ブレ画像対応、Haar Cascadeは緩和パラメータの1回のみ）
+//  4. NMS + 偽陽性フィルタリング（最小サイズ未満の検出しか無い場合はErrFaceTooSmall）
+//  5. 先頭バックエンドとの交差検証（フォールバックで検出した場合のみ）
+//
+// 各フェーズの間とバックエンドの切り替え時にctxのキャンセルを確認し、
+// キャンセルされていればctx.Err()を返して処理を打ち切ります。
+func (d *Detector) detectInMat(ctx context.Context, mat gocv.Mat) ([]Face, error) {
 	// ========================================================================
 	// Phase 1: 適応的前処理
 	// ========================================================================
-	preprocessed := applyAdaptivePreprocessing(mat)
+	preprocessed := d.applyAdaptivePreprocessing(mat)
 	defer preprocessed.Close()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// ========================================================================
-	// Phase 2: DNN による検出（メイン経路）
+	// Phase 2: バックエンドのチェーンによる検出
 	// ========================================================================
 	var allDetections []detectionWithConfidence
-	dnnDetected := false
+	detectedBy := -1 // 顔を検出したバックエンドのチェーン内の位置
 
-	// 前処理済み画像でDNN検出
-	dnnDets := detectWithDNN(preprocessed, dnnConfidenceLow)
-	if len(dnnDets) > 0 {
-		allDetections = append(allDetections, dnnDets...)
-		dnnDetected = true
+	inputs := []struct {
+		mat   gocv.Mat
+		phase DetectionPhase
+	}{
+		{preprocessed, PhasePreprocessed},
+		// 前処理済みで見つからなければ元画像でも試行
+		{mat, PhaseRaw},
 	}
 
-	// 前処理済みで見つからなければ元画像でも試行
-	if !dnnDetected {
-		dnnDets = detectWithDNN(mat, dnnConfidenceLow)
-		if len(dnnDets) > 0 {
-			allDetections = append(allDetections, dnnDets...)
-			dnnDetected = true
+	for i, backend := range d.backends {
+		for _, in := range inputs {
+			if in.phase == PhaseRaw && !retriesOnRaw(backend) {
+				continue
+			}
+			dets := toDetections(detectWith(ctx, backend, in.mat), in.phase)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if len(dets) > 0 {
+				allDetections = dets
+				detectedBy = i
+				break
+			}
+		}
+		if detectedBy >= 0 {
+			break
 		}
 	}
 
 	// ========================================================================
-	// Phase 3: Haar Cascade によるフォールバック（DNNで見つからなかった場合）
+	// Phase 3: シャープネス改善による再検出（ブレ画像対応）
 	// ========================================================================
-	if !dnnDetected {
-		// グレースケールに変換
-		grayMat := gocv.NewMat()
-		defer grayMat.Close()
-		gocv.CvtColor(preprocessed, &grayMat, gocv.ColorBGRToGray)
-
-		// ガウシアンブラーでノイズを軽減
-		blurredMat := gocv.NewMat()
-		defer blurredMat.Close()
-		gocv.GaussianBlur(grayMat, &blurredMat, image.Point{X: 5, Y: 5}, 0, 0, gocv.BorderDefault)
-
-		// 通常パラメータで検出
-		cascadeDets := detectWithCascades(blurredMat, 4)
-		allDetections = append(allDetections, cascadeDets...)
-
-		// 見つからなければパラメータを緩和して再試行
-		if len(allDetections) == 0 {
-			cascadeDets = detectWithCascades(blurredMat, 3)
-			allDetections = append(allDetections, cascadeDets...)
-		}
-
-		// ====================================================================
-		// Phase 4: 多スケール検出（低解像度画像対応）
-		// ====================================================================
-		if len(allDetections) == 0 {
-			for _, scale := range []int{2, 3} {
-				upscaled := gocv.NewMat()
-				gocv.Resize(blurredMat, &upscaled, image.Point{
-					X: blurredMat.Cols() * scale,
-					Y: blurredMat.Rows() * scale,
-				}, 0, 0, gocv.InterpolationCubic) // Cubicで高品質アップスケール
-
-				upDets := detectWithCascades(upscaled, 3)
-				upscaled.Close()
-
-				// 座標を元のスケールに戻す
-				for i := range upDets {
-					upDets[i].rect.Min.X /= scale
-					upDets[i].rect.Min.Y /= scale
-					upDets[i].rect.Max.X /= scale
-					upDets[i].rect.Max.Y /= scale
-				}
-				allDetections = append(allDetections, upDets...)
-
-				if len(allDetections) > 0 {
-					break
+	if detectedBy < 0 {
+		blurLevel := estimateBlurLevel(mat)
+		if blurLevel < 100.0 { // ブレが大きい場合
+			sharpened := applySharpeningFilter(preprocessed)
+			defer sharpened.Close()
+
+			// 専用の軽量な検出パスを持つバックエンド（Haar Cascade）を先に試行し、残りは通常の検出で試行
+			order := make([]int, 0, len(d.backends))
+			for _, dedicated := range []bool{true, false} {
+				for i, backend := range d.backends {
+					if _, ok := backend.(sharpenedDetector); ok == dedicated {
+						order = append(order, i)
+					}
 				}
 			}
-		}
 
-		// ====================================================================
-		// Phase 5: シャープネス改善による再検出（ブレ画像対応）
-		// ====================================================================
-		if len(allDetections) == 0 {
-			blurLevel := estimateBlurLevel(mat)
-			if blurLevel < 100.0 { // ブレが大きい場合
-				sharpened := applySharpeningFilter(preprocessed)
-				defer sharpened.Close()
-
-				sharpGray := gocv.NewMat()
-				defer sharpGray.Close()
-				gocv.CvtColor(sharpened, &sharpGray, gocv.ColorBGRToGray)
-
-				sharpDets := detectWithCascades(sharpGray, 3)
-				allDetections = append(allDetections, sharpDets...)
-
-				// シャープ化画像でDNNも試行
-				if len(allDetections) == 0 {
-					dnnSharpDets := detectWithDNN(sharpened, dnnConfidenceLow)
-					allDetections = append(allDetections, dnnSharpDets...)
+			for _, i := range order {
+				dets := toDetections(detectSharpenedWith(ctx, d.backends[i], sharpened), PhaseSharpened)
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if len(dets) > 0 {
+					allDetections = dets
+					detectedBy = i
+					break
 				}
 			}
 		}
 	}
 
 	// ========================================================================
-	// Phase 6: NMS + 偽陽性フィルタリング
+	// Phase 4: NMS + 偽陽性フィルタリング
 	// ========================================================================
 	if len(allDetections) == 0 {
-		return img, []Detection{}, nil
+		return []Face{}, nil
 	}
 
 	// NMS で重複検出を除去
-	allDetections = nonMaxSuppression(allDetections, nmsIOUThreshold)
+	allDetections = nonMaxSuppression(allDetections, d.cfg.nmsIOUThreshold)
+
+	// 最小サイズ未満の検出を除外（全て除外された場合は顔が小さすぎるとしてErrFaceTooSmallを返す）
+	// 拡大画像での検出は、拡大率で割った最小サイズで判定（meetsMinimumSize）
+	var sized []detectionWithConfidence
+	for _, det := range allDetections {
+		if d.meetsMinimumSize(det) {
+			sized = append(sized, det)
+		}
+	}
+	if len(sized) == 0 {
+		return nil, ErrFaceTooSmall
+	}
+	allDetections = sized
 
 	// 偽陽性フィルタリング
-	filtered := filterFalsePositives(mat, allDetections)
+	filtered := d.filterFalsePositives(mat, allDetections)
 	if len(filtered) > 0 {
 		allDetections = filtered
 	}
 	// フィルタで全て除外された場合は元の検出結果を維持（過剰除外防止）
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// ========================================================================
-	// Phase 7: DNN/Cascade 交差検証（Cascade経路のみ）
+	// Phase 5: 先頭バックエンドとの交差検証（フォールバック経路のみ）
 	// ========================================================================
-	if !dnnDetected && len(allDetections) > 0 {
-		validated := crossValidateDetections(mat, allDetections)
+	if detectedBy > 0 && len(allDetections) > 0 {
+		validated := d.crossValidateDetections(ctx, mat, d.backends[0], allDetections)
 		if len(validated) > 0 {
 			allDetections = validated
 		}
@@ -916,38 +949,58 @@ func detectFaces(imageData []byte) (image.Image, []Detection, error) {
 	// ========================================================================
 	// 結果変換
 	// ========================================================================
-	var dets []Detection
-	for _, d := range allDetections {
-		r := d.rect
-		dets = append(dets, Detection{
-			Row:   r.Min.Y + r.Dy()/2,
-			Col:   r.Min.X + r.Dx()/2,
-			Scale: (r.Dx() + r.Dy()) / 2,
-			Q:     d.confidence,
+	faces := make([]Face, 0, len(allDetections))
+	for _, det := range allDetections {
+		faces = append(faces, Face{
+			Rect:       det.rect,
+			Confidence: det.confidence,
+			Source:     det.source,
+			Phase:      det.phase,
 		})
 	}
+	d.fitLandmarks(mat, faces)
 
-	return img, dets, nil
+	return faces, nil
 }
 
 // ============================================================================
 // エクスポートされるAPI関数
 // ============================================================================
 
+// DetectFaces は画像データから全ての顔を検出し、実際の矩形・信頼度・検出器の種類・
+// 検出段階を含む結果を返します。結果は信頼度の降順に並びます。
+// 顔が見つからない場合は空のスライスを返します（エラーにはなりません）。
+// 検出された顔が全てWithMinFaceSize未満の場合はErrFaceTooSmallを返します
+// （Haar Cascadeの拡大画像での検出は、最小サイズを拡大率で割った大きさまで許容します）。
+func (d *Detector) DetectFaces(imageData []byte) ([]Face, error) {
+	return d.DetectFacesContext(context.Background(), imageData)
+}
+
+// DetectFacesContext はctxのキャンセルに対応したDetectFacesです。
+func (d *Detector) DetectFacesContext(ctx context.Context, imageData []byte) ([]Face, error) {
+	_, faces, _, err := d.detectFaces(ctx, imageData)
+	return faces, err
+}
+
 // DrawFaceRects は画像内の検出された最大の顔の周りに太い四角い枠を描画します。
-func DrawFaceRects(imageData []byte) ([]byte, error) {
-	img, dets, err := detectFaces(imageData)
+func (d *Detector) DrawFaceRects(imageData []byte) ([]byte, error) {
+	return d.DrawFaceRectsContext(context.Background(), imageData)
+}
+
+// DrawFaceRectsContext はctxのキャンセルに対応したDrawFaceRectsです。
+func (d *Detector) DrawFaceRectsContext(ctx context.Context, imageData []byte) ([]byte, error) {
+	img, faces, _, err := d.detectFaces(ctx, imageData)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(dets) == 0 {
-		return nil, fmt.Errorf("顔が検出されませんでした")
+	if len(faces) == 0 {
+		return nil, ErrNoFace
 	}
 
-	largestDet, ok := largestDetection(dets)
+	largest, ok := largestFace(faces)
 	if !ok {
-		return nil, fmt.Errorf("適切なサイズの顔が検出されませんでした")
+		return nil, ErrFaceTooSmall
 	}
 
 	// 描画用の新しいRGBA画像を作成
@@ -956,7 +1009,7 @@ func DrawFaceRects(imageData []byte) ([]byte, error) {
 	draw.Draw(rgba, b, img, image.Point{0, 0}, draw.Src)
 
 	// 最も大きい顔の周りに赤い四角を描画
-	rect := clipRect(detectionRect(largestDet), b)
+	rect := clipRect(largest.Rect, b)
 
 	red := color.RGBA{255, 0, 0, 255}
 	thickness := 3
@@ -965,7 +1018,7 @@ func DrawFaceRects(imageData []byte) ([]byte, error) {
 	// 画像をPNGとしてエンコード
 	buf := new(bytes.Buffer)
 	if err := png.Encode(buf, rgba); err != nil {
-		return nil, fmt.Errorf("画像のエンコードに失敗しました: %v", err)
+		return nil, fmt.Errorf("画像のエンコードに失敗しました: %w", err)
 	}
 
 	return buf.Bytes(), nil
@@ -1013,23 +1066,28 @@ func drawThickRect(img *image.RGBA, rect image.Rectangle, c color.Color, thickne
 }
 
 // CropFace は画像から最も大きく検出された顔を切り抜きます。
-func CropFace(imageData []byte) ([]byte, error) {
-	img, dets, err := detectFaces(imageData)
+func (d *Detector) CropFace(imageData []byte) ([]byte, error) {
+	return d.CropFaceContext(context.Background(), imageData)
+}
+
+// CropFaceContext はctxのキャンセルに対応したCropFaceです。
+func (d *Detector) CropFaceContext(ctx context.Context, imageData []byte) ([]byte, error) {
+	img, faces, _, err := d.detectFaces(ctx, imageData)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(dets) == 0 {
-		return nil, fmt.Errorf("顔が検出されませんでした")
+	if len(faces) == 0 {
+		return nil, ErrNoFace
 	}
 
-	largestDet, ok := largestDetection(dets)
+	largest, ok := largestFace(faces)
 	if !ok {
-		return nil, fmt.Errorf("適切なサイズの顔が検出されませんでした")
+		return nil, ErrFaceTooSmall
 	}
 
 	// 顔領域を切り抜く（15%のマージンを追加して額・顎を含める）
-	faceRect := addMargin(detectionRect(largestDet), 0.15)
+	faceRect := addMargin(largest.Rect, 0.15)
 	faceRect = clipRect(faceRect, img.Bounds())
 
 	var croppedImg image.Image
@@ -1044,7 +1102,7 @@ func CropFace(imageData []byte) ([]byte, error) {
 	// 画像をPNGとしてエンコード
 	buf := new(bytes.Buffer)
 	if err := png.Encode(buf, croppedImg); err != nil {
-		return nil, fmt.Errorf("画像のエンコードに失敗しました: %v", err)
+		return nil, fmt.Errorf("画像のエンコードに失敗しました: %w", err)
 	}
 
 	return buf.Bytes(), nil
@@ -1054,95 +1112,192 @@ func CropFace(imageData []byte) ([]byte, error) {
 // 強化された顔検出パイプラインで検出した顔の中心60%領域（目・鼻・口）のみを評価対象とし、
 // 撮影環境やカメラの品質に依存しない客観的な指標を提供します。
 // 複数の顔が検出された場合は、最も高いスコアの結果を返します。
-func CalculateFaceSharpness(imageData []byte) (SharpnessResult, error) {
-	img, dets, err := detectFaces(imageData)
+// 結果には採用した顔の矩形・検出器の種類・信頼度・検出段階と、検出された顔の数が含まれます。
+// また、顔以外の領域の鮮明度と比較し、ピントが顔・背景のどちらに合っているか（focus_target）を返します。
+func (d *Detector) CalculateFaceSharpness(imageData []byte, opts ...AnalysisOption) (FaceSharpnessResult, error) {
+	return d.CalculateFaceSharpnessContext(context.Background(), imageData, opts...)
+}
+
+// CalculateFaceSharpnessContext はctxのキャンセルに対応したCalculateFaceSharpnessです。
+// 顔ごとの鮮明度計算の間にもキャンセルを確認します。
+func (d *Detector) CalculateFaceSharpnessContext(ctx context.Context, imageData []byte, opts ...AnalysisOption) (FaceSharpnessResult, error) {
+	an, err := d.newAnalysis(opts)
 	if err != nil {
-		return SharpnessResult{}, err
+		return FaceSharpnessResult{}, err
+	}
+	if err := d.checkFrontalOnly(an); err != nil {
+		return FaceSharpnessResult{}, err
+	}
+
+	img, faces, orientation, err := d.detectFaces(ctx, imageData)
+	if err != nil {
+		return FaceSharpnessResult{}, err
 	}
 
-	if len(dets) == 0 {
-		return SharpnessResult{}, fmt.Errorf("顔が検出されませんでした")
+	if len(faces) == 0 {
+		return FaceSharpnessResult{}, ErrNoFace
 	}
 
-	var bestResult SharpnessResult
+	gray := convertToGrayscale(img)
+
+	var bestResult FaceSharpness
+	var bestFrame faceFrame
 	bestScore := -1.0
 
 	// 検出された各顔に対して鮮明度を計算
-	for _, det := range dets {
-		faceRect := clipRect(detectionRect(det), img.Bounds())
-
-		var faceImg image.Image
-		if sub, ok := img.(subImager); ok {
-			faceImg = sub.SubImage(faceRect)
-		} else {
-			cropped := image.NewRGBA(faceRect.Bounds())
-			draw.Draw(cropped, faceRect.Bounds(), img, faceRect.Min, draw.Src)
-			faceImg = cropped
+	for i, face := range faces {
+		if err := ctx.Err(); err != nil {
+			return FaceSharpnessResult{}, err
 		}
 
-		// グレースケール画像に変換
-		grayImg := convertToGrayscale(faceImg)
-
-		// 顔中心60%領域のみを抽出（髪・服・背景を排除）
-		centerGray := extractFaceCenterRegion(grayImg, faceCenterRatio)
+		// 横を向いた顔は鮮明度を計算する前に除外
+		if an.frontalOnly && !d.isFrontal(face, img.Bounds().Size()) {
+			continue
+		}
 
-		// 正規化鮮明度パイプラインで計算
-		result := calculateNormalizedSharpness(centerGray, faceRect.Dx(), faceRect.Dy())
+		frame := uprightFaceFrame(img, gray, face)
+		result := d.analyzeFaceSharpness(frame, i, an)
 
 		if result.NormalizedScore > bestScore {
 			bestScore = result.NormalizedScore
 			bestResult = result
+			bestFrame = frame
 		}
 	}
 
-	return bestResult, nil
+	if bestScore < 0 {
+		return FaceSharpnessResult{}, ErrNotFrontal
+	}
+
+	// 顔の周囲・画像全体との輝度の比較で逆光を、顔の中の輝度の偏りで照明のむらを判定
+	bestResult.Backlight = d.analyzeBacklight(bestFrame.gray, calculateMeanBrightnessFromGray(gray), bestFrame.face.Rect)
+	bestResult.Lighting = d.analyzeLighting(bestFrame.gray, bestFrame.face.Rect)
+
+	// 背景と比較してピントの位置を判定（スコアの基準によらずエッジ減衰率で比較）
+	faceScore := decayRatioToScore(bestResult.EdgeDecayRatio, d.cfg.sigmoidMidpoint, d.cfg.sigmoidSteepness)
+	focus, err := d.analyzeFocus(ctx, gray, faces, faceScore)
+	if err != nil {
+		return FaceSharpnessResult{}, err
+	}
+
+	return FaceSharpnessResult{
+		FaceSharpness: bestResult,
+		FaceCount:     len(faces),
+		Orientation:   orientation,
+		ImageExposure: d.analyzeExposure(gray),
+		FocusAnalysis: focus,
+	}, nil
+}
+
+// faceSharpness は1つの顔の中心領域について正規化鮮明度パイプラインを実行します。
+func (d *Detector) faceSharpness(img image.Image, face Face, an *analysis) SharpnessResult {
+	faceRect := clipRect(face.Rect, img.Bounds())
+
+	var faceImg image.Image
+	if sub, ok := img.(subImager); ok {
+		faceImg = sub.SubImage(faceRect)
+	} else {
+		cropped := image.NewRGBA(faceRect.Bounds())
+		draw.Draw(cropped, faceRect.Bounds(), img, faceRect.Min, draw.Src)
+		faceImg = cropped
+	}
+
+	// グレースケール画像に変換
+	grayImg := convertToGrayscale(faceImg)
+
+	// 顔中心60%領域のみを抽出（髪・服・背景を排除）
+	centerGray := extractFaceCenterRegion(grayImg, d.cfg.faceCenterRatio)
+
+	// 正規化鮮明度パイプラインで計算
+	return d.calculateNormalizedSharpness(centerGray, faceRect.Dx(), faceRect.Dy(), an)
 }
 
 // CalculateSharpness は、画像データの鮮明度を分析し、正規化されたスコアと診断情報を返します。
 // 画像全体の鮮明度を評価します（顔に限定しない汎用評価）。
-func CalculateSharpness(imageData []byte) (SharpnessResult, error) {
-	if len(imageData) == 0 {
-		return SharpnessResult{}, fmt.Errorf("画像データが空です")
+func (d *Detector) CalculateSharpness(imageData []byte, opts ...AnalysisOption) (SharpnessResult, error) {
+	return d.CalculateSharpnessContext(context.Background(), imageData, opts...)
+}
+
+// CalculateSharpnessContext はctxのキャンセルに対応したCalculateSharpnessです。
+func (d *Detector) CalculateSharpnessContext(ctx context.Context, imageData []byte, opts ...AnalysisOption) (SharpnessResult, error) {
+	if err := ctx.Err(); err != nil {
+		return SharpnessResult{}, err
+	}
+
+	an, err := d.newAnalysis(opts)
+	if err != nil {
+		return SharpnessResult{}, err
 	}
 
-	img, _, err := image.Decode(bytes.NewReader(imageData))
+	img, orientation, err := decodeImage(imageData)
 	if err != nil {
-		return SharpnessResult{}, fmt.Errorf("画像のデコードに失敗しました: %v", err)
+		return SharpnessResult{}, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return SharpnessResult{}, err
 	}
 
 	bounds := img.Bounds()
 	grayImg := convertToGrayscale(img)
-	result := calculateNormalizedSharpness(grayImg, bounds.Dx(), bounds.Dy())
+	result := d.calculateNormalizedSharpness(grayImg, bounds.Dx(), bounds.Dy(), an)
+	result.Orientation = orientation
 	return result, nil
 }
 
-// ============================================================================
-// 正規化鮮明度パイプライン（商用レベル）
-// ============================================================================
+// DetectFaces はデフォルト設定のDetectorで画像データから顔を検出します。
+func DetectFaces(imageData []byte) ([]Face, error) {
+	return getDefaultDetector().DetectFaces(imageData)
+}
 
-const (
-	// 鮮明度計算の基準サイズ（ピクセル）
-	// カメラの画素数に依存しないよう、この大きさに統一してから計算する
-	sharpnessNormalizeSize = 128
+// DetectFacesContext はctxのキャンセルに対応したDetectFacesです。
+func DetectFacesContext(ctx context.Context, imageData []byte) ([]Face, error) {
+	return getDefaultDetector().DetectFacesContext(ctx, imageData)
+}
 
-	// エッジ減衰率計算用のガウシアンブラーのカーネルサイズ
-	edgeDecayBlurKernelSize = 5
+// DrawFaceRects はデフォルト設定のDetectorで検出した最大の顔の周りに枠を描画します。
+func DrawFaceRects(imageData []byte) ([]byte, error) {
+	return getDefaultDetector().DrawFaceRects(imageData)
+}
 
-	// エッジ減衰率計算用のガウシアンブラーのσ値
-	edgeDecayBlurSigma = 2.0
+// DrawFaceRectsContext はctxのキャンセルに対応したDrawFaceRectsです。
+func DrawFaceRectsContext(ctx context.Context, imageData []byte) ([]byte, error) {
+	return getDefaultDetector().DrawFaceRectsContext(ctx, imageData)
+}
 
-	// バイラテラルフィルタのパラメータ
-	bilateralD          = 5    // フィルタの直径
-	bilateralSigmaColor = 50.0 // 色空間のσ
-	bilateralSigmaSpace = 50.0 // 座標空間のσ
+// CropFace はデフォルト設定のDetectorで検出した最大の顔を切り抜きます。
+func CropFace(imageData []byte) ([]byte, error) {
+	return getDefaultDetector().CropFace(imageData)
+}
 
-	// 顔中心マスクの比率（顔矩形に対する中心領域の割合）
-	faceCenterRatio = 0.6
+// CropFaceContext はctxのキャンセルに対応したCropFaceです。
+func CropFaceContext(ctx context.Context, imageData []byte) ([]byte, error) {
+	return getDefaultDetector().CropFaceContext(ctx, imageData)
+}
 
-	// スコアマッピング用パラメータ（シグモイド関数）
-	sigmoidMidpoint  = 0.45 // 減衰率がこの値で約50点
-	sigmoidSteepness = 10.0 // カーブの急峻さ
-)
+// CalculateFaceSharpness はデフォルト設定のDetectorで顔の鮮明度を分析します。
+func CalculateFaceSharpness(imageData []byte, opts ...AnalysisOption) (FaceSharpnessResult, error) {
+	return getDefaultDetector().CalculateFaceSharpness(imageData, opts...)
+}
+
+// CalculateFaceSharpnessContext はctxのキャンセルに対応したCalculateFaceSharpnessです。
+func CalculateFaceSharpnessContext(ctx context.Context, imageData []byte, opts ...AnalysisOption) (FaceSharpnessResult, error) {
+	return getDefaultDetector().CalculateFaceSharpnessContext(ctx, imageData, opts...)
+}
+
+// CalculateSharpness はデフォルト設定のDetectorで画像全体の鮮明度を分析します。
+func CalculateSharpness(imageData []byte, opts ...AnalysisOption) (SharpnessResult, error) {
+	return getDefaultDetector().CalculateSharpness(imageData, opts...)
+}
+
+// CalculateSharpnessContext はctxのキャンセルに対応したCalculateSharpnessです。
+func CalculateSharpnessContext(ctx context.Context, imageData []byte, opts ...AnalysisOption) (SharpnessResult, error) {
+	return getDefaultDetector().CalculateSharpnessContext(ctx, imageData, opts...)
+}
+
+// ============================================================================
+// 正規化鮮明度パイプライン（商用レベル）
+// ============================================================================
 
 // normalizeSize は2D画像データを基準サイズにリサイズします（Bilinear補間）。
 // カメラの画素数・距離に依存しない評価を実現します。
@@ -1230,13 +1385,13 @@ func normalizeContrast(gray [][]float64) [][]float64 {
 // applyBilateralDenoise はバイラテラルフィルタでカメラノイズを除去します。
 // エッジは保持しつつ、センサーノイズのような微小な輝度変動のみを平滑化します。
 // 簡易実装（純Go、OpenCV不要）: 各ピクセルの周囲を距離と輝度差で重み付き平均します。
-func applyBilateralDenoise(gray [][]float64) [][]float64 {
+func applyBilateralDenoise(gray [][]float64, diameter int, sigmaColor, sigmaSpace float64) [][]float64 {
 	h := len(gray)
 	if h == 0 {
 		return gray
 	}
 	w := len(gray[0])
-	r := bilateralD / 2
+	r := diameter / 2
 	result := make([][]float64, h)
 
 	for y := 0; y < h; y++ {
@@ -1255,8 +1410,8 @@ func applyBilateralDenoise(gray [][]float64) [][]float64 {
 					neighborVal := gray[ny][nx]
 					spatialDist := float64(dx*dx + dy*dy)
 					colorDist := (neighborVal - centerVal) * (neighborVal - centerVal)
-					weight := math.Exp(-spatialDist/(2*bilateralSigmaSpace*bilateralSigmaSpace)) *
-						math.Exp(-colorDist/(2*bilateralSigmaColor*bilateralSigmaColor))
+					weight := math.Exp(-spatialDist/(2*sigmaSpace*sigmaSpace)) *
+						math.Exp(-colorDist/(2*sigmaColor*sigmaColor))
 					sumWeight += weight
 					sumValue += weight * neighborVal
 				}
@@ -1375,7 +1530,7 @@ func applyGaussianBlur2D(gray [][]float64, kernelSize int, sigma float64) [][]fl
 // calculateEdgeDecayRatio はエッジ減衰率を計算します。
 // 元画像と意図的にぼかした画像のエッジ強度の比率を取ることで、
 // 被写体のテクスチャ量に依存しない、純粋な「ピントの合い具合」を測定します。
-func calculateEdgeDecayRatio(gray [][]float64) float64 {
+func calculateEdgeDecayRatio(gray [][]float64, blurKernelSize int, blurSigma float64) float64 {
 	// 元画像のエッジ強度
 	origEnergy := calculateEdgeEnergy(gray)
 	if origEnergy < 1.0 {
@@ -1384,7 +1539,7 @@ func calculateEdgeDecayRatio(gray [][]float64) float64 {
 	}
 
 	// 意図的にぼかした画像のエッジ強度
-	blurred := applyGaussianBlur2D(gray, edgeDecayBlurKernelSize, edgeDecayBlurSigma)
+	blurred := applyGaussianBlur2D(gray, blurKernelSize, blurSigma)
 	blurredEnergy := calculateEdgeEnergy(blurred)
 
 	// 減衰率 = 1 - (ぼかし後のエッジ / 元のエッジ)
@@ -1454,9 +1609,9 @@ func extractFaceCenterRegion(gray [][]float64, ratio float64) [][]float64 {
 
 // decayRatioToScore はエッジ減衰率（0.0〜1.0）を0〜100点に変換します。
 // シグモイド関数で非線形にマッピングし、人間の知覚に近い分布にします。
-func decayRatioToScore(ratio float64) float64 {
+func decayRatioToScore(ratio, midpoint, steepness float64) float64 {
 	// シグモイド: score = 100 / (1 + exp(-steepness * (ratio - midpoint)))
-	score := 100.0 / (1.0 + math.Exp(-sigmoidSteepness*(ratio-sigmoidMidpoint)))
+	score := 100.0 / (1.0 + math.Exp(-steepness*(ratio-midpoint)))
 	if score < 0 {
 		score = 0
 	}
@@ -1485,10 +1640,31 @@ func calculateMeanBrightnessFromGray(gray [][]float64) float64 {
 	return sum / float64(count)
 }
 
+// normalizeAndDenoise は正規化鮮明度パイプラインのステップ1〜3を実行し、
+// コントラスト正規化済みの画像とノイズ除去済みの画像、正規化済みの画像で推定したノイズの標準偏差を返します。
+// size は正規化後の大きさ（ピクセル）です。
+// ノイズ適応が有効な場合はノイズが多いほど強くノイズ除去を行い、無効な場合はノイズの標準偏差を0として返します。
+func (d *Detector) normalizeAndDenoise(gray [][]float64, size int) (normalized, denoised [][]float64, noiseSigma float64) {
+	// ステップ1: サイズ正規化
+	normalized = normalizeSize(gray, size)
+
+	// ステップ2: コントラスト正規化
+	normalized = normalizeContrast(normalized)
+
+	// ステップ3: ノイズ除去
+	diameter, sigmaColor := d.cfg.bilateralD, d.cfg.bilateralSigmaColor
+	if d.cfg.noiseAdaptive {
+		noiseSigma = estimateNoiseSigma(normalized)
+		diameter, sigmaColor = adaptiveBilateralParams(noiseSigma, diameter, sigmaColor)
+	}
+	denoised = applyBilateralDenoise(normalized, diameter, sigmaColor, d.cfg.bilateralSigmaSpace)
+	return normalized, denoised, noiseSigma
+}
+
 // calculateNormalizedSharpness は正規化鮮明度パイプラインの全ステップを統合して実行します。
 // 入力: グレースケール画像（任意サイズ）、元画像の幅・高さ
 // 出力: SharpnessResult
-func calculateNormalizedSharpness(gray [][]float64, origWidth, origHeight int) SharpnessResult {
+func (d *Detector) calculateNormalizedSharpness(gray [][]float64, origWidth, origHeight int, an *analysis) SharpnessResult {
 	// まず生のラプラシアン分散を計算（参考値として返却）
 	rawLaplacian := calculateLaplacianVariance(gray)
 
@@ -1498,36 +1674,60 @@ func calculateNormalizedSharpness(gray [][]float64, origWidth, origHeight int) S
 	// ブレ推定（正規化前の生データで）
 	rawBlurLevel := rawLaplacian // ラプラシアン分散がそのままブレ推定値
 
-	// ステップ1: サイズ正規化
-	normalized := normalizeSize(gray, sharpnessNormalizeSize)
-	analyzedSize := sharpnessNormalizeSize
-
-	// ステップ2: コントラスト正規化
-	normalized = normalizeContrast(normalized)
-
-	// ステップ3: ノイズ除去
-	denoised := applyBilateralDenoise(normalized)
+	// ステップ1〜3: サイズ正規化・コントラスト正規化・ノイズ除去
+	analyzedSize := d.cfg.sharpnessNormalizeSize
+	if an.normalizeSize > 0 {
+		analyzedSize = an.normalizeSize
+	}
+	normalized, denoised, noiseSigma := d.normalizeAndDenoise(gray, analyzedSize)
 
 	// Tenengrad法の生値（正規化済み画像に対して計算）
 	rawTenengrad := calculateTenengradVariance(denoised)
 
-	// ステップ4: エッジ減衰率（相対評価）
-	edgeDecay := calculateEdgeDecayRatio(denoised)
+	// ステップ4: エッジ減衰率（相対評価、ノイズの多い画像ではノイズ分を補正）
+	edgeDecay := noiseCompensatedEdgeDecay(normalized, denoised, noiseSigma, d.cfg.edgeDecayBlurKernelSize, d.cfg.edgeDecayBlurSigma)
 
-	// スコア変換（0〜100点）
-	score := decayRatioToScore(edgeDecay)
+	// 周波数領域の高周波エネルギー比（ノイズ除去前の正規化済み画像で計算）
+	freqRatio := calculateFrequencyEnergyRatio(normalized, d.cfg.frequencyCutoff)
+
+	// スコア変換（0〜100点）: デフォルトはエッジ減衰率、指定があれば別の指標を使用
+	in := MetricInput{Region: gray, Normalized: normalized, Denoised: denoised, NoiseSigma: noiseSigma}
+	score := decayRatioToScore(edgeDecay, d.cfg.sigmoidMidpoint, d.cfg.sigmoidSteepness)
+	if _, ok := an.scoreBasis.(edgeDecayMetric); !ok {
+		score = math.Round(an.scoreBasis.Measure(in).Score*10) / 10
+	}
+
+	// ブレの種類（手ブレ / ピンボケ）の分類
+	blur := classifyBlurType(gray, normalized, denoised, score < d.cfg.blurThreshold, d.cfg.motionCoherenceThreshold)
+
+	// 知覚的鮮明度と元の解像度のノイズは顔の中心領域・画像全体でのみ計算（部位では省略）
+	perceptual, rawNoise := 0.0, 0.0
+	if !an.region {
+		perceptual = math.Round(calculateCPBD(gray)*1000) / 10
+		rawNoise = math.Round(estimateNoiseSigma(gray)*100) / 100
+	}
 
 	return SharpnessResult{
 		NormalizedScore:      score,
+		ScoreBasis:           an.scoreBasis.Name(),
 		RawLaplacianVariance: math.Round(rawLaplacian*1000) / 1000,
 		RawTenengradVariance: math.Round(rawTenengrad*1000) / 1000,
 		EdgeDecayRatio:       math.Round(edgeDecay*10000) / 10000,
+		FrequencyEnergyRatio: math.Round(freqRatio*10000) / 10000,
+		PerceptualBlurScore:  perceptual,
+		BlurType:             blur.blurType,
+		MotionAngleDeg:       blur.angleDeg,
+		MotionLengthPx:       blur.lengthPx,
 		MeanBrightness:       math.Round(meanBrightness*10) / 10,
+		NoiseSigma:           math.Round(noiseSigma*100) / 100,
+		RawNoiseSigma:        rawNoise,
+		Exposure:             d.analyzeExposure(gray),
 		EstimatedBlurLevel:   math.Round(rawBlurLevel*1000) / 1000,
 		OriginalWidth:        origWidth,
 		OriginalHeight:       origHeight,
 		AnalyzedWidth:        analyzedSize,
 		AnalyzedHeight:       analyzedSize,
+		Metrics:              measureMetrics(an.metrics, in),
 	}
 }
 
@@ -1535,7 +1735,6 @@ func calculateNormalizedSharpness(gray [][]float64, origWidth, origHeight int) S
 // 画像解析ヘルパー
 // ============================================================================
 
-
 // convertToGrayscale は画像をグレースケールに変換します
 func convertToGrayscale(img image.Image) [][]float64 {
 	bounds := img.Bounds()
@@ -1594,4 +1793,3 @@ func calculateLaplacianVariance(gray [][]float64) float64 {
 
 	return math.Abs(variance)
 }
-
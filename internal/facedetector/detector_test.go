@@ -1,6 +1,9 @@
 package facedetector
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"image"
 	"os"
 	"sync"
@@ -43,6 +46,34 @@ func TestCropFace_Selfie(t *testing.T) {
 	}
 }
 
+func TestDetectFaces_Selfie(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/selfie1.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	faces, err := DetectFaces(imageData)
+	if err != nil {
+		t.Fatalf("DetectFaces failed: %v", err)
+	}
+	if len(faces) == 0 {
+		t.Fatal("Expected at least one face")
+	}
+
+	for i, f := range faces {
+		if f.Rect.Empty() {
+			t.Errorf("Face %d has empty rect", i)
+		}
+		if f.Source != SourceDNN && f.Source != SourceCascade {
+			t.Errorf("Face %d has unexpected source %q", i, f.Source)
+		}
+		if f.Phase == "" {
+			t.Errorf("Face %d has no phase", i)
+		}
+		t.Logf("Face %d: rect=%v confidence=%.3f source=%s phase=%s", i, f.Rect, f.Confidence, f.Source, f.Phase)
+	}
+}
+
 func TestDrawFaceRects_LegacyFace(t *testing.T) {
 	imageData, err := os.ReadFile("testdata/face.jpg")
 	if err != nil {
@@ -78,6 +109,26 @@ func TestDrawFaceRects_EmptyData(t *testing.T) {
 	}
 }
 
+func TestDetectFacesContext_Canceled(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/selfie1.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := DetectFacesContext(ctx, imageData); !errors.Is(err, context.Canceled) {
+		t.Errorf("DetectFacesContext() error = %v, want context.Canceled", err)
+	}
+	if _, err := CalculateFaceSharpnessContext(ctx, imageData); !errors.Is(err, context.Canceled) {
+		t.Errorf("CalculateFaceSharpnessContext() error = %v, want context.Canceled", err)
+	}
+	if _, err := CalculateSharpnessContext(ctx, imageData); !errors.Is(err, context.Canceled) {
+		t.Errorf("CalculateSharpnessContext() error = %v, want context.Canceled", err)
+	}
+}
+
 func TestCalculateSharpness_EmptyData(t *testing.T) {
 	_, err := CalculateSharpness([]byte{})
 	if err == nil {
@@ -128,9 +179,17 @@ func TestCalculateFaceSharpness_SharpFace(t *testing.T) {
 	if result.NormalizedScore > 100 {
 		t.Fatalf("Normalized score should be <= 100, got %f", result.NormalizedScore)
 	}
+	if result.FaceCount == 0 || result.Index >= result.FaceCount {
+		t.Fatalf("Unexpected face index %d for face count %d", result.Index, result.FaceCount)
+	}
+	if result.Face.Rect.Empty() || result.Face.Source == "" || result.Face.Phase == "" {
+		t.Fatalf("Expected face metadata, got %+v", result.Face)
+	}
 	t.Logf("Face normalized score: %.1f, EdgeDecay: %.4f, RawLaplacian: %.3f, OrigSize: %dx%d, AnalyzedSize: %dx%d",
 		result.NormalizedScore, result.EdgeDecayRatio, result.RawLaplacianVariance,
 		result.OriginalWidth, result.OriginalHeight, result.AnalyzedWidth, result.AnalyzedHeight)
+	t.Logf("Face rect: %v, source: %s, phase: %s, confidence: %.3f, faces: %d",
+		result.Face.Rect, result.Face.Source, result.Face.Phase, result.Face.Confidence, result.FaceCount)
 }
 
 // ============================================================================
@@ -291,9 +350,10 @@ func TestHasValidAspectRatio(t *testing.T) {
 		{"zero height", image.Rect(0, 0, 100, 0), false},
 	}
 
+	d := New()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := hasValidAspectRatio(tt.rect)
+			result := d.hasValidAspectRatio(tt.rect)
 			if result != tt.expect {
 				t.Errorf("hasValidAspectRatio(%v) = %v, want %v", tt.rect, result, tt.expect)
 			}
@@ -313,9 +373,10 @@ func TestHasMinimumSize(t *testing.T) {
 		{"too small width", image.Rect(0, 0, 10, 50), false},
 	}
 
+	d := New()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := hasMinimumSize(tt.rect)
+			result := d.hasMinimumSize(tt.rect)
 			if result != tt.expect {
 				t.Errorf("hasMinimumSize(%v) = %v, want %v", tt.rect, result, tt.expect)
 			}
@@ -323,6 +384,65 @@ func TestHasMinimumSize(t *testing.T) {
 	}
 }
 
+func TestMeetsMinimumSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		rect   image.Rectangle
+		phase  DetectionPhase
+		expect bool
+	}{
+		{"minimum size", image.Rect(0, 0, 20, 20), PhasePreprocessed, true},
+		{"small face", image.Rect(0, 0, 10, 10), PhasePreprocessed, false},
+		{"small face found by upscaling", image.Rect(0, 0, 10, 10), PhaseUpscaled, true},
+		{"smallest upscaled face", image.Rect(0, 0, 6, 6), PhaseUpscaled, true},
+		{"too small even for upscaling", image.Rect(0, 0, 5, 5), PhaseUpscaled, false},
+	}
+
+	d := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			det := detectionWithConfidence{rect: tt.rect, phase: tt.phase}
+			if got := d.meetsMinimumSize(det); got != tt.expect {
+				t.Errorf("meetsMinimumSize(%v, %s) = %v, want %v", tt.rect, tt.phase, got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	d := New(
+		WithMinFaceSize(40),
+		WithAspectRatioRange(0.8, 1.2),
+		WithCascadeFiles("a.xml", "b.xml"),
+	)
+
+	if d.hasMinimumSize(image.Rect(0, 0, 30, 30)) {
+		t.Error("Expected 30x30 rect to be rejected with WithMinFaceSize(40)")
+	}
+	if d.hasValidAspectRatio(image.Rect(0, 0, 70, 100)) {
+		t.Error("Expected aspect ratio 0.7 to be rejected with WithAspectRatioRange(0.8, 1.2)")
+	}
+	if len(d.cfg.cascadeFiles) != 2 {
+		t.Errorf("Expected 2 cascade files, got %d", len(d.cfg.cascadeFiles))
+	}
+
+	// 他のDetectorの設定に影響しないことを確認
+	if !New().hasMinimumSize(image.Rect(0, 0, 30, 30)) {
+		t.Error("Expected default detector to accept 30x30 rect")
+	}
+}
+
+func TestNew_MissingDNNModelFiles(t *testing.T) {
+	d := New(WithDNNModelFiles("testdata/missing.prototxt", "testdata/missing.caffemodel"))
+	dnn, ok := d.backends[0].(*DNNDetector)
+	if !ok {
+		t.Fatalf("Expected first backend to be *DNNDetector, got %T", d.backends[0])
+	}
+	if dnn.Available() {
+		t.Errorf("Expected DNN backend to be unavailable, got paths %q, %q", dnn.protoPath, dnn.modelPath)
+	}
+}
+
 func TestCalculateIoU(t *testing.T) {
 	tests := []struct {
 		name string
@@ -368,6 +488,40 @@ func TestNonMaxSuppression(t *testing.T) {
 	}
 }
 
+func TestLargestFace(t *testing.T) {
+	faces := []Face{
+		{Rect: image.Rect(0, 0, 50, 50), Confidence: 0.9},
+		{Rect: image.Rect(100, 100, 220, 180), Confidence: 0.6},
+		{Rect: image.Rect(300, 300, 380, 380), Confidence: 0.8},
+	}
+
+	largest, ok := largestFace(faces)
+	if !ok {
+		t.Fatal("Expected a largest face")
+	}
+	// 120x80=9600 が 80x80=6400 より大きい（正方形に補正しない）
+	if largest.Rect != image.Rect(100, 100, 220, 180) {
+		t.Errorf("largestFace() = %v, want %v", largest.Rect, image.Rect(100, 100, 220, 180))
+	}
+
+	if _, ok := largestFace(nil); ok {
+		t.Error("Expected no largest face for empty input")
+	}
+}
+
+func TestFace_MarshalJSON(t *testing.T) {
+	f := Face{Rect: image.Rect(10, 20, 110, 150), Confidence: 0.5, Source: SourceDNN, Phase: PhaseRaw}
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+
+	want := `{"bounding_box":{"x":10,"y":20,"width":100,"height":130},"confidence":0.5,"source":"dnn","phase":"raw"}`
+	if string(data) != want {
+		t.Errorf("json.Marshal(Face) = %s, want %s", data, want)
+	}
+}
+
 func TestClipRect(t *testing.T) {
 	bounds := image.Rect(0, 0, 640, 480)
 
@@ -0,0 +1,59 @@
+package facedetector
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ============================================================================
+// エラー定義
+// ============================================================================
+
+// 呼び出し側が errors.Is で判別できるセンチネルエラーです。
+// 下位のエラー（デコーダのエラーなど）は %w でラップされるため、errors.As で取り出せます。
+var (
+	// ErrEmptyImage は画像データが空の場合に返されます。
+	ErrEmptyImage = errors.New("画像データが空です")
+
+	// ErrDecode は画像のデコードに失敗した場合に返されます（DecodeErrorでラップされます）。
+	ErrDecode = errors.New("画像のデコードに失敗しました")
+
+	// ErrNoFace は画像から顔が検出されなかった場合に返されます。
+	ErrNoFace = errors.New("顔が検出されませんでした")
+
+	// ErrFaceTooSmall は検出された顔が処理に必要なサイズに満たない場合に返されます。
+	ErrFaceTooSmall = errors.New("適切なサイズの顔が検出されませんでした")
+
+	// ErrNotFrontal はWithFrontalOnlyを指定し、正面を向いた顔が無かった場合に返されます。
+	ErrNotFrontal = errors.New("正面を向いた顔が検出されませんでした")
+
+	// ErrUnknownMetric は登録されていない鮮明度指標の名前が指定された場合に返されます。
+	ErrUnknownMetric = errors.New("不明な鮮明度指標です")
+
+	// ErrModelUnavailable はDNNモデルもHaar Cascade分類器も読み込めず、顔検出を実行できない場合や、
+	// WithFrontalOnlyを指定したがLBF Facemarkモデルが無く頭部姿勢を推定できない場合に返されます。
+	ErrModelUnavailable = errors.New("顔検出モデルが利用できません")
+)
+
+// DecodeError は画像のデコード失敗を表します。
+// errors.Is(err, ErrDecode) が真になり、Errで元のエラーを取り出せます。
+type DecodeError struct {
+	// Decoder はデコードに使用した実装（"image" はGo標準ライブラリ、"opencv" はOpenCVのMatへの変換）。
+	Decoder string
+	// Err はデコーダが返した元のエラー。
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", ErrDecode.Error(), e.Decoder, e.Err)
+}
+
+// Unwrap は元のエラーを返します。
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is は errors.Is(err, ErrDecode) を真にします。
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
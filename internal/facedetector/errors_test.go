@@ -0,0 +1,102 @@
+package facedetector
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+)
+
+// uniformPNG は顔を含まない単色のPNG画像データを生成します。
+func uniformPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestErrors_EmptyImage(t *testing.T) {
+	if _, err := DetectFaces(nil); !errors.Is(err, ErrEmptyImage) {
+		t.Errorf("DetectFaces(nil) error = %v, want ErrEmptyImage", err)
+	}
+	if _, err := CalculateSharpness([]byte{}); !errors.Is(err, ErrEmptyImage) {
+		t.Errorf("CalculateSharpness(empty) error = %v, want ErrEmptyImage", err)
+	}
+}
+
+func TestErrors_Decode(t *testing.T) {
+	data, err := os.ReadFile("../../LICENSE")
+	if err != nil {
+		t.Fatalf("Failed to read non-image file: %v", err)
+	}
+
+	_, err = CalculateFaceSharpness(data)
+	if !errors.Is(err, ErrDecode) {
+		t.Fatalf("CalculateFaceSharpness(LICENSE) error = %v, want ErrDecode", err)
+	}
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %T", err)
+	}
+	if !errors.Is(decodeErr.Err, image.ErrFormat) {
+		t.Errorf("Expected wrapped image.ErrFormat, got %v", decodeErr.Err)
+	}
+}
+
+func TestErrors_NoFace(t *testing.T) {
+	data := uniformPNG(t, 200, 200)
+
+	if _, err := CalculateFaceSharpness(data); !errors.Is(err, ErrNoFace) {
+		t.Errorf("CalculateFaceSharpness(uniform) error = %v, want ErrNoFace", err)
+	}
+	if _, err := CropFace(data); !errors.Is(err, ErrNoFace) {
+		t.Errorf("CropFace(uniform) error = %v, want ErrNoFace", err)
+	}
+}
+
+func TestErrors_FaceTooSmall(t *testing.T) {
+	data := uniformPNG(t, 200, 200)
+	for _, rotationRetry := range []bool{false, true} {
+		stub := &stubDetector{rects: []image.Rectangle{image.Rect(40, 40, 52, 52)}}
+		d := New(WithBackends(stub), WithRotationRetry(rotationRetry))
+
+		if _, err := d.DetectFaces(data); !errors.Is(err, ErrFaceTooSmall) {
+			t.Errorf("rotationRetry=%v: DetectFaces(small face) error = %v, want ErrFaceTooSmall", rotationRetry, err)
+		}
+		if _, err := d.CalculateFaceSharpness(data); !errors.Is(err, ErrFaceTooSmall) {
+			t.Errorf("rotationRetry=%v: CalculateFaceSharpness(small face) error = %v, want ErrFaceTooSmall", rotationRetry, err)
+		}
+	}
+}
+
+func TestErrors_ModelUnavailable(t *testing.T) {
+	d := New(
+		WithDNNModelFiles("testdata/missing.prototxt", "testdata/missing.caffemodel"),
+		WithCascadeFiles("testdata/missing.xml"),
+	)
+
+	_, err := d.DetectFaces(uniformPNG(t, 64, 64))
+	if !errors.Is(err, ErrModelUnavailable) {
+		t.Errorf("DetectFaces() error = %v, want ErrModelUnavailable", err)
+	}
+}
+
+func TestDecodeError_Message(t *testing.T) {
+	err := &DecodeError{Decoder: "image", Err: image.ErrFormat}
+	want := "画像のデコードに失敗しました (image): image: unknown format"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, ErrDecode) {
+		t.Error("Expected errors.Is(err, ErrDecode) to be true")
+	}
+}
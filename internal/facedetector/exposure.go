@@ -0,0 +1,138 @@
+package facedetector
+
+import "math"
+
+// ============================================================================
+// 露出の診断
+// ============================================================================
+
+const (
+	// exposureShadowLevel 以下の画素を黒つぶれとみなす
+	exposureShadowLevel = 5
+	// exposureHighlightLevel 以上の画素を白飛びとみなす
+	exposureHighlightLevel = 250
+)
+
+// ExposureVerdict は露出の判定です。
+type ExposureVerdict string
+
+const (
+	// ExposureUnder は露出不足（暗すぎる・黒つぶれが多い）
+	ExposureUnder ExposureVerdict = "under"
+	// ExposureOver は露出過多（明るすぎる・白飛びが多い）
+	ExposureOver ExposureVerdict = "over"
+	// ExposureOK は適正露出
+	ExposureOK ExposureVerdict = "ok"
+)
+
+// ExposurePercentiles は輝度ヒストグラムのパーセンタイル（0〜255）です。
+type ExposurePercentiles struct {
+	P1  float64 `json:"p1"`
+	P5  float64 `json:"p5"`
+	P25 float64 `json:"p25"`
+	P50 float64 `json:"p50"`
+	P75 float64 `json:"p75"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// Exposure は輝度ヒストグラムによる露出の診断結果です。
+type Exposure struct {
+	// MeanBrightness は平均輝度（0〜255）。
+	MeanBrightness float64 `json:"mean_brightness"`
+
+	// ShadowClipPercent は黒つぶれ（輝度5以下）の画素の割合（%）。
+	ShadowClipPercent float64 `json:"shadow_clip_percent"`
+
+	// HighlightClipPercent は白飛び（輝度250以上）の画素の割合（%）。
+	HighlightClipPercent float64 `json:"highlight_clip_percent"`
+
+	// Percentiles は輝度のパーセンタイル。
+	Percentiles ExposurePercentiles `json:"percentiles"`
+
+	// DynamicRange は輝度の1〜99パーセンタイルの幅（0〜255）。白飛びした顔などで小さくなります。
+	DynamicRange float64 `json:"dynamic_range"`
+
+	// Verdict は露出の判定。平均輝度をWithBrightnessThresholdsの閾値と、
+	// 白飛び・黒つぶれの割合をWithExposureClipPercentの上限と比較します。
+	Verdict ExposureVerdict `json:"exposure_verdict"`
+}
+
+// analyzeExposure はグレースケール画像の輝度ヒストグラムから露出を診断します。
+// 画素がない場合はゼロ値（Verdictは空）を返します。
+func (d *Detector) analyzeExposure(gray [][]float64) Exposure {
+	var histogram [256]int
+	total := 0
+	sum := 0.0
+	for _, row := range gray {
+		for _, v := range row {
+			histogram[int(math.Max(0, math.Min(255, math.Round(v))))]++
+			sum += v
+			total++
+		}
+	}
+	if total == 0 {
+		return Exposure{}
+	}
+
+	shadows, highlights := 0, 0
+	for level, count := range histogram {
+		if level <= exposureShadowLevel {
+			shadows += count
+		}
+		if level >= exposureHighlightLevel {
+			highlights += count
+		}
+	}
+
+	percentile := func(p float64) float64 {
+		target := p / 100 * float64(total)
+		cumulative := 0
+		for level, count := range histogram {
+			cumulative += count
+			if float64(cumulative) >= target {
+				return float64(level)
+			}
+		}
+		return 255
+	}
+
+	e := Exposure{
+		MeanBrightness:       math.Round(sum/float64(total)*10) / 10,
+		ShadowClipPercent:    math.Round(float64(shadows)/float64(total)*1000) / 10,
+		HighlightClipPercent: math.Round(float64(highlights)/float64(total)*1000) / 10,
+		Percentiles: ExposurePercentiles{
+			P1:  percentile(1),
+			P5:  percentile(5),
+			P25: percentile(25),
+			P50: percentile(50),
+			P75: percentile(75),
+			P95: percentile(95),
+			P99: percentile(99),
+		},
+	}
+	e.DynamicRange = e.Percentiles.P99 - e.Percentiles.P1
+	e.Verdict = exposureVerdict(e, d.cfg.darkThreshold, d.cfg.brightThreshold, d.cfg.exposureClipPercent)
+	return e
+}
+
+// exposureVerdict は平均輝度と白飛び・黒つぶれの割合から露出を判定します。
+// 白飛びと黒つぶれが両方とも上限を超える場合（強いコントラスト）は、割合の大きい方を採用します。
+func exposureVerdict(e Exposure, darkThreshold, brightThreshold, clipPercent float64) ExposureVerdict {
+	over := e.MeanBrightness > brightThreshold || e.HighlightClipPercent >= clipPercent
+	under := e.MeanBrightness < darkThreshold || e.ShadowClipPercent >= clipPercent
+
+	switch {
+	case over && under:
+		if e.HighlightClipPercent >= e.ShadowClipPercent {
+			return ExposureOver
+		}
+		return ExposureUnder
+	case over:
+		return ExposureOver
+	case under:
+		return ExposureUnder
+	default:
+		return ExposureOK
+	}
+}
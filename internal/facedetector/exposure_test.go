@@ -0,0 +1,109 @@
+package facedetector
+
+import (
+	"os"
+	"testing"
+)
+
+// constantGray は全ての画素が value のグレースケール画像を返します。
+func constantGray(w, h int, value float64) [][]float64 {
+	gray := make([][]float64, h)
+	for y := range gray {
+		gray[y] = make([]float64, w)
+		for x := range gray[y] {
+			gray[y][x] = value
+		}
+	}
+	return gray
+}
+
+func TestAnalyzeExposure_Gradient(t *testing.T) {
+	// 0〜255の水平グラデーション（各輝度が同じ数だけ存在）
+	gray := make([][]float64, 10)
+	for y := range gray {
+		gray[y] = make([]float64, 256)
+		for x := range gray[y] {
+			gray[y][x] = float64(x)
+		}
+	}
+
+	e := New().analyzeExposure(gray)
+	if e.Verdict != ExposureOK {
+		t.Errorf("Verdict = %q, want %q", e.Verdict, ExposureOK)
+	}
+	if e.Percentiles.P50 != 127 {
+		t.Errorf("P50 = %.0f, want 127", e.Percentiles.P50)
+	}
+	if e.Percentiles.P1 > e.Percentiles.P5 || e.Percentiles.P5 > e.Percentiles.P25 ||
+		e.Percentiles.P75 > e.Percentiles.P95 || e.Percentiles.P95 > e.Percentiles.P99 {
+		t.Errorf("Percentiles are not monotonic: %+v", e.Percentiles)
+	}
+	if e.DynamicRange < 240 {
+		t.Errorf("Expected wide dynamic range, got %.0f", e.DynamicRange)
+	}
+	// 0〜5 と 250〜255 はそれぞれ6/256 ≒ 2.3%
+	if e.ShadowClipPercent != 2.3 || e.HighlightClipPercent != 2.3 {
+		t.Errorf("Clip = %.1f%% / %.1f%%, want 2.3%% / 2.3%%", e.ShadowClipPercent, e.HighlightClipPercent)
+	}
+}
+
+func TestAnalyzeExposure_Verdicts(t *testing.T) {
+	d := New()
+
+	over := d.analyzeExposure(constantGray(32, 32, 252))
+	if over.Verdict != ExposureOver || over.HighlightClipPercent != 100 || over.DynamicRange != 0 {
+		t.Errorf("Expected washed-out image to be over-exposed, got %+v", over)
+	}
+
+	under := d.analyzeExposure(constantGray(32, 32, 40))
+	if under.Verdict != ExposureUnder {
+		t.Errorf("Expected dark image to be under-exposed, got %+v", under)
+	}
+
+	if got := d.analyzeExposure(nil); got.Verdict != "" {
+		t.Errorf("Expected zero value for empty image, got %+v", got)
+	}
+}
+
+func TestExposureVerdict_HighContrast(t *testing.T) {
+	// 白飛びと黒つぶれが両方多い場合は割合の大きい方
+	e := Exposure{MeanBrightness: 128, ShadowClipPercent: 20, HighlightClipPercent: 30}
+	if got := exposureVerdict(e, 80, 180, 5); got != ExposureOver {
+		t.Errorf("exposureVerdict = %q, want %q", got, ExposureOver)
+	}
+	e.ShadowClipPercent = 40
+	if got := exposureVerdict(e, 80, 180, 5); got != ExposureUnder {
+		t.Errorf("exposureVerdict = %q, want %q", got, ExposureUnder)
+	}
+}
+
+func TestWithExposureClipPercent(t *testing.T) {
+	// 輝度252の画素が10%の画像は、上限5%では露出過多、上限20%では適正
+	gray := constantGray(10, 10, 128)
+	gray[0] = constantGray(10, 1, 252)[0]
+
+	if got := New().analyzeExposure(gray).Verdict; got != ExposureOver {
+		t.Errorf("Default verdict = %q, want %q", got, ExposureOver)
+	}
+	if got := New(WithExposureClipPercent(20)).analyzeExposure(gray).Verdict; got != ExposureOK {
+		t.Errorf("Verdict with 20%% limit = %q, want %q", got, ExposureOK)
+	}
+}
+
+func TestCalculateSharpness_Exposure(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/test.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := CalculateSharpness(imageData)
+	if err != nil {
+		t.Fatalf("CalculateSharpness failed: %v", err)
+	}
+	if result.Exposure.Verdict == "" {
+		t.Error("Expected exposure verdict to be set")
+	}
+	if result.Exposure.MeanBrightness != result.MeanBrightness {
+		t.Errorf("Exposure.MeanBrightness = %.1f, want %.1f", result.Exposure.MeanBrightness, result.MeanBrightness)
+	}
+}
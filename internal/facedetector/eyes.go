@@ -0,0 +1,182 @@
+package facedetector
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================================
+// 目の開閉（まばたき）の判定
+// ============================================================================
+
+const (
+	// eyeAspectRatioFullConfidence は目のアスペクト比（EAR）が閾値からこの値以上離れている場合に
+	// 信頼度を1とする距離
+	eyeAspectRatioFullConfidence = 0.1
+
+	// 目のHaar Cascadeで判定した場合の信頼度（両目・片目を検出した場合）。
+	// カスケードは眼鏡や低解像度でも目を見逃すため、特徴点による判定より低くする
+	eyeCascadeBothConfidence = 0.8
+	eyeCascadeOneConfidence  = 0.6
+
+	// eyeSearchHeight は目のHaar Cascadeで探索する顔矩形の上側の割合
+	eyeSearchHeight = 0.55
+)
+
+// EyeOpenness は顔の目の開閉の推定結果です。
+// 鮮明でも目を閉じているフレームをベストショットの候補から外すために使用します。
+type EyeOpenness struct {
+	// EyesOpen は目が開いているかどうか。
+	EyesOpen bool `json:"eyes_open"`
+
+	// Confidence はEyesOpenの判定の信頼度（0.0〜1.0）。
+	Confidence float64 `json:"confidence"`
+
+	// Source は判定に使用した情報（landmarks: 特徴点の目のアスペクト比、cascade: 目のHaar Cascade）。
+	// 特徴点もカスケード分類器も無い場合や、カスケードで目が見つからない場合は判定できないため空で、
+	// EyesOpenはfalse、Confidenceは0です。
+	Source RegionSource `json:"source,omitempty"`
+
+	// EyeAspectRatio は両目の目のアスペクト比（EAR）の平均。特徴点で判定した場合のみ設定されます。
+	// 開いた目で0.25〜0.35程度、閉じた目で0.1前後になります。
+	EyeAspectRatio float64 `json:"eye_aspect_ratio,omitempty"`
+}
+
+// eyeDetection は目のHaar Cascadeによる画像上の左目・右目の検出結果です。
+type eyeDetection struct {
+	left, right image.Rectangle
+
+	// ran はいずれかのカスケード分類器を読み込んで検出を実行できたかどうか
+	ran bool
+}
+
+// analyzeEyeOpenness は目の開閉を推定します。
+// 特徴点があれば目のアスペクト比（EAR）を閾値と比較し、なければ顔の上側で目のHaar Cascadeが
+// 目を検出したかどうかで判定します（カスケードは主に開いた目に反応するため）。
+// カスケードは眼鏡や低解像度でも目を見逃すため、目が見つからない場合は閉じているとはみなさず、判定できない結果を返します。
+func (d *Detector) analyzeEyeOpenness(face Face, eyes eyeDetection) EyeOpenness {
+	if face.Landmarks.Valid() {
+		ear := (eyeAspectRatio(face.Landmarks.LeftEye()) + eyeAspectRatio(face.Landmarks.RightEye())) / 2
+		return EyeOpenness{
+			EyesOpen:       ear >= d.cfg.eyeAspectRatioThreshold,
+			Confidence:     math.Round((0.5+0.5*math.Min(1, math.Abs(ear-d.cfg.eyeAspectRatioThreshold)/eyeAspectRatioFullConfidence))*100) / 100,
+			Source:         RegionFromLandmarks,
+			EyeAspectRatio: math.Round(ear*1000) / 1000,
+		}
+	}
+
+	found := 0
+	for _, r := range []image.Rectangle{eyes.left, eyes.right} {
+		if !r.Empty() {
+			found++
+		}
+	}
+
+	switch {
+	case !eyes.ran || found == 0:
+		return EyeOpenness{}
+	case found == 2:
+		return EyeOpenness{EyesOpen: true, Confidence: eyeCascadeBothConfidence, Source: RegionFromCascade}
+	default:
+		return EyeOpenness{EyesOpen: true, Confidence: eyeCascadeOneConfidence, Source: RegionFromCascade}
+	}
+}
+
+// eyeAspectRatio は目の6点から目のアスペクト比（EAR: まぶたの縦の開き2箇所の平均 / 目尻から目頭までの幅）を計算します。
+// Soukupová & Čech (2016) の定義に従います。
+func eyeAspectRatio(eye []LandmarkPoint) float64 {
+	if len(eye) != 6 {
+		return 0
+	}
+	dist := func(a, b LandmarkPoint) float64 { return math.Hypot(a.X-b.X, a.Y-b.Y) }
+	width := dist(eye[0], eye[3])
+	if width == 0 {
+		return 0
+	}
+	return (dist(eye[1], eye[5]) + dist(eye[2], eye[4])) / (2 * width)
+}
+
+// defaultEyeCascadeFiles は目のHaar Cascade分類器のデフォルトのファイルパスリスト（OpenCVに同梱の分類器）
+var defaultEyeCascadeFiles = []string{
+	"/usr/share/opencv4/haarcascades/haarcascade_eye_tree_eyeglasses.xml",
+	"/usr/share/opencv4/haarcascades/haarcascade_eye.xml",
+	"/usr/local/share/opencv4/haarcascades/haarcascade_eye_tree_eyeglasses.xml",
+	"/usr/local/share/opencv4/haarcascades/haarcascade_eye.xml",
+}
+
+// detectEyes は顔矩形の上側から目のHaar Cascadeで目を検出し、画像上の左目・右目の矩形
+// （元画像の座標系）を返します。見つからない目は空の矩形です。
+// 顔の中心線のそれぞれの側で最も大きい検出を採用します。
+func (d *Detector) detectEyes(gray [][]float64, faceRect image.Rectangle) eyeDetection {
+	bounds := image.Rect(0, 0, 0, 0)
+	if len(gray) > 0 {
+		bounds = image.Rect(0, 0, len(gray[0]), len(gray))
+	}
+	search := clipRect(image.Rect(faceRect.Min.X, faceRect.Min.Y,
+		faceRect.Max.X, faceRect.Min.Y+int(float64(faceRect.Dy())*eyeSearchHeight)), bounds)
+	minEye := faceRect.Dx() / 10
+	if search.Dx() < regionMinSize || search.Dy() < regionMinSize || minEye < 4 {
+		return eyeDetection{}
+	}
+
+	mat := grayToMat(subRegion(gray, search))
+	defer mat.Close()
+
+	var eyes eyeDetection
+	for _, cascadeFile := range d.cfg.eyeCascadeFiles {
+		pool := d.eyeCascades.get(cascadeFile)
+		classVal := pool.Get()
+		if classVal == nil {
+			continue
+		}
+		eyes.ran = true
+		classifier := classVal.(*gocv.CascadeClassifier)
+		rects := classifier.DetectMultiScaleWithParams(mat, 1.1, 3, 0,
+			image.Point{X: minEye, Y: minEye}, image.Point{X: faceRect.Dx() / 2, Y: faceRect.Dx() / 2})
+		pool.Put(classifier)
+		if len(rects) == 0 {
+			continue
+		}
+
+		// 大きい順に、顔の中心線の左右それぞれで最初の検出を採用
+		sort.Slice(rects, func(i, j int) bool {
+			return rects[i].Dx()*rects[i].Dy() > rects[j].Dx()*rects[j].Dy()
+		})
+		midX := faceRect.Min.X + faceRect.Dx()/2
+		for _, r := range rects {
+			r = r.Add(search.Min)
+			if (r.Min.X+r.Max.X)/2 < midX {
+				if eyes.left.Empty() {
+					eyes.left = r
+				}
+			} else if eyes.right.Empty() {
+				eyes.right = r
+			}
+		}
+		return eyes
+	}
+	return eyes
+}
+
+// grayToMat はグレースケールの2D配列を8ビットのMatに変換します。呼び出し側でClose()する必要があります。
+func grayToMat(gray [][]float64) gocv.Mat {
+	h := len(gray)
+	if h == 0 {
+		return gocv.NewMat()
+	}
+	w := len(gray[0])
+	data := make([]byte, 0, w*h)
+	for _, row := range gray {
+		for _, v := range row {
+			data = append(data, uint8(math.Max(0, math.Min(255, math.Round(v)))))
+		}
+	}
+	mat, err := gocv.NewMatFromBytes(h, w, gocv.MatTypeCV8UC1, data)
+	if err != nil {
+		return gocv.NewMat()
+	}
+	return mat
+}
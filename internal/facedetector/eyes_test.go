@@ -0,0 +1,107 @@
+package facedetector
+
+import (
+	"image"
+	"testing"
+)
+
+// withEyeOpening は目の上下のまぶたの点を、目尻と目頭を結ぶ線からの距離が ratio 倍になるよう動かした特徴点を返します。
+func withEyeOpening(lm Landmarks, ratio float64) Landmarks {
+	out := append(Landmarks(nil), lm...)
+	for _, r := range [][2]int{landmarkLeftEye, landmarkRightEye} {
+		cy := (out[r[0]].Y + out[r[0]+3].Y) / 2
+		for i := r[0]; i < r[1]; i++ {
+			out[i].Y = cy + (out[i].Y-cy)*ratio
+		}
+	}
+	return out
+}
+
+func TestEyeAspectRatio(t *testing.T) {
+	lm := syntheticLandmarks(image.Rect(0, 0, 200, 200))
+
+	// 目の高さは顔の6%、幅は16%: (12 + 12) / (2 * 32)
+	if got := eyeAspectRatio(lm.LeftEye()); got < 0.374 || got > 0.376 {
+		t.Errorf("eyeAspectRatio = %.4f, want 0.375", got)
+	}
+	if got := eyeAspectRatio(make([]LandmarkPoint, 6)); got != 0 {
+		t.Errorf("eyeAspectRatio of degenerate eye = %.4f, want 0", got)
+	}
+}
+
+func TestAnalyzeEyeOpenness_Landmarks(t *testing.T) {
+	rect := image.Rect(0, 0, 200, 200)
+	d := New()
+
+	tests := []struct {
+		name       string
+		opening    float64
+		wantOpen   bool
+		confidence float64
+	}{
+		{"open", 1.0, true, 1},
+		{"closed", 0.2, false, 1},
+		{"half closed", 0.56, true, 0.55},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			face := Face{Rect: rect, Landmarks: withEyeOpening(syntheticLandmarks(rect), tt.opening)}
+			e := d.analyzeEyeOpenness(face, eyeDetection{})
+			if e.EyesOpen != tt.wantOpen || e.Confidence != tt.confidence || e.Source != RegionFromLandmarks {
+				t.Errorf("analyzeEyeOpenness = %+v, want open=%v confidence=%.2f", e, tt.wantOpen, tt.confidence)
+			}
+		})
+	}
+
+	// 閾値を上げると開いた目も閉じていると判定される
+	face := Face{Rect: rect, Landmarks: syntheticLandmarks(rect)}
+	if e := New(WithEyeAspectRatioThreshold(0.4)).analyzeEyeOpenness(face, eyeDetection{}); e.EyesOpen {
+		t.Errorf("Expected closed eyes with threshold 0.4, got %+v", e)
+	}
+}
+
+func TestAnalyzeEyeOpenness_Cascade(t *testing.T) {
+	eye := image.Rect(0, 0, 20, 12)
+	face := Face{Rect: image.Rect(0, 0, 200, 200)}
+	d := New()
+
+	tests := []struct {
+		name string
+		eyes eyeDetection
+		want EyeOpenness
+	}{
+		{"both eyes", eyeDetection{left: eye, right: eye, ran: true}, EyeOpenness{EyesOpen: true, Confidence: 0.8, Source: RegionFromCascade}},
+		{"one eye", eyeDetection{right: eye, ran: true}, EyeOpenness{EyesOpen: true, Confidence: 0.6, Source: RegionFromCascade}},
+		// 目の見逃しを閉じた目とみなさない
+		{"no eyes", eyeDetection{ran: true}, EyeOpenness{}},
+		{"no cascade", eyeDetection{}, EyeOpenness{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.analyzeEyeOpenness(face, tt.eyes); got != tt.want {
+				t.Errorf("analyzeEyeOpenness = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectEyes_NoCascade(t *testing.T) {
+	d := New(WithEyeCascadeFiles("testdata/missing_eye_cascade.xml"))
+	eyes := d.detectEyes(constantGray(200, 200, 128), image.Rect(0, 0, 200, 200))
+	if eyes.ran || !eyes.left.Empty() || !eyes.right.Empty() {
+		t.Errorf("Expected no detection without a cascade, got %+v", eyes)
+	}
+}
+
+func TestSummarizeFacesSharpness_EyesClosed(t *testing.T) {
+	faces := []FaceSharpness{
+		{Eyes: EyeOpenness{EyesOpen: true, Confidence: 0.9, Source: RegionFromLandmarks}},
+		{Eyes: EyeOpenness{EyesOpen: false, Confidence: 0.8, Source: RegionFromLandmarks}},
+		{Eyes: EyeOpenness{}}, // 判定できない顔は数えない
+	}
+
+	result := summarizeFacesSharpness(faces, 50)
+	if result.EyesClosedCount != 1 || !result.AnyEyesClosed {
+		t.Errorf("EyesClosedCount = %d, AnyEyesClosed = %v, want 1 / true", result.EyesClosedCount, result.AnyEyesClosed)
+	}
+}
@@ -0,0 +1,54 @@
+#include "facemark_lbf.h"
+
+// LBFFacemark_Create はLBFモデルを読み込んだFacemarkを生成します。
+// モデルファイルを読み込めない場合はNULLを返します。
+LBFFacemark LBFFacemark_Create(const char* modelPath) {
+    try {
+        cv::Ptr<cv::face::Facemark> fm = cv::face::createFacemarkLBF();
+        fm->loadModel(modelPath);
+        return new cv::Ptr<cv::face::Facemark>(fm);
+    } catch (const cv::Exception&) {
+        return NULL;
+    }
+}
+
+void LBFFacemark_Close(LBFFacemark fm) {
+    delete fm;
+}
+
+// LBFFacemark_Fit は8ビットのグレースケール画像の顔矩形（x, y, width, height の並び）ごとに
+// 68点の特徴点を推定し、points に (x, y) の並びで書き込みます。
+// points は numRects * LBF_FACEMARK_POINTS * 2 個の要素を持つ必要があります。
+// 推定できた場合は1、失敗した場合は0を返します。
+int LBFFacemark_Fit(LBFFacemark fm, const unsigned char* gray, int width, int height,
+                    const int* rects, int numRects, float* points) {
+    cv::Mat image(height, width, CV_8UC1, const_cast<unsigned char*>(gray));
+
+    std::vector<cv::Rect> faces;
+    for (int i = 0; i < numRects; ++i) {
+        faces.push_back(cv::Rect(rects[i * 4], rects[i * 4 + 1], rects[i * 4 + 2], rects[i * 4 + 3]));
+    }
+
+    std::vector<std::vector<cv::Point2f> > landmarks;
+    try {
+        if (!(*fm)->fit(image, faces, landmarks)) {
+            return 0;
+        }
+    } catch (const cv::Exception&) {
+        return 0;
+    }
+
+    if ((int)landmarks.size() != numRects) {
+        return 0;
+    }
+    for (int i = 0; i < numRects; ++i) {
+        if ((int)landmarks[i].size() != LBF_FACEMARK_POINTS) {
+            return 0;
+        }
+        for (int j = 0; j < LBF_FACEMARK_POINTS; ++j) {
+            points[(i * LBF_FACEMARK_POINTS + j) * 2] = landmarks[i][j].x;
+            points[(i * LBF_FACEMARK_POINTS + j) * 2 + 1] = landmarks[i][j].y;
+        }
+    }
+    return 1;
+}
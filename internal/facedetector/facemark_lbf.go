@@ -0,0 +1,86 @@
+package facedetector
+
+/*
+#cgo !windows pkg-config: opencv4
+#cgo CXXFLAGS: --std=c++11
+#cgo LDFLAGS: -lopencv_face
+#include <stdlib.h>
+#include "facemark_lbf.h"
+*/
+import "C"
+
+import (
+	"image"
+	"runtime"
+	"unsafe"
+)
+
+// ============================================================================
+// LBF Facemark（OpenCV contrib の face モジュール）のラッパー
+// ============================================================================
+
+// gocv v0.31 の contrib パッケージは Facemark を提供していないため、
+// cv::face::FacemarkLBF を直接呼び出す最小限のcgoラッパーを用意しています。
+
+// lbfFacemark は読み込み済みのLBF Facemarkモデルです。
+type lbfFacemark struct {
+	p C.LBFFacemark
+}
+
+// newLBFFacemark はLBFモデル（lbfmodel.yaml）を読み込みます。
+// 読み込めない場合はnilを返します。
+func newLBFFacemark(modelPath string) *lbfFacemark {
+	cPath := C.CString(modelPath)
+	defer C.free(unsafe.Pointer(cPath))
+
+	p := C.LBFFacemark_Create(cPath)
+	if p == nil {
+		return nil
+	}
+	fm := &lbfFacemark{p: p}
+	runtime.SetFinalizer(fm, (*lbfFacemark).Close)
+	return fm
+}
+
+// Close はモデルを解放します。
+func (fm *lbfFacemark) Close() {
+	if fm.p != nil {
+		C.LBFFacemark_Close(fm.p)
+		fm.p = nil
+	}
+}
+
+// fit は8ビットのグレースケール画像（width x height、行優先）の顔矩形ごとに68点の特徴点を推定します。
+// 推定に失敗した場合はnilを返します。
+func (fm *lbfFacemark) fit(gray []byte, width, height int, rects []image.Rectangle) []Landmarks {
+	if fm.p == nil || len(rects) == 0 || len(gray) < width*height {
+		return nil
+	}
+
+	// 矩形は (x, y, width, height) の並び、特徴点は (x, y) の並びでC側とやり取りする
+	cRects := make([]int32, 0, len(rects)*4)
+	for _, r := range rects {
+		cRects = append(cRects, int32(r.Min.X), int32(r.Min.Y), int32(r.Dx()), int32(r.Dy()))
+	}
+	points := make([]float32, len(rects)*landmarkCount*2)
+
+	ok := C.LBFFacemark_Fit(fm.p,
+		(*C.uchar)(unsafe.Pointer(&gray[0])), C.int(width), C.int(height),
+		(*C.int)(unsafe.Pointer(&cRects[0])), C.int(len(rects)),
+		(*C.float)(unsafe.Pointer(&points[0])))
+	runtime.KeepAlive(fm)
+	if ok == 0 {
+		return nil
+	}
+
+	results := make([]Landmarks, len(rects))
+	for i := range rects {
+		lm := make(Landmarks, landmarkCount)
+		for j := range lm {
+			k := (i*landmarkCount + j) * 2
+			lm[j] = newLandmarkPoint(float64(points[k]), float64(points[k+1]))
+		}
+		results[i] = lm
+	}
+	return results
+}
@@ -0,0 +1,29 @@
+#ifndef _FACEDETECTOR_FACEMARK_LBF_H_
+#define _FACEDETECTOR_FACEMARK_LBF_H_
+
+#ifdef __cplusplus
+#include <opencv2/opencv.hpp>
+#include <opencv2/face.hpp>
+
+extern "C" {
+#endif
+
+#ifdef __cplusplus
+typedef cv::Ptr<cv::face::Facemark>* LBFFacemark;
+#else
+typedef void* LBFFacemark;
+#endif
+
+// 1つの顔あたりの特徴点の数（iBUG 300-W の68点モデル）
+#define LBF_FACEMARK_POINTS 68
+
+LBFFacemark LBFFacemark_Create(const char* modelPath);
+void LBFFacemark_Close(LBFFacemark fm);
+int LBFFacemark_Fit(LBFFacemark fm, const unsigned char* gray, int width, int height,
+                    const int* rects, int numRects, float* points);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif //_FACEDETECTOR_FACEMARK_LBF_H_
@@ -0,0 +1,214 @@
+package facedetector
+
+import (
+	"context"
+	"math"
+)
+
+// ============================================================================
+// 複数顔の鮮明度分析
+// ============================================================================
+
+// FaceSharpness は検出された1つの顔の鮮明度分析結果です。
+// SharpnessResultのフィールドはJSONでは同じ階層に展開されます。
+type FaceSharpness struct {
+	// Index はDetectFacesの結果における顔の位置（0始まり）。
+	Index int `json:"index"`
+
+	// Face は検出された顔の矩形・信頼度・検出器の種類。
+	Face Face `json:"face"`
+
+	SharpnessResult
+
+	// ScoreRegion はNormalizedScoreの算出に使用した顔の領域（WithEyeRegionScoreを指定した場合は eyes）。
+	ScoreRegion ScoreRegion `json:"score_region"`
+
+	// Regions は目・口の部位ごとの鮮明度。
+	Regions FaceRegions `json:"regions"`
+
+	// Eyes は目の開閉（まばたき）の推定結果。
+	Eyes EyeOpenness `json:"eyes"`
+
+	// Pose は特徴点から推定した頭部姿勢。特徴点が無い場合はnil。
+	Pose *HeadPose `json:"pose,omitempty"`
+
+	// Occlusion は顔の部位ごとの肌色の割合によるマスク・サングラス・手などの遮蔽の判定結果。
+	Occlusion Occlusion `json:"occlusion"`
+
+	// Backlight は顔・顔の周囲・画像全体の輝度の比較による逆光の判定結果。
+	Backlight Backlight `json:"backlight"`
+
+	// Lighting は顔の照明の均一性（左右・上下の輝度の偏りと影の割合）の診断結果。
+	Lighting Lighting `json:"lighting"`
+}
+
+// FaceSharpnessResult はCalculateFaceSharpnessの結果です。
+// 鮮明度分析に採用した顔（最も高いスコアの顔）の結果と、検出された顔の数、
+// 顔と背景の鮮明度の比較結果を含みます。
+type FaceSharpnessResult struct {
+	FaceSharpness
+
+	// FaceCount は画像内で検出された顔の数。
+	FaceCount int `json:"face_count"`
+
+	// Orientation は解析前に適用したEXIFの向き（1は回転なし）。顔の座標は適用後の画像の座標系です。
+	Orientation Orientation `json:"orientation"`
+
+	// ImageExposure は画像全体の露出の診断結果（顔の露出は Exposure）。
+	ImageExposure Exposure `json:"image_exposure"`
+
+	// FocusAnalysis は採用した顔と背景（全ての顔以外の領域）の鮮明度の比較結果。
+	FocusAnalysis
+}
+
+// FacesSharpnessResult は画像内の全ての顔の鮮明度分析結果と集計値です。
+// 集合写真で「誰か1人でもブレているか」を判定するために使用します。
+type FacesSharpnessResult struct {
+	// Faces は顔ごとの分析結果（DetectFacesと同じ順序）。
+	// WithFrontalOnlyを指定した場合は正面を向いた顔のみを含み、集計値もそれらの顔から計算します。
+	Faces []FaceSharpness `json:"faces"`
+
+	// FaceCount は検出された顔の数（WithFrontalOnlyで除外した顔を含む）。
+	FaceCount int `json:"face_count"`
+
+	// Orientation は解析前に適用したEXIFの向き（1は回転なし）。顔の座標は適用後の画像の座標系です。
+	Orientation Orientation `json:"orientation"`
+
+	// MinScore / MaxScore / MeanScore は全ての顔のNormalizedScoreの最小・最大・平均。
+	MinScore  float64 `json:"min_score"`
+	MaxScore  float64 `json:"max_score"`
+	MeanScore float64 `json:"mean_score"`
+
+	// Threshold はブレ判定に使用したNormalizedScoreの閾値。
+	Threshold float64 `json:"threshold"`
+
+	// BelowThresholdCount はNormalizedScoreが閾値未満（ブレあり）の顔の数。
+	BelowThresholdCount int `json:"below_threshold_count"`
+
+	// AnyBlurry は閾値未満の顔が1つ以上あるかどうか。
+	AnyBlurry bool `json:"any_blurry"`
+
+	// EyesClosedCount は目を閉じていると推定された顔の数（目の開閉を判定できなかった顔は含みません）。
+	EyesClosedCount int `json:"eyes_closed_count"`
+
+	// AnyEyesClosed は目を閉じている顔が1つ以上あるかどうか。
+	AnyEyesClosed bool `json:"any_eyes_closed"`
+
+	// OccludedCount はマスクや手などで隠れていると判定された顔の数。
+	OccludedCount int `json:"occluded_count"`
+
+	// AnyOccluded は隠れている顔が1つ以上あるかどうか。
+	AnyOccluded bool `json:"any_occluded"`
+
+	// ImageExposure は画像全体の露出の診断結果（顔ごとの露出は各顔の Exposure）。
+	ImageExposure Exposure `json:"image_exposure"`
+}
+
+// CalculateAllFacesSharpness は画像内で検出された全ての顔について鮮明度を分析し、
+// 顔ごとの結果とスコアの集計値を返します。
+func (d *Detector) CalculateAllFacesSharpness(imageData []byte, opts ...AnalysisOption) (FacesSharpnessResult, error) {
+	return d.CalculateAllFacesSharpnessContext(context.Background(), imageData, opts...)
+}
+
+// CalculateAllFacesSharpnessContext はctxのキャンセルに対応したCalculateAllFacesSharpnessです。
+// 顔ごとの鮮明度計算の間にもキャンセルを確認します。
+func (d *Detector) CalculateAllFacesSharpnessContext(ctx context.Context, imageData []byte, opts ...AnalysisOption) (FacesSharpnessResult, error) {
+	an, err := d.newAnalysis(opts)
+	if err != nil {
+		return FacesSharpnessResult{}, err
+	}
+	if err := d.checkFrontalOnly(an); err != nil {
+		return FacesSharpnessResult{}, err
+	}
+
+	img, faces, orientation, err := d.detectFaces(ctx, imageData)
+	if err != nil {
+		return FacesSharpnessResult{}, err
+	}
+
+	if len(faces) == 0 {
+		return FacesSharpnessResult{}, ErrNoFace
+	}
+
+	gray := convertToGrayscale(img)
+	frameLuminance := calculateMeanBrightnessFromGray(gray)
+
+	results := make([]FaceSharpness, 0, len(faces))
+	for i, face := range faces {
+		if err := ctx.Err(); err != nil {
+			return FacesSharpnessResult{}, err
+		}
+
+		// 横を向いた顔は鮮明度を計算する前に除外
+		if an.frontalOnly && !d.isFrontal(face, img.Bounds().Size()) {
+			continue
+		}
+
+		frame := uprightFaceFrame(img, gray, face)
+		result := d.analyzeFaceSharpness(frame, i, an)
+		result.Backlight = d.analyzeBacklight(frame.gray, frameLuminance, frame.face.Rect)
+		result.Lighting = d.analyzeLighting(frame.gray, frame.face.Rect)
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return FacesSharpnessResult{}, ErrNotFrontal
+	}
+
+	result := summarizeFacesSharpness(results, d.cfg.blurThreshold)
+	result.FaceCount = len(faces)
+	result.Orientation = orientation
+	result.ImageExposure = d.analyzeExposure(gray)
+	return result, nil
+}
+
+// summarizeFacesSharpness は顔ごとの結果からスコアの集計値を計算します。
+func summarizeFacesSharpness(faces []FaceSharpness, threshold float64) FacesSharpnessResult {
+	result := FacesSharpnessResult{
+		Faces:     faces,
+		FaceCount: len(faces),
+		Threshold: threshold,
+	}
+	if len(faces) == 0 {
+		return result
+	}
+
+	result.MinScore = math.MaxFloat64
+	result.MaxScore = -math.MaxFloat64
+	sum := 0.0
+	for _, f := range faces {
+		score := f.NormalizedScore
+		sum += score
+		if score < result.MinScore {
+			result.MinScore = score
+		}
+		if score > result.MaxScore {
+			result.MaxScore = score
+		}
+		if score < threshold {
+			result.BelowThresholdCount++
+		}
+		if f.Eyes.Source != "" && !f.Eyes.EyesOpen {
+			result.EyesClosedCount++
+		}
+		if f.Occlusion.Occluded {
+			result.OccludedCount++
+		}
+	}
+	result.MeanScore = math.Round(sum/float64(len(faces))*10) / 10
+	result.AnyBlurry = result.BelowThresholdCount > 0
+	result.AnyEyesClosed = result.EyesClosedCount > 0
+	result.AnyOccluded = result.OccludedCount > 0
+
+	return result
+}
+
+// CalculateAllFacesSharpness はデフォルト設定のDetectorで全ての顔の鮮明度を分析します。
+func CalculateAllFacesSharpness(imageData []byte, opts ...AnalysisOption) (FacesSharpnessResult, error) {
+	return getDefaultDetector().CalculateAllFacesSharpness(imageData, opts...)
+}
+
+// CalculateAllFacesSharpnessContext はctxのキャンセルに対応したCalculateAllFacesSharpnessです。
+func CalculateAllFacesSharpnessContext(ctx context.Context, imageData []byte, opts ...AnalysisOption) (FacesSharpnessResult, error) {
+	return getDefaultDetector().CalculateAllFacesSharpnessContext(ctx, imageData, opts...)
+}
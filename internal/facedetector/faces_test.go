@@ -0,0 +1,95 @@
+package facedetector
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCalculateAllFacesSharpness_Selfie(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/selfie1.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := CalculateAllFacesSharpness(imageData)
+	if err != nil {
+		t.Fatalf("CalculateAllFacesSharpness failed: %v", err)
+	}
+
+	if result.FaceCount == 0 || result.FaceCount != len(result.Faces) {
+		t.Fatalf("Unexpected face count: %d (faces: %d)", result.FaceCount, len(result.Faces))
+	}
+	for i, f := range result.Faces {
+		if f.Index != i {
+			t.Errorf("Face %d has index %d", i, f.Index)
+		}
+		if f.Face.Rect.Empty() {
+			t.Errorf("Face %d has empty bounding box", i)
+		}
+		if f.NormalizedScore < result.MinScore || f.NormalizedScore > result.MaxScore {
+			t.Errorf("Face %d score %.1f outside [%.1f, %.1f]", i, f.NormalizedScore, result.MinScore, result.MaxScore)
+		}
+	}
+}
+
+func TestSummarizeFacesSharpness(t *testing.T) {
+	faces := []FaceSharpness{
+		{Index: 0, SharpnessResult: SharpnessResult{NormalizedScore: 90}},
+		{Index: 1, SharpnessResult: SharpnessResult{NormalizedScore: 30}},
+		{Index: 2, SharpnessResult: SharpnessResult{NormalizedScore: 45}},
+		{Index: 3, SharpnessResult: SharpnessResult{NormalizedScore: 75}},
+	}
+
+	result := summarizeFacesSharpness(faces, 50)
+
+	if result.FaceCount != 4 {
+		t.Errorf("FaceCount = %d, want 4", result.FaceCount)
+	}
+	if result.MinScore != 30 || result.MaxScore != 90 {
+		t.Errorf("MinScore/MaxScore = %.1f/%.1f, want 30/90", result.MinScore, result.MaxScore)
+	}
+	if result.MeanScore != 60 {
+		t.Errorf("MeanScore = %.1f, want 60", result.MeanScore)
+	}
+	if result.BelowThresholdCount != 2 {
+		t.Errorf("BelowThresholdCount = %d, want 2", result.BelowThresholdCount)
+	}
+	if !result.AnyBlurry {
+		t.Error("Expected AnyBlurry to be true")
+	}
+
+	sharp := summarizeFacesSharpness(faces[:1], 50)
+	if sharp.AnyBlurry || sharp.BelowThresholdCount != 0 {
+		t.Errorf("Expected no blurry faces, got %d", sharp.BelowThresholdCount)
+	}
+}
+
+func TestCalculateAllFacesSharpness_FrontalOnly(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/selfie1.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := CalculateAllFacesSharpness(imageData, WithFrontalOnly())
+	if !getDefaultDetector().landmarker.Available() {
+		if !errors.Is(err, ErrModelUnavailable) {
+			t.Fatalf("Expected ErrModelUnavailable without the landmark model, got %v", err)
+		}
+		return
+	}
+	if errors.Is(err, ErrNotFrontal) {
+		return
+	}
+	if err != nil {
+		t.Fatalf("CalculateAllFacesSharpness failed: %v", err)
+	}
+	if len(result.Faces) == 0 || len(result.Faces) > result.FaceCount {
+		t.Fatalf("Unexpected face count: %d (faces: %d)", result.FaceCount, len(result.Faces))
+	}
+	for _, f := range result.Faces {
+		if f.Pose == nil || !f.Pose.Frontal {
+			t.Errorf("Face %d is not frontal: %+v", f.Index, f.Pose)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package facedetector
+
+import (
+	"context"
+	"image"
+	"math"
+	"sort"
+)
+
+// ============================================================================
+// ピント位置の判定（顔 / 背景）
+// ============================================================================
+
+// focusFaceMargin は背景から除外する顔矩形の拡張割合（髪・首など顔と同じ距離の領域を含める）
+const focusFaceMargin = 0.3
+
+// FocusTarget はピントが合っている対象の判定です。
+type FocusTarget string
+
+const (
+	// FocusTargetFace は顔にピントが合っている（背景はボケている）
+	FocusTargetFace FocusTarget = "face"
+	// FocusTargetBackground は背景にピントが合い、顔がボケている（オートフォーカスの失敗）
+	FocusTargetBackground FocusTarget = "background"
+	// FocusTargetUniformBlur は顔も背景もブレている（手ブレ・全体のピンボケ）
+	FocusTargetUniformBlur FocusTarget = "uniform_blur"
+	// FocusTargetUniformSharp は顔も背景も鮮明（パンフォーカス）
+	FocusTargetUniformSharp FocusTarget = "uniform_sharp"
+)
+
+// FocusAnalysis は顔と背景（顔以外の領域）の鮮明度の比較結果です。
+type FocusAnalysis struct {
+	// FocusTarget はピントが合っている対象。
+	FocusTarget FocusTarget `json:"focus_target"`
+
+	// FaceScore は比較に使用した顔のエッジ減衰率スコア（0〜100点）。
+	FaceScore float64 `json:"face_score"`
+
+	// BackgroundScore は背景の鮮明度スコア（0〜100点）。背景のタイルのうち
+	// 鮮明な上位1/4の平均で、背景のどこか1か所にでもピントが合っていれば高くなります。
+	BackgroundScore float64 `json:"background_score"`
+
+	// FaceBackgroundRatio は FaceScore / BackgroundScore。1未満なら背景の方が鮮明です。
+	// 背景を評価できない場合は0。
+	FaceBackgroundRatio float64 `json:"face_background_ratio"`
+}
+
+// analyzeFocus は顔のスコアと、顔以外の領域をタイルに分割して計算した鮮明度を比較します。
+// gray は画像全体のグレースケール画像、faces は検出された全ての顔です（全ての顔を背景から除外します）。
+// タイルはヒートマップと同じ分割ですが、顔に重なるタイルの鮮明度は計算しません。
+func (d *Detector) analyzeFocus(ctx context.Context, gray [][]float64, faces []Face, faceScore float64) (FocusAnalysis, error) {
+	height := len(gray)
+	width := 0
+	if height > 0 {
+		width = len(gray[0])
+	}
+
+	excluded := make([]image.Rectangle, 0, len(faces))
+	for _, f := range faces {
+		excluded = append(excluded, addMargin(f.Rect, focusFaceMargin))
+	}
+
+	var scores []float64
+	for _, row := range tileGrid(width, height, d.cfg.heatmapCols, d.cfg.heatmapRows) {
+		if err := ctx.Err(); err != nil {
+			return FocusAnalysis{}, err
+		}
+		for _, rect := range row {
+			if overlapsAny(rect, excluded) {
+				continue
+			}
+			if tile := d.tileSharpness(gray, rect); !tile.LowTexture {
+				scores = append(scores, tile.Score)
+			}
+		}
+	}
+
+	result := FocusAnalysis{FaceScore: faceScore}
+	if len(scores) == 0 {
+		// 背景を評価できない（顔が画面いっぱい・背景が無地）場合は顔のスコアのみで判定
+		result.FocusTarget = focusVerdict(faceScore, -1, d.cfg.blurThreshold)
+		return result, nil
+	}
+
+	result.BackgroundScore = topQuarterMean(scores)
+	if result.BackgroundScore > 0 {
+		result.FaceBackgroundRatio = math.Round(faceScore/result.BackgroundScore*100) / 100
+	}
+	result.FocusTarget = focusVerdict(faceScore, result.BackgroundScore, d.cfg.blurThreshold)
+	return result, nil
+}
+
+// focusVerdict は顔と背景のスコアをブレ判定閾値と比較してピントの対象を判定します。
+// backgroundScore が負の場合は背景なしとして顔のスコアのみで判定します。
+func focusVerdict(faceScore, backgroundScore, threshold float64) FocusTarget {
+	faceSharp := faceScore >= threshold
+	if backgroundScore < 0 {
+		if faceSharp {
+			return FocusTargetFace
+		}
+		return FocusTargetUniformBlur
+	}
+
+	backgroundSharp := backgroundScore >= threshold
+	switch {
+	case faceSharp && backgroundSharp:
+		return FocusTargetUniformSharp
+	case faceSharp:
+		return FocusTargetFace
+	case backgroundSharp:
+		return FocusTargetBackground
+	default:
+		return FocusTargetUniformBlur
+	}
+}
+
+// topQuarterMean は値の上位1/4（少なくとも1つ）の平均を返します。
+func topQuarterMean(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	n := max(1, len(sorted)/4)
+	sum := 0.0
+	for _, v := range sorted[:n] {
+		sum += v
+	}
+	return math.Round(sum/float64(n)*10) / 10
+}
+
+// overlapsAny は矩形がいずれかの矩形と重なっているかを返します。
+func overlapsAny(rect image.Rectangle, others []image.Rectangle) bool {
+	for _, o := range others {
+		if rect.Overlaps(o) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package facedetector
+
+import (
+	"context"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestFocusVerdict(t *testing.T) {
+	tests := []struct {
+		face, background float64
+		want             FocusTarget
+	}{
+		{80, 20, FocusTargetFace},
+		{20, 80, FocusTargetBackground},
+		{20, 30, FocusTargetUniformBlur},
+		{80, 90, FocusTargetUniformSharp},
+		// 背景なし
+		{80, -1, FocusTargetFace},
+		{20, -1, FocusTargetUniformBlur},
+	}
+	for _, tt := range tests {
+		if got := focusVerdict(tt.face, tt.background, 50); got != tt.want {
+			t.Errorf("focusVerdict(%.0f, %.0f) = %q, want %q", tt.face, tt.background, got, tt.want)
+		}
+	}
+}
+
+func TestTopQuarterMean(t *testing.T) {
+	if got := topQuarterMean([]float64{10, 90, 20, 80, 30, 40, 50, 60}); got != 85 {
+		t.Errorf("topQuarterMean = %.1f, want 85.0", got)
+	}
+	if got := topQuarterMean([]float64{42}); got != 42 {
+		t.Errorf("topQuarterMean single = %.1f, want 42.0", got)
+	}
+}
+
+func TestAnalyzeFocus_BackgroundInFocus(t *testing.T) {
+	// 鮮明な背景の中央に、ぼかした「顔」領域を置く
+	gray := stepEdgeImage(256, 256, 8)
+	blurred := applyGaussianBlur2D(gray, 15, 4.0)
+	faceRect := image.Rect(96, 96, 160, 160)
+	for y := faceRect.Min.Y; y < faceRect.Max.Y; y++ {
+		copy(gray[y][faceRect.Min.X:faceRect.Max.X], blurred[y][faceRect.Min.X:faceRect.Max.X])
+	}
+
+	d := New()
+	faces := []Face{{Rect: faceRect}}
+	focus, err := d.analyzeFocus(context.Background(), gray, faces, 20)
+	if err != nil {
+		t.Fatalf("analyzeFocus failed: %v", err)
+	}
+	t.Logf("focus=%+v", focus)
+
+	if focus.FocusTarget != FocusTargetBackground {
+		t.Errorf("FocusTarget = %q, want %q", focus.FocusTarget, FocusTargetBackground)
+	}
+	if focus.FaceBackgroundRatio <= 0 || focus.FaceBackgroundRatio >= 1 {
+		t.Errorf("Expected face/background ratio in (0, 1), got %.2f", focus.FaceBackgroundRatio)
+	}
+}
+
+func TestAnalyzeFocus_UniformBlur(t *testing.T) {
+	gray := applyGaussianBlur2D(stepEdgeImage(256, 256, 8), 15, 4.0)
+
+	focus, err := New().analyzeFocus(context.Background(), gray, []Face{{Rect: image.Rect(96, 96, 160, 160)}}, 20)
+	if err != nil {
+		t.Fatalf("analyzeFocus failed: %v", err)
+	}
+	if focus.FocusTarget != FocusTargetUniformBlur {
+		t.Errorf("FocusTarget = %q, want %q (background=%.1f)", focus.FocusTarget, FocusTargetUniformBlur, focus.BackgroundScore)
+	}
+}
+
+func TestAnalyzeFocus_NoBackground(t *testing.T) {
+	// 顔が画像全体を覆う場合は顔のスコアのみで判定
+	gray := stepEdgeImage(128, 128, 8)
+
+	focus, err := New().analyzeFocus(context.Background(), gray, []Face{{Rect: image.Rect(0, 0, 128, 128)}}, 80)
+	if err != nil {
+		t.Fatalf("analyzeFocus failed: %v", err)
+	}
+	if focus.FocusTarget != FocusTargetFace || focus.BackgroundScore != 0 || focus.FaceBackgroundRatio != 0 {
+		t.Errorf("Expected face-only verdict without background, got %+v", focus)
+	}
+}
+
+func TestAnalyzeFocus_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := New().analyzeFocus(ctx, stepEdgeImage(64, 64, 8), nil, 80); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package facedetector
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// ============================================================================
+// 周波数領域（FFT）の鮮明度指標
+// ============================================================================
+
+// frequencyRatioHalfScore は高周波エネルギー比が50点となる値。
+// 1/f^2 のスペクトルを持つ鮮明な自然画像はカットオフ0.25で約0.3（75点）になり、
+// ブレで高周波が失われると0.1（50点）を下回ります
+const frequencyRatioHalfScore = 0.1
+
+// frequencyMetric は高周波エネルギー比を指標とします。
+// 空間勾配ベースの指標（ラプラシアン・Tenengrad・エッジ減衰率）とは独立した判定として使用します。
+type frequencyMetric struct {
+	cutoff float64
+}
+
+func (m frequencyMetric) Name() string { return MetricFrequency }
+
+func (m frequencyMetric) Measure(in MetricInput) MetricResult {
+	ratio := calculateFrequencyEnergyRatio(in.Normalized, m.cutoff)
+	return MetricResult{Value: ratio, Score: saturatingScore(ratio, frequencyRatioHalfScore)}
+}
+
+// calculateFrequencyEnergyRatio はパワースペクトルのうち、カットオフ周波数以上の
+// 高周波成分が占めるエネルギーの割合（0.0〜1.0）を計算します。
+// cutoff はナイキスト周波数に対する割合（0.0〜1.0）です。
+//
+// ブレた画像は高周波成分が失われるため値が小さくなります。平均値を除去してから
+// ハン窓を掛けることで、直流成分と画像端の不連続による漏れの影響を抑えます。
+func calculateFrequencyEnergyRatio(gray [][]float64, cutoff float64) float64 {
+	h := len(gray)
+	if h < 2 {
+		return 0
+	}
+	w := len(gray[0])
+	if w < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			mean += gray[y][x]
+		}
+	}
+	mean /= float64(h * w)
+
+	// FFTのサイズは2のべき乗（足りない部分はゼロ埋め）
+	fh, fw := nextPowerOfTwo(h), nextPowerOfTwo(w)
+	spectrum := make([][]complex128, fh)
+	for y := 0; y < fh; y++ {
+		spectrum[y] = make([]complex128, fw)
+		if y >= h {
+			continue
+		}
+		wy := hannWindow(y, h)
+		for x := 0; x < w; x++ {
+			spectrum[y][x] = complex((gray[y][x]-mean)*wy*hannWindow(x, w), 0)
+		}
+	}
+	fft2D(spectrum)
+
+	var total, high float64
+	for y := 0; y < fh; y++ {
+		fy := signedFrequency(y, fh) / float64(fh/2)
+		for x := 0; x < fw; x++ {
+			if x == 0 && y == 0 {
+				continue // 直流成分は除外
+			}
+			fx := signedFrequency(x, fw) / float64(fw/2)
+			power := real(spectrum[y][x])*real(spectrum[y][x]) + imag(spectrum[y][x])*imag(spectrum[y][x])
+			total += power
+			if math.Sqrt(fx*fx+fy*fy) >= cutoff {
+				high += power
+			}
+		}
+	}
+
+	if total <= 0 {
+		return 0
+	}
+	return high / total
+}
+
+// hannWindow はハン窓の i 番目の係数を返します。
+func hannWindow(i, n int) float64 {
+	return 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+}
+
+// signedFrequency はFFTのインデックスを符号付きの周波数（-n/2〜n/2）に変換します。
+func signedFrequency(i, n int) float64 {
+	if i > n/2 {
+		return float64(i - n)
+	}
+	return float64(i)
+}
+
+// nextPowerOfTwo は n 以上の最小の2のべき乗を返します。
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fft2D は2次元FFTをインプレースで実行します（行方向→列方向）。
+// 各次元のサイズは2のべき乗である必要があります。
+func fft2D(data [][]complex128) {
+	for y := range data {
+		fft(data[y])
+	}
+
+	h := len(data)
+	if h == 0 {
+		return
+	}
+	col := make([]complex128, h)
+	for x := range data[0] {
+		for y := 0; y < h; y++ {
+			col[y] = data[y][x]
+		}
+		fft(col)
+		for y := 0; y < h; y++ {
+			data[y][x] = col[y]
+		}
+	}
+}
+
+// fft は基数2のCooley-Tukey FFTをインプレースで実行します。
+// len(a) は2のべき乗である必要があります。
+func fft(a []complex128) {
+	n := len(a)
+
+	// ビット反転による並べ替え
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u := a[start+k]
+				v := a[start+k+size/2] * w
+				a[start+k] = u + v
+				a[start+k+size/2] = u - v
+				w *= step
+			}
+		}
+	}
+}
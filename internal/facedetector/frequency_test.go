@@ -0,0 +1,121 @@
+package facedetector
+
+import (
+	"math"
+	"math/cmplx"
+	"os"
+	"testing"
+)
+
+func TestFFT_MatchesNaiveDFT(t *testing.T) {
+	input := []complex128{1, 2, 3, 4, 0, -1, -2, 5}
+	got := append([]complex128(nil), input...)
+	fft(got)
+
+	n := len(input)
+	for k := 0; k < n; k++ {
+		var want complex128
+		for j := 0; j < n; j++ {
+			want += input[j] * cmplx.Exp(complex(0, -2*math.Pi*float64(j*k)/float64(n)))
+		}
+		if cmplx.Abs(got[k]-want) > 1e-9 {
+			t.Errorf("fft[%d] = %v, want %v", k, got[k], want)
+		}
+	}
+}
+
+func TestNextPowerOfTwo(t *testing.T) {
+	tests := map[int]int{1: 1, 2: 2, 3: 4, 100: 128, 128: 128, 129: 256}
+	for n, want := range tests {
+		if got := nextPowerOfTwo(n); got != want {
+			t.Errorf("nextPowerOfTwo(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestCalculateFrequencyEnergyRatio_SharpVsBlurred(t *testing.T) {
+	// 大きさの異なる矩形を並べた画像（急峻なエッジは広い帯域の周波数成分を持つ）
+	size := 64
+	sharp := make([][]float64, size)
+	for y := range sharp {
+		sharp[y] = make([]float64, size)
+		for x := range sharp[y] {
+			if (x/16+y/16)%2 == 0 || (x > 20 && x < 28 && y > 36 && y < 60) {
+				sharp[y][x] = 255
+			}
+		}
+	}
+	blurred := applyGaussianBlur2D(sharp, 9, 3.0)
+
+	sharpRatio := calculateFrequencyEnergyRatio(sharp, 0.25)
+	blurredRatio := calculateFrequencyEnergyRatio(blurred, 0.25)
+	t.Logf("sharp=%.4f blurred=%.4f", sharpRatio, blurredRatio)
+
+	if sharpRatio < 0 || sharpRatio > 1 || blurredRatio < 0 || blurredRatio > 1 {
+		t.Fatalf("Ratio out of range [0, 1]: sharp=%.4f blurred=%.4f", sharpRatio, blurredRatio)
+	}
+	if sharpRatio <= blurredRatio {
+		t.Errorf("Expected sharp ratio (%.4f) > blurred ratio (%.4f)", sharpRatio, blurredRatio)
+	}
+}
+
+func TestCalculateFrequencyEnergyRatio_Uniform(t *testing.T) {
+	uniform := make([][]float64, 32)
+	for y := range uniform {
+		uniform[y] = make([]float64, 32)
+		for x := range uniform[y] {
+			uniform[y][x] = 128
+		}
+	}
+	if got := calculateFrequencyEnergyRatio(uniform, 0.25); got != 0 {
+		t.Errorf("Expected 0 for uniform image, got %.4f", got)
+	}
+}
+
+func TestFrequencyScoreMapping(t *testing.T) {
+	// 鮮明な自然画像の目安（約0.3）で75点、frequencyRatioHalfScoreで50点
+	tests := []struct {
+		ratio float64
+		want  float64
+	}{
+		{0, 0},
+		{0.05, 33.3},
+		{frequencyRatioHalfScore, 50},
+		{0.3, 75},
+	}
+	for _, tt := range tests {
+		if got := saturatingScore(tt.ratio, frequencyRatioHalfScore); got != tt.want {
+			t.Errorf("score(%.2f) = %.1f, want %.1f", tt.ratio, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateSharpness_FrequencyScoreBasis(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/test.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := CalculateSharpness(imageData, WithScoreBasis(MetricFrequency), WithMetrics(MetricFrequency))
+	if err != nil {
+		t.Fatalf("CalculateSharpness failed: %v", err)
+	}
+	if result.ScoreBasis != MetricFrequency {
+		t.Errorf("ScoreBasis = %q, want %q", result.ScoreBasis, MetricFrequency)
+	}
+	if got := result.Metrics[MetricFrequency]; got.Score != result.NormalizedScore {
+		t.Errorf("NormalizedScore = %.1f, want frequency score %.1f", result.NormalizedScore, got.Score)
+	}
+	if result.FrequencyEnergyRatio != result.Metrics[MetricFrequency].Value {
+		t.Errorf("FrequencyEnergyRatio = %.4f, want %.4f", result.FrequencyEnergyRatio, result.Metrics[MetricFrequency].Value)
+	}
+
+	// デフォルトはエッジ減衰率
+	defaultResult, err := CalculateSharpness(imageData)
+	if err != nil {
+		t.Fatalf("CalculateSharpness failed: %v", err)
+	}
+	if defaultResult.ScoreBasis != MetricEdgeDecay {
+		t.Errorf("Default ScoreBasis = %q, want %q", defaultResult.ScoreBasis, MetricEdgeDecay)
+	}
+}
@@ -0,0 +1,34 @@
+#include "head_pose.h"
+
+// HeadPose_SolvePnP は3Dモデル点（x, y, z の並び）と画像上の点（x, y の並び）の対応から
+// cv::solvePnP でカメラに対する顔の回転を求め、3x3の回転行列を行優先で rotation に書き込みます。
+// カメラは焦点距離 focalLength・光学中心 (centerX, centerY) のピンホールカメラで、レンズ歪みは無いとみなします。
+// 推定できた場合は1、失敗した場合は0を返します。
+int HeadPose_SolvePnP(const double* objectPoints, const double* imagePoints, int numPoints,
+                      double focalLength, double centerX, double centerY, double* rotation) {
+    std::vector<cv::Point3d> object;
+    std::vector<cv::Point2d> image;
+    for (int i = 0; i < numPoints; ++i) {
+        object.push_back(cv::Point3d(objectPoints[i * 3], objectPoints[i * 3 + 1], objectPoints[i * 3 + 2]));
+        image.push_back(cv::Point2d(imagePoints[i * 2], imagePoints[i * 2 + 1]));
+    }
+
+    cv::Mat camera = (cv::Mat_<double>(3, 3) << focalLength, 0, centerX, 0, focalLength, centerY, 0, 0, 1);
+    cv::Mat distortion = cv::Mat::zeros(4, 1, CV_64F);
+    cv::Mat rvec, tvec, rmat;
+    try {
+        if (!cv::solvePnP(object, image, camera, distortion, rvec, tvec, false, cv::SOLVEPNP_ITERATIVE)) {
+            return 0;
+        }
+        cv::Rodrigues(rvec, rmat);
+    } catch (const cv::Exception&) {
+        return 0;
+    }
+
+    for (int r = 0; r < 3; ++r) {
+        for (int c = 0; c < 3; ++c) {
+            rotation[r * 3 + c] = rmat.at<double>(r, c);
+        }
+    }
+    return 1;
+}
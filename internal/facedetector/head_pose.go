@@ -0,0 +1,52 @@
+package facedetector
+
+/*
+#cgo !windows pkg-config: opencv4
+#cgo CXXFLAGS: --std=c++11
+#cgo LDFLAGS: -lopencv_calib3d
+#include "head_pose.h"
+*/
+import "C"
+
+import "unsafe"
+
+// ============================================================================
+// cv::solvePnP のラッパー
+// ============================================================================
+
+// gocv v0.31 は solvePnP を提供していないため、LBF Facemarkと同様に
+// cv::solvePnP を直接呼び出す最小限のcgoラッパーを用意しています。
+
+// solvePnP は3Dモデル点と画像上の点の対応から、焦点距離 focal・光学中心 (cx, cy) のカメラに対する
+// 回転行列（OpenCVのカメラ座標系: x 右、y 下、z 奥）を求めます。推定できない場合はfalseを返します。
+func solvePnP(model [][3]float64, points [][2]float64, focal, cx, cy float64) ([3][3]float64, bool) {
+	var rotation [3][3]float64
+	if len(model) == 0 || len(model) != len(points) {
+		return rotation, false
+	}
+
+	cModel := make([]float64, 0, len(model)*3)
+	for _, p := range model {
+		cModel = append(cModel, p[0], p[1], p[2])
+	}
+	cPoints := make([]float64, 0, len(points)*2)
+	for _, p := range points {
+		cPoints = append(cPoints, p[0], p[1])
+	}
+	cRotation := make([]float64, 9)
+
+	ok := C.HeadPose_SolvePnP(
+		(*C.double)(unsafe.Pointer(&cModel[0])), (*C.double)(unsafe.Pointer(&cPoints[0])), C.int(len(model)),
+		C.double(focal), C.double(cx), C.double(cy),
+		(*C.double)(unsafe.Pointer(&cRotation[0])))
+	if ok == 0 {
+		return rotation, false
+	}
+
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			rotation[r][c] = cRotation[r*3+c]
+		}
+	}
+	return rotation, true
+}
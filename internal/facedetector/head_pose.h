@@ -0,0 +1,18 @@
+#ifndef _FACEDETECTOR_HEAD_POSE_H_
+#define _FACEDETECTOR_HEAD_POSE_H_
+
+#ifdef __cplusplus
+#include <opencv2/opencv.hpp>
+#include <opencv2/calib3d.hpp>
+
+extern "C" {
+#endif
+
+int HeadPose_SolvePnP(const double* objectPoints, const double* imagePoints, int numPoints,
+                      double focalLength, double centerX, double centerY, double* rotation);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif //_FACEDETECTOR_HEAD_POSE_H_
@@ -0,0 +1,282 @@
+package facedetector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+// ============================================================================
+// 鮮明度ヒートマップ
+// ============================================================================
+
+// heatmapMinStdDev は鮮明度を評価できるタイルの輝度の最小標準偏差。
+// これ未満のタイル（空・壁など）はコントラスト正規化でノイズが強調されるため評価しません。
+const heatmapMinStdDev = 4.0
+
+// heatmapOverlayAlpha はヒートマップPNGで元画像に重ねる色の不透明度
+const heatmapOverlayAlpha = 0.45
+
+// HeatmapTile はヒートマップの1タイルの鮮明度です。
+type HeatmapTile struct {
+	// BoundingBox はEXIFの向きを適用した後の画像上のタイルの位置。
+	BoundingBox BoundingBox `json:"bounding_box"`
+
+	// Score はエッジ減衰率による鮮明度スコア（0〜100点）。LowTextureの場合は0。
+	Score float64 `json:"score"`
+
+	// EdgeDecayRatio はタイルのエッジ減衰率（0.0〜1.0）。
+	EdgeDecayRatio float64 `json:"edge_decay_ratio"`
+
+	// LowTexture はタイルの輝度変化が小さく、鮮明度を評価できないかどうか。
+	LowTexture bool `json:"low_texture"`
+}
+
+// SharpnessHeatmap は画像をタイルに分割して計算した鮮明度の分布です。
+// 手前で動いた手・ティルトシフトなど、画像の一部だけがブレている状態を確認するために使用します。
+type SharpnessHeatmap struct {
+	// Rows / Cols はタイルの行数・列数。
+	Rows int `json:"rows"`
+	Cols int `json:"cols"`
+
+	// Tiles はタイルごとの結果（Tiles[行][列]）。
+	Tiles [][]HeatmapTile `json:"tiles"`
+
+	// MinScore / MaxScore / MeanScore は評価できたタイルのスコアの最小・最大・平均。
+	MinScore  float64 `json:"min_score"`
+	MaxScore  float64 `json:"max_score"`
+	MeanScore float64 `json:"mean_score"`
+
+	// Width / Height はEXIFの向きを適用した後の画像のサイズ（ピクセル）。タイルの位置もこの画像の座標系です。
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Orientation は解析前に適用したEXIFの向き（1は回転なし）。
+	Orientation Orientation `json:"orientation"`
+}
+
+// CalculateSharpnessHeatmap は画像をタイルに分割し、タイルごとにエッジ減衰率の
+// 鮮明度パイプラインを実行した結果を返します。
+func (d *Detector) CalculateSharpnessHeatmap(imageData []byte) (SharpnessHeatmap, error) {
+	return d.CalculateSharpnessHeatmapContext(context.Background(), imageData)
+}
+
+// CalculateSharpnessHeatmapContext はctxのキャンセルに対応したCalculateSharpnessHeatmapです。
+// タイルの行ごとにキャンセルを確認します。
+func (d *Detector) CalculateSharpnessHeatmapContext(ctx context.Context, imageData []byte) (SharpnessHeatmap, error) {
+	if err := ctx.Err(); err != nil {
+		return SharpnessHeatmap{}, err
+	}
+
+	img, orientation, err := decodeImage(imageData)
+	if err != nil {
+		return SharpnessHeatmap{}, err
+	}
+
+	heatmap, err := d.sharpnessHeatmap(ctx, convertToGrayscale(img), d.cfg.heatmapCols, d.cfg.heatmapRows)
+	if err != nil {
+		return SharpnessHeatmap{}, err
+	}
+	heatmap.Orientation = orientation
+	return heatmap, nil
+}
+
+// DrawSharpnessHeatmap は鮮明度ヒートマップを元画像に色で重ねたPNG画像を返します。
+// 画像はEXIFの向きを適用した、表示される向きの画像です。
+// 鮮明なタイルは緑、ブレたタイルは赤で表示し、評価できないタイルは元画像のままです。
+func (d *Detector) DrawSharpnessHeatmap(imageData []byte) ([]byte, error) {
+	return d.DrawSharpnessHeatmapContext(context.Background(), imageData)
+}
+
+// DrawSharpnessHeatmapContext はctxのキャンセルに対応したDrawSharpnessHeatmapです。
+func (d *Detector) DrawSharpnessHeatmapContext(ctx context.Context, imageData []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	img, _, err := decodeImage(imageData)
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap, err := d.sharpnessHeatmap(ctx, convertToGrayscale(img), d.cfg.heatmapCols, d.cfg.heatmapRows)
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+
+	for _, row := range heatmap.Tiles {
+		for _, tile := range row {
+			if tile.LowTexture {
+				continue
+			}
+			blendRect(rgba, tile.BoundingBox.rect(), scoreColor(tile.Score), heatmapOverlayAlpha)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, rgba); err != nil {
+		return nil, fmt.Errorf("画像のエンコードに失敗しました: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sharpnessHeatmap はグレースケール画像を cols × rows のタイルに分割して鮮明度を計算します。
+func (d *Detector) sharpnessHeatmap(ctx context.Context, gray [][]float64, cols, rows int) (SharpnessHeatmap, error) {
+	height := len(gray)
+	width := 0
+	if height > 0 {
+		width = len(gray[0])
+	}
+	grid := tileGrid(width, height, cols, rows)
+	rows, cols = len(grid), len(grid[0])
+
+	heatmap := SharpnessHeatmap{
+		Rows:   rows,
+		Cols:   cols,
+		Tiles:  make([][]HeatmapTile, rows),
+		Width:  width,
+		Height: height,
+	}
+
+	heatmap.MinScore = math.MaxFloat64
+	heatmap.MaxScore = -math.MaxFloat64
+	sum, count := 0.0, 0
+
+	for r := 0; r < rows; r++ {
+		if err := ctx.Err(); err != nil {
+			return SharpnessHeatmap{}, err
+		}
+
+		heatmap.Tiles[r] = make([]HeatmapTile, cols)
+		for c, rect := range grid[r] {
+			tile := d.tileSharpness(gray, rect)
+			heatmap.Tiles[r][c] = tile
+
+			if tile.LowTexture {
+				continue
+			}
+			sum += tile.Score
+			count++
+			heatmap.MinScore = math.Min(heatmap.MinScore, tile.Score)
+			heatmap.MaxScore = math.Max(heatmap.MaxScore, tile.Score)
+		}
+	}
+
+	if count == 0 {
+		heatmap.MinScore, heatmap.MaxScore = 0, 0
+	} else {
+		heatmap.MeanScore = math.Round(sum/float64(count)*10) / 10
+	}
+	return heatmap, nil
+}
+
+// tileGrid は width × height の画像を cols × rows に分割したタイルの矩形（[行][列]）を返します。
+// タイルが1ピクセル未満にならないよう分割数を制限します。
+func tileGrid(width, height, cols, rows int) [][]image.Rectangle {
+	cols = max(1, min(cols, width))
+	rows = max(1, min(rows, height))
+
+	grid := make([][]image.Rectangle, rows)
+	for r := range grid {
+		grid[r] = make([]image.Rectangle, cols)
+		for c := range grid[r] {
+			grid[r][c] = image.Rect(c*width/cols, r*height/rows, (c+1)*width/cols, (r+1)*height/rows)
+		}
+	}
+	return grid
+}
+
+// tileSharpness は画像の矩形領域についてエッジ減衰率の鮮明度パイプラインを実行します。
+func (d *Detector) tileSharpness(gray [][]float64, rect image.Rectangle) HeatmapTile {
+	region := subRegion(gray, rect)
+	tile := HeatmapTile{BoundingBox: newBoundingBox(rect)}
+
+	if regionStdDev(region) < heatmapMinStdDev {
+		tile.LowTexture = true
+		return tile
+	}
+
+	normalized, denoised, noiseSigma := d.normalizeAndDenoise(region, d.cfg.sharpnessNormalizeSize)
+	ratio := noiseCompensatedEdgeDecay(normalized, denoised, noiseSigma, d.cfg.edgeDecayBlurKernelSize, d.cfg.edgeDecayBlurSigma)
+	tile.EdgeDecayRatio = math.Round(ratio*10000) / 10000
+	tile.Score = decayRatioToScore(ratio, d.cfg.sigmoidMidpoint, d.cfg.sigmoidSteepness)
+	return tile
+}
+
+// subRegion は2D配列の矩形領域を、コピーせずにスライスとして返します。
+func subRegion(gray [][]float64, rect image.Rectangle) [][]float64 {
+	region := make([][]float64, 0, rect.Dy())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		region = append(region, gray[y][rect.Min.X:rect.Max.X])
+	}
+	return region
+}
+
+// regionStdDev は2D配列の輝度の標準偏差を計算します。
+func regionStdDev(gray [][]float64) float64 {
+	var sum, sumSq float64
+	n := 0
+	for _, row := range gray {
+		for _, v := range row {
+			sum += v
+			sumSq += v * v
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	mean := sum / float64(n)
+	return math.Sqrt(math.Max(0, sumSq/float64(n)-mean*mean))
+}
+
+// scoreColor は鮮明度スコアをヒートマップの色に変換します（0: 赤 → 50: 黄 → 100: 緑）。
+func scoreColor(score float64) color.RGBA {
+	t := math.Max(0, math.Min(1, score/100))
+	if t < 0.5 {
+		return color.RGBA{R: 255, G: uint8(255 * t * 2), A: 255}
+	}
+	return color.RGBA{R: uint8(255 * (1 - t) * 2), G: 255, A: 255}
+}
+
+// blendRect は矩形領域に色を指定の不透明度で重ねます。
+func blendRect(img *image.RGBA, rect image.Rectangle, c color.RGBA, alpha float64) {
+	rect = rect.Intersect(img.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			img.Pix[i+0] = uint8(float64(img.Pix[i+0])*(1-alpha) + float64(c.R)*alpha)
+			img.Pix[i+1] = uint8(float64(img.Pix[i+1])*(1-alpha) + float64(c.G)*alpha)
+			img.Pix[i+2] = uint8(float64(img.Pix[i+2])*(1-alpha) + float64(c.B)*alpha)
+		}
+	}
+}
+
+// CalculateSharpnessHeatmap はデフォルト設定のDetectorで鮮明度ヒートマップを計算します。
+func CalculateSharpnessHeatmap(imageData []byte) (SharpnessHeatmap, error) {
+	return getDefaultDetector().CalculateSharpnessHeatmap(imageData)
+}
+
+// CalculateSharpnessHeatmapContext はctxのキャンセルに対応したCalculateSharpnessHeatmapです。
+func CalculateSharpnessHeatmapContext(ctx context.Context, imageData []byte) (SharpnessHeatmap, error) {
+	return getDefaultDetector().CalculateSharpnessHeatmapContext(ctx, imageData)
+}
+
+// DrawSharpnessHeatmap はデフォルト設定のDetectorで鮮明度ヒートマップを重ねた画像を返します。
+func DrawSharpnessHeatmap(imageData []byte) ([]byte, error) {
+	return getDefaultDetector().DrawSharpnessHeatmap(imageData)
+}
+
+// DrawSharpnessHeatmapContext はctxのキャンセルに対応したDrawSharpnessHeatmapです。
+func DrawSharpnessHeatmapContext(ctx context.Context, imageData []byte) ([]byte, error) {
+	return getDefaultDetector().DrawSharpnessHeatmapContext(ctx, imageData)
+}
@@ -0,0 +1,139 @@
+package facedetector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func TestCalculateSharpnessHeatmap_Grid(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/test.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	d := New(WithHeatmapGrid(4, 3))
+	heatmap, err := d.CalculateSharpnessHeatmap(imageData)
+	if err != nil {
+		t.Fatalf("CalculateSharpnessHeatmap failed: %v", err)
+	}
+
+	if heatmap.Rows != 3 || heatmap.Cols != 4 || len(heatmap.Tiles) != 3 {
+		t.Fatalf("Expected 3x4 grid, got rows=%d cols=%d", heatmap.Rows, heatmap.Cols)
+	}
+
+	// タイルは重ならずに画像全体を覆う
+	area := 0
+	for _, row := range heatmap.Tiles {
+		if len(row) != 4 {
+			t.Fatalf("Expected 4 tiles per row, got %d", len(row))
+		}
+		for _, tile := range row {
+			area += tile.BoundingBox.Width * tile.BoundingBox.Height
+			if tile.Score < 0 || tile.Score > 100 {
+				t.Errorf("Tile score out of range [0, 100]: %.1f", tile.Score)
+			}
+		}
+	}
+	if area != heatmap.Width*heatmap.Height {
+		t.Errorf("Tiles cover %d pixels, want %d", area, heatmap.Width*heatmap.Height)
+	}
+}
+
+func TestCalculateSharpnessHeatmap_PartialBlur(t *testing.T) {
+	// 左半分はステップエッジ、右半分はそれをぼかした画像
+	sharp := stepEdgeImage(256, 128, 8)
+	blurred := applyGaussianBlur2D(sharp, 15, 4.0)
+	for y := range sharp {
+		copy(sharp[y][128:], blurred[y][128:])
+	}
+
+	heatmap, err := New().sharpnessHeatmap(context.Background(), sharp, 2, 1)
+	if err != nil {
+		t.Fatalf("sharpnessHeatmap failed: %v", err)
+	}
+
+	left, right := heatmap.Tiles[0][0], heatmap.Tiles[0][1]
+	t.Logf("left=%.1f right=%.1f", left.Score, right.Score)
+	if left.Score <= right.Score {
+		t.Errorf("Expected sharp tile (%.1f) > blurred tile (%.1f)", left.Score, right.Score)
+	}
+}
+
+func TestCalculateSharpnessHeatmap_LowTexture(t *testing.T) {
+	heatmap, err := CalculateSharpnessHeatmap(uniformPNG(t, 64, 64))
+	if err != nil {
+		t.Fatalf("CalculateSharpnessHeatmap failed: %v", err)
+	}
+	for _, row := range heatmap.Tiles {
+		for _, tile := range row {
+			if !tile.LowTexture {
+				t.Errorf("Expected uniform tile to be low texture: %+v", tile)
+			}
+		}
+	}
+	if heatmap.MeanScore != 0 || heatmap.MinScore != 0 || heatmap.MaxScore != 0 {
+		t.Errorf("Expected zero summary for uniform image, got %+v", heatmap)
+	}
+}
+
+func TestDrawSharpnessHeatmap(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/test.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	pngData, err := DrawSharpnessHeatmap(imageData)
+	if err != nil {
+		t.Fatalf("DrawSharpnessHeatmap failed: %v", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatalf("Failed to decode heatmap PNG: %v", err)
+	}
+	src, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		t.Fatalf("Failed to decode source image: %v", err)
+	}
+	if img.Bounds().Size() != src.Bounds().Size() {
+		t.Errorf("Heatmap size %v, want %v", img.Bounds().Size(), src.Bounds().Size())
+	}
+}
+
+func TestCalculateSharpnessHeatmap_EmptyData(t *testing.T) {
+	if _, err := CalculateSharpnessHeatmap(nil); !errors.Is(err, ErrEmptyImage) {
+		t.Errorf("CalculateSharpnessHeatmap(nil) error = %v, want ErrEmptyImage", err)
+	}
+}
+
+func TestScoreColor(t *testing.T) {
+	if c := scoreColor(0); c.R != 255 || c.G != 0 {
+		t.Errorf("scoreColor(0) = %v, want red", c)
+	}
+	if c := scoreColor(50); c.R != 255 || c.G != 255 {
+		t.Errorf("scoreColor(50) = %v, want yellow", c)
+	}
+	if c := scoreColor(100); c.R != 0 || c.G != 255 {
+		t.Errorf("scoreColor(100) = %v, want green", c)
+	}
+}
+
+func TestTileGrid(t *testing.T) {
+	grid := tileGrid(100, 50, 4, 2)
+	if len(grid) != 2 || len(grid[0]) != 4 {
+		t.Fatalf("Expected 2x4 tiles, got %dx%d", len(grid), len(grid[0]))
+	}
+	if want := image.Rect(75, 25, 100, 50); grid[1][3] != want {
+		t.Errorf("grid[1][3] = %v, want %v", grid[1][3], want)
+	}
+
+	// 画像より細かい分割は画素数までに制限される
+	if grid := tileGrid(3, 2, 8, 8); len(grid) != 2 || len(grid[0]) != 3 {
+		t.Errorf("Expected 2x3 tiles for a 3x2 image, got %dx%d", len(grid), len(grid[0]))
+	}
+}
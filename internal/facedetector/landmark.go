@@ -0,0 +1,212 @@
+package facedetector
+
+import (
+	"image"
+	"log"
+	"math"
+	"path/filepath"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================================
+// 顔の特徴点（ランドマーク）検出
+// ============================================================================
+
+const (
+	// LBF Facemark モデルファイル名
+	landmarkModelFileName = "lbfmodel.yaml"
+
+	// landmarkCount は1つの顔あたりの特徴点の数（iBUG 300-W の68点）
+	landmarkCount = 68
+)
+
+// 68点モデルにおける各部位の特徴点の範囲 [開始, 終了)。
+// 左右は画像上の左右です（被写体から見た左右とは逆になります）。
+var (
+	landmarkJaw       = [2]int{0, 17}
+	landmarkLeftBrow  = [2]int{17, 22}
+	landmarkRightBrow = [2]int{22, 27}
+	landmarkNose      = [2]int{27, 36}
+	landmarkLeftEye   = [2]int{36, 42}
+	landmarkRightEye  = [2]int{42, 48}
+	landmarkMouth     = [2]int{48, 68}
+)
+
+// LandmarkPoint は特徴点の座標（元画像の座標系、サブピクセル精度）です。
+type LandmarkPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// newLandmarkPoint は座標を0.1ピクセル単位に丸めたLandmarkPointを返します。
+func newLandmarkPoint(x, y float64) LandmarkPoint {
+	return LandmarkPoint{X: math.Round(x*10) / 10, Y: math.Round(y*10) / 10}
+}
+
+// Landmarks は顔の68点の特徴点（iBUG 300-W の順序）です。
+// 0〜16: 輪郭、17〜26: 眉、27〜35: 鼻、36〜47: 目、48〜67: 口。
+type Landmarks []LandmarkPoint
+
+// Valid は68点が揃っているかを返します。
+func (l Landmarks) Valid() bool {
+	return len(l) == landmarkCount
+}
+
+func (l Landmarks) group(r [2]int) []LandmarkPoint {
+	if !l.Valid() {
+		return nil
+	}
+	return l[r[0]:r[1]]
+}
+
+// Jaw は輪郭の17点を返します。
+func (l Landmarks) Jaw() []LandmarkPoint { return l.group(landmarkJaw) }
+
+// LeftBrow は画像上の左の眉の5点を返します。
+func (l Landmarks) LeftBrow() []LandmarkPoint { return l.group(landmarkLeftBrow) }
+
+// RightBrow は画像上の右の眉の5点を返します。
+func (l Landmarks) RightBrow() []LandmarkPoint { return l.group(landmarkRightBrow) }
+
+// Nose は鼻筋と鼻の下の9点を返します。
+func (l Landmarks) Nose() []LandmarkPoint { return l.group(landmarkNose) }
+
+// LeftEye は画像上の左目の6点（外側の目尻から時計回り）を返します。
+func (l Landmarks) LeftEye() []LandmarkPoint { return l.group(landmarkLeftEye) }
+
+// RightEye は画像上の右目の6点を返します。
+func (l Landmarks) RightEye() []LandmarkPoint { return l.group(landmarkRightEye) }
+
+// Mouth は唇の外周12点と内周8点を返します。
+func (l Landmarks) Mouth() []LandmarkPoint { return l.group(landmarkMouth) }
+
+// landmarkCenter は特徴点の重心を返します。
+func landmarkCenter(points []LandmarkPoint) LandmarkPoint {
+	if len(points) == 0 {
+		return LandmarkPoint{}
+	}
+	var c LandmarkPoint
+	for _, p := range points {
+		c.X += p.X
+		c.Y += p.Y
+	}
+	c.X /= float64(len(points))
+	c.Y /= float64(len(points))
+	return c
+}
+
+// landmarkBounds は特徴点を囲む矩形を返します。
+func landmarkBounds(points []LandmarkPoint) image.Rectangle {
+	if len(points) == 0 {
+		return image.Rectangle{}
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1)
+}
+
+// ============================================================================
+// LandmarkDetector
+// ============================================================================
+
+// LandmarkDetector はLBF Facemark（68点）による顔の特徴点検出器です。
+// モデル（lbfmodel.yaml）の読み込みには数秒かかるため、最初の使用時に1度だけ読み込み、
+// 推定はミューテックスで直列化します（1顔あたり数ミリ秒）。
+// 複数のゴルーチンから同時に使用できます。
+type LandmarkDetector struct {
+	modelPath string
+
+	loadOnce sync.Once
+	mu       sync.Mutex
+	facemark *lbfFacemark
+}
+
+// NewLandmarkDetector はLBFモデルのパスを指定してLandmarkDetectorを生成します。
+// パスが空またはファイルが存在しない場合、Availableはfalseを返し、Detectは常にnilを返します。
+func NewLandmarkDetector(modelPath string) *LandmarkDetector {
+	l := &LandmarkDetector{}
+	if modelPath != "" && fileExists(modelPath) {
+		l.modelPath = modelPath
+	}
+	return l
+}
+
+// Available はLBFモデルファイルが見つかっているかを返します。
+func (l *LandmarkDetector) Available() bool {
+	return l.modelPath != ""
+}
+
+// load はモデルを読み込み、読み込めた場合はtrueを返します。
+func (l *LandmarkDetector) load() bool {
+	l.loadOnce.Do(func() {
+		if l.modelPath == "" {
+			log.Println("[FaceDetector] WARNING: LBF facemark model NOT found. Landmarks, landmark-based eye regions, eye aspect ratio and head pose are disabled.")
+			return
+		}
+		l.facemark = newLBFFacemark(l.modelPath)
+		if l.facemark == nil {
+			log.Printf("[FaceDetector] WARNING: Failed to load LBF facemark model: %s\n", l.modelPath)
+			return
+		}
+		log.Printf("[FaceDetector] LBF facemark model loaded: %s\n", l.modelPath)
+	})
+	return l.facemark != nil
+}
+
+// Detect は画像（BGRまたはグレースケール）の顔矩形ごとに68点の特徴点を推定します。
+// 結果はrectsと同じ順序です。モデルが利用できない場合や推定に失敗した場合はnilを返します。
+func (l *LandmarkDetector) Detect(mat gocv.Mat, rects []image.Rectangle) []Landmarks {
+	if len(rects) == 0 || mat.Empty() || !l.load() {
+		return nil
+	}
+
+	gray := gocv.NewMat()
+	defer gray.Close()
+	if mat.Channels() == 1 {
+		mat.CopyTo(&gray)
+	} else {
+		gocv.CvtColor(mat, &gray, gocv.ColorBGRToGray)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.facemark.fit(gray.ToBytes(), gray.Cols(), gray.Rows(), rects)
+}
+
+// findLandmarkModelFile はLBFモデルファイルをDNNモデルと同じ検索パスから探します。
+func findLandmarkModelFile() (string, bool) {
+	for _, dir := range dnnModelSearchPaths() {
+		path := filepath.Join(dir, landmarkModelFileName)
+		if fileExists(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// fitLandmarks は検出された顔に特徴点を設定します。
+// 特徴点の検出が無効な場合やモデルが無い場合は何もしません（Landmarksは空のまま）。
+// モデルが無い場合は最初の呼び出しで警告をログに出力します。
+func (d *Detector) fitLandmarks(mat gocv.Mat, faces []Face) {
+	if d.landmarker == nil || len(faces) == 0 {
+		return
+	}
+
+	rects := make([]image.Rectangle, len(faces))
+	for i, f := range faces {
+		rects[i] = f.Rect
+	}
+	landmarks := d.landmarker.Detect(mat, rects)
+	if len(landmarks) != len(faces) {
+		return
+	}
+	for i := range faces {
+		faces[i].Landmarks = landmarks[i]
+	}
+}
@@ -0,0 +1,174 @@
+package facedetector
+
+import (
+	"encoding/json"
+	"image"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+// syntheticLandmarks は顔矩形 rect に正面顔の平均的な配置で68点を並べた特徴点を返します。
+func syntheticLandmarks(rect image.Rectangle) Landmarks {
+	w, h := float64(rect.Dx()), float64(rect.Dy())
+	at := func(x, y float64) LandmarkPoint {
+		return LandmarkPoint{X: float64(rect.Min.X) + x*w, Y: float64(rect.Min.Y) + y*h}
+	}
+
+	lm := make(Landmarks, 0, landmarkCount)
+	// 輪郭（左のこめかみから顎を通って右のこめかみまで）
+	for i := 0; i < 17; i++ {
+		t := math.Pi * float64(i) / 16
+		lm = append(lm, at(0.5-0.5*math.Cos(t), 0.3+0.65*math.Sin(t)))
+	}
+	// 眉
+	for _, x0 := range []float64{0.12, 0.58} {
+		for i := 0; i < 5; i++ {
+			lm = append(lm, at(x0+0.075*float64(i), 0.22-0.03*math.Sin(math.Pi*float64(i)/4)))
+		}
+	}
+	// 鼻筋と鼻の下
+	for i := 0; i < 4; i++ {
+		lm = append(lm, at(0.5, 0.35+0.08*float64(i)))
+	}
+	for i := 0; i < 5; i++ {
+		lm = append(lm, at(0.4+0.05*float64(i), 0.65))
+	}
+	// 目（外側の目尻から時計回り）
+	for _, cx := range []float64{0.3, 0.7} {
+		for _, p := range [][2]float64{{-0.08, 0}, {-0.027, -0.03}, {0.027, -0.03}, {0.08, 0}, {0.027, 0.03}, {-0.027, 0.03}} {
+			lm = append(lm, at(cx+p[0], 0.38+p[1]))
+		}
+	}
+	// 唇の外周12点と内周8点
+	for _, m := range []struct {
+		n    int
+		w, h float64
+	}{{12, 0.36, 0.12}, {8, 0.24, 0.04}} {
+		for k := 0; k < m.n; k++ {
+			t := 2 * math.Pi * float64(k) / float64(m.n)
+			lm = append(lm, at(0.5-m.w/2*math.Cos(t), 0.8-m.h/2*math.Sin(t)))
+		}
+	}
+	return lm
+}
+
+func TestLandmarks_Groups(t *testing.T) {
+	lm := syntheticLandmarks(image.Rect(0, 0, 100, 100))
+	if !lm.Valid() {
+		t.Fatalf("Expected %d points, got %d", landmarkCount, len(lm))
+	}
+
+	groups := []struct {
+		name   string
+		points []LandmarkPoint
+		want   int
+	}{
+		{"Jaw", lm.Jaw(), 17},
+		{"LeftBrow", lm.LeftBrow(), 5},
+		{"RightBrow", lm.RightBrow(), 5},
+		{"Nose", lm.Nose(), 9},
+		{"LeftEye", lm.LeftEye(), 6},
+		{"RightEye", lm.RightEye(), 6},
+		{"Mouth", lm.Mouth(), 20},
+	}
+	for _, g := range groups {
+		if len(g.points) != g.want {
+			t.Errorf("%s has %d points, want %d", g.name, len(g.points), g.want)
+		}
+	}
+
+	if landmarkCenter(lm.LeftEye()).X >= landmarkCenter(lm.RightEye()).X {
+		t.Error("Expected left eye to be on the left side of the image")
+	}
+	if Landmarks(lm[:10]).LeftEye() != nil {
+		t.Error("Expected nil groups for incomplete landmarks")
+	}
+}
+
+func TestLandmarkCenterAndBounds(t *testing.T) {
+	points := []LandmarkPoint{{X: 10, Y: 20}, {X: 30, Y: 20}, {X: 20, Y: 35.5}}
+
+	if c := landmarkCenter(points); c.X != 20 || c.Y != 25.166666666666668 {
+		t.Errorf("landmarkCenter = %+v", c)
+	}
+	if got, want := landmarkBounds(points), image.Rect(10, 20, 31, 37); got != want {
+		t.Errorf("landmarkBounds = %v, want %v", got, want)
+	}
+	if got := landmarkBounds(nil); !got.Empty() {
+		t.Errorf("Expected empty bounds for no points, got %v", got)
+	}
+}
+
+func TestLandmarkDetector_MissingModel(t *testing.T) {
+	l := NewLandmarkDetector("testdata/missing_lbfmodel.yaml")
+	if l.Available() {
+		t.Error("Expected landmark detector to be unavailable without a model")
+	}
+	mat := gocv.NewMatWithSize(100, 100, gocv.MatTypeCV8UC3)
+	defer mat.Close()
+	if got := l.Detect(mat, []image.Rectangle{image.Rect(10, 10, 90, 90)}); got != nil {
+		t.Errorf("Expected nil landmarks without a model, got %d", len(got))
+	}
+}
+
+func TestNew_Landmarks(t *testing.T) {
+	if d := New(WithLandmarks(false)); d.landmarker != nil {
+		t.Error("Expected no landmark detector with WithLandmarks(false)")
+	}
+	if d := New(WithLandmarkModel("testdata/missing_lbfmodel.yaml")); d.landmarker == nil || d.landmarker.Available() {
+		t.Error("Expected unavailable landmark detector for a missing model file")
+	}
+}
+
+func TestFace_MarshalJSON_Landmarks(t *testing.T) {
+	face := Face{Rect: image.Rect(0, 0, 100, 100), Source: SourceDNN}
+
+	data, err := json.Marshal(face)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(data), "landmarks") {
+		t.Errorf("Expected landmarks to be omitted, got %s", data)
+	}
+
+	face.Landmarks = syntheticLandmarks(face.Rect)
+	data, err = json.Marshal(face)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"landmarks":[{"x":0,"y":30}`) {
+		t.Errorf("Expected landmarks in JSON, got %s", data)
+	}
+}
+
+func TestDetectFaces_Landmarks(t *testing.T) {
+	if _, ok := findLandmarkModelFile(); !ok {
+		t.Skip("LBF facemark model not available")
+	}
+	imageData, err := os.ReadFile("testdata/face.jpg")
+	if err != nil {
+		t.Skipf("Test image not available: %v", err)
+	}
+
+	faces, err := DetectFaces(imageData)
+	if err != nil {
+		t.Fatalf("DetectFaces failed: %v", err)
+	}
+	for i, f := range faces {
+		if !f.Landmarks.Valid() {
+			t.Errorf("Face %d: expected 68 landmarks, got %d", i, len(f.Landmarks))
+			continue
+		}
+		left, right := landmarkCenter(f.Landmarks.LeftEye()), landmarkCenter(f.Landmarks.RightEye())
+		if left.X >= right.X {
+			t.Errorf("Face %d: left eye %+v is not left of right eye %+v", i, left, right)
+		}
+		if eye := image.Pt(int(left.X), int(left.Y)); !eye.In(addMargin(f.Rect, 0.2)) {
+			t.Errorf("Face %d: left eye %+v is outside the face %v", i, left, f.Rect)
+		}
+	}
+}
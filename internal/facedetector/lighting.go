@@ -0,0 +1,127 @@
+package facedetector
+
+import (
+	"image"
+	"math"
+)
+
+// ============================================================================
+// 顔の照明の均一性（片側の影）の判定
+// ============================================================================
+
+const (
+	// lightingShadowRatio は顔の明るい部分（輝度の90パーセンタイル）に対してこの割合未満の画素を影とみなす
+	lightingShadowRatio = 0.6
+	// lightingHalfShadowBalance / lightingHalfShadowCoverage は左右の輝度の偏りと影の割合が
+	// 共にこの値以上の場合に、顔の片側が影になっている（half_shadow）と判定する
+	lightingHalfShadowBalance  = 0.25
+	lightingHalfShadowCoverage = 0.2
+)
+
+// LightingVerdict は顔の照明の判定です。
+type LightingVerdict string
+
+const (
+	// LightingEven は顔全体が均一に照らされている
+	LightingEven LightingVerdict = "even"
+	// LightingUneven は照明にむらがある（上からの強い光など）
+	LightingUneven LightingVerdict = "uneven"
+	// LightingHalfShadow は横からの光で顔の片側が影になっている（顔照合の精度が落ちる）
+	LightingHalfShadow LightingVerdict = "half_shadow"
+)
+
+// Lighting は顔の照明の均一性の診断結果です。
+type Lighting struct {
+	// Uniformity は照明の均一性のスコア（0〜100、高いほど均一）。
+	Uniformity float64 `json:"lighting_uniformity"`
+
+	// Verdict は照明の判定。
+	Verdict LightingVerdict `json:"lighting_verdict"`
+
+	// LeftRightBalance は左右の輝度の偏り（(左 - 右) / (左 + 右)、-1〜1）。
+	// 正の値は画像上で顔の左側が明るいことを示します。
+	LeftRightBalance float64 `json:"left_right_balance"`
+
+	// TopBottomBalance は上下の輝度の偏り（(上 - 下) / (上 + 下)、-1〜1）。
+	TopBottomBalance float64 `json:"top_bottom_balance"`
+
+	// ShadowCoverage は顔のうち影になっている画素の割合（0.0〜1.0）。
+	ShadowCoverage float64 `json:"shadow_coverage"`
+}
+
+// analyzeLighting は顔の中心領域の輝度の左右・上下の偏りと影の割合から照明の均一性を評価します。
+//
+// 上下の偏り（額が明るく顎が暗いなど）は自然な照明でも生じるため、スコアへの影響を左右の半分とします。
+// gray は顔を含むグレースケール画像（uprightFaceFrame）です。顔の領域がない場合はゼロ値（Verdictは空）を返します。
+func (d *Detector) analyzeLighting(gray [][]float64, faceRect image.Rectangle) Lighting {
+	bounds := image.Rect(0, 0, 0, 0)
+	if len(gray) > 0 {
+		bounds = image.Rect(0, 0, len(gray[0]), len(gray))
+	}
+	center := shrinkRect(clipRect(faceRect, bounds), d.cfg.faceCenterRatio)
+	if center.Dx() < 2 || center.Dy() < 2 {
+		return Lighting{}
+	}
+	region := subRegion(gray, center)
+
+	midX, midY := center.Dx()/2, center.Dy()/2
+	var left, right, top, bottom float64
+	var histogram [256]int
+	for y, row := range region {
+		for x, v := range row {
+			if x < midX {
+				left += v
+			} else if x >= center.Dx()-midX {
+				right += v
+			}
+			if y < midY {
+				top += v
+			} else if y >= center.Dy()-midY {
+				bottom += v
+			}
+			histogram[int(math.Max(0, math.Min(255, math.Round(v))))]++
+		}
+	}
+
+	// 影の基準: 顔の明るい部分（90パーセンタイル）
+	total := center.Dx() * center.Dy()
+	highlight := 255.0
+	for level, cumulative := 0, 0; level < 256; level++ {
+		cumulative += histogram[level]
+		if float64(cumulative) >= 0.9*float64(total) {
+			highlight = float64(level)
+			break
+		}
+	}
+	shadows := 0
+	for level := 0; level < 256 && float64(level) < highlight*lightingShadowRatio; level++ {
+		shadows += histogram[level]
+	}
+
+	l := Lighting{
+		LeftRightBalance: math.Round(balance(left, right)*1000) / 1000,
+		TopBottomBalance: math.Round(balance(top, bottom)*1000) / 1000,
+		ShadowCoverage:   math.Round(float64(shadows)/float64(total)*1000) / 1000,
+	}
+
+	uniformity := 100 * (1 - math.Abs(l.LeftRightBalance)) * (1 - math.Abs(l.TopBottomBalance)/2) * (1 - l.ShadowCoverage)
+	l.Uniformity = math.Round(uniformity*10) / 10
+
+	switch {
+	case math.Abs(l.LeftRightBalance) >= lightingHalfShadowBalance && l.ShadowCoverage >= lightingHalfShadowCoverage:
+		l.Verdict = LightingHalfShadow
+	case l.Uniformity < d.cfg.lightingUniformityThreshold:
+		l.Verdict = LightingUneven
+	default:
+		l.Verdict = LightingEven
+	}
+	return l
+}
+
+// balance は2つの輝度の合計の偏り（(a - b) / (a + b)、-1〜1）を返します。
+func balance(a, b float64) float64 {
+	if a+b <= 0 {
+		return 0
+	}
+	return (a - b) / (a + b)
+}
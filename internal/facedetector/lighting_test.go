@@ -0,0 +1,75 @@
+package facedetector
+
+import (
+	"image"
+	"testing"
+)
+
+func TestAnalyzeLighting_Even(t *testing.T) {
+	faceRect := image.Rect(20, 20, 120, 140)
+	gray := faceOnBackground(140, 160, faceRect, 150, 60)
+
+	l := New().analyzeLighting(gray, faceRect)
+	if l.Verdict != LightingEven || l.Uniformity != 100 {
+		t.Errorf("Expected evenly lit face, got %+v", l)
+	}
+	if l.LeftRightBalance != 0 || l.TopBottomBalance != 0 || l.ShadowCoverage != 0 {
+		t.Errorf("Expected no imbalance, got %+v", l)
+	}
+}
+
+func TestAnalyzeLighting_HalfShadow(t *testing.T) {
+	// 顔の右半分（画像上）が影
+	faceRect := image.Rect(20, 20, 120, 140)
+	gray := faceOnBackground(140, 160, faceRect, 160, 60)
+	for y := faceRect.Min.Y; y < faceRect.Max.Y; y++ {
+		for x := 70; x < faceRect.Max.X; x++ {
+			gray[y][x] = 50
+		}
+	}
+
+	l := New().analyzeLighting(gray, faceRect)
+	t.Logf("lighting=%+v", l)
+
+	if l.Verdict != LightingHalfShadow {
+		t.Errorf("Verdict = %q, want %q", l.Verdict, LightingHalfShadow)
+	}
+	if l.LeftRightBalance <= 0.25 {
+		t.Errorf("Expected left side brighter (balance > 0.25), got %.3f", l.LeftRightBalance)
+	}
+	if l.ShadowCoverage < 0.4 || l.ShadowCoverage > 0.6 {
+		t.Errorf("Expected about half the face in shadow, got %.3f", l.ShadowCoverage)
+	}
+	if l.Uniformity >= 60 {
+		t.Errorf("Expected low uniformity, got %.1f", l.Uniformity)
+	}
+}
+
+func TestAnalyzeLighting_TopBottomGradient(t *testing.T) {
+	// 上から下へ緩やかに暗くなる照明は片側の影ではない
+	faceRect := image.Rect(0, 0, 100, 100)
+	gray := constantGray(100, 100, 0)
+	for y := range gray {
+		for x := range gray[y] {
+			gray[y][x] = 200 - float64(y)
+		}
+	}
+
+	l := New().analyzeLighting(gray, faceRect)
+	if l.TopBottomBalance <= 0 {
+		t.Errorf("Expected top brighter, got %.3f", l.TopBottomBalance)
+	}
+	if l.Verdict != LightingEven {
+		t.Errorf("Verdict = %q, want %q (%+v)", l.Verdict, LightingEven, l)
+	}
+
+	if got := New(WithLightingUniformityThreshold(99)).analyzeLighting(gray, faceRect).Verdict; got != LightingUneven {
+		t.Errorf("Verdict with threshold 99 = %q, want %q", got, LightingUneven)
+	}
+}
+
+func TestAnalyzeLighting_EmptyFace(t *testing.T) {
+	if got := New().analyzeLighting(constantGray(10, 10, 100), image.Rect(50, 50, 60, 60)); got.Verdict != "" {
+		t.Errorf("Expected zero value for face outside the image, got %+v", got)
+	}
+}
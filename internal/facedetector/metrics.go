@@ -0,0 +1,180 @@
+package facedetector
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// 鮮明度指標（SharpnessMetric）
+// ============================================================================
+
+// 組み込みの鮮明度指標の名前
+const (
+	// MetricEdgeDecay はエッジ減衰率（NormalizedScoreの算出に使用するデフォルトの指標）
+	MetricEdgeDecay = "edge_decay"
+	// MetricTenengrad はSobel勾配強度の分散（Tenengrad法）
+	MetricTenengrad = "tenengrad"
+	// MetricLaplacian はラプラシアン分散
+	MetricLaplacian = "laplacian"
+	// MetricFrequency はFFTによる高周波エネルギー比
+	MetricFrequency = "frequency"
+	// MetricPerceptual はJNBモデルに基づく知覚的鮮明度（CPBD）
+	MetricPerceptual = "perceptual"
+)
+
+// MetricInput は鮮明度指標に渡される解析対象の画像です。
+// 画像はいずれも輝度値（0〜255）の2D配列で、[y][x] の順にアクセスします。
+type MetricInput struct {
+	// Region は解析対象領域（顔の中心領域または画像全体）の元の解像度のグレースケール画像。
+	Region [][]float64
+
+	// Normalized はRegionを基準サイズにリサイズし、コントラスト正規化を行った画像。
+	// カメラの画素数や照明に依存しない比較にはこちらを使用します。
+	Normalized [][]float64
+
+	// Denoised はNormalizedにバイラテラルフィルタでノイズ除去を行った画像。
+	Denoised [][]float64
+
+	// NoiseSigma はNormalizedで推定したノイズの標準偏差（輝度値）。ノイズ適応が無効な場合は0。
+	NoiseSigma float64
+}
+
+// MetricResult は1つの鮮明度指標の計算結果です。
+type MetricResult struct {
+	// Value は指標の生の値（単位・範囲は指標ごとに異なる）。
+	Value float64 `json:"value"`
+
+	// Score はValueを0〜100に変換したスコア（高いほど鮮明）。
+	Score float64 `json:"score"`
+}
+
+// SharpnessMetric は鮮明度指標のインタフェースです。
+// WithCustomMetricsでDetectorに登録し、WithMetricsで名前を指定すると
+// SharpnessResult.Metricsに結果が並べて出力されます。
+type SharpnessMetric interface {
+	// Name は指標の名前（APIの ?metrics= で指定する値）を返します。
+	Name() string
+
+	// Measure は解析対象の画像から指標を計算します。
+	Measure(in MetricInput) MetricResult
+}
+
+// edgeDecayMetric はエッジ減衰率をシグモイド関数で0〜100点に変換する指標です。
+type edgeDecayMetric struct {
+	blurKernelSize int
+	blurSigma      float64
+	midpoint       float64
+	steepness      float64
+}
+
+func (m edgeDecayMetric) Name() string { return MetricEdgeDecay }
+
+func (m edgeDecayMetric) Measure(in MetricInput) MetricResult {
+	ratio := noiseCompensatedEdgeDecay(in.Normalized, in.Denoised, in.NoiseSigma, m.blurKernelSize, m.blurSigma)
+	return MetricResult{
+		Value: ratio,
+		Score: decayRatioToScore(ratio, m.midpoint, m.steepness),
+	}
+}
+
+// tenengradMetric はノイズ除去済み画像のTenengrad分散を指標とします。
+type tenengradMetric struct{}
+
+func (tenengradMetric) Name() string { return MetricTenengrad }
+
+func (tenengradMetric) Measure(in MetricInput) MetricResult {
+	v := calculateTenengradVariance(in.Denoised)
+	return MetricResult{Value: v, Score: saturatingScore(v, tenengradHalfScore)}
+}
+
+// laplacianMetric は元の解像度の画像のラプラシアン分散を指標とします。
+type laplacianMetric struct{}
+
+func (laplacianMetric) Name() string { return MetricLaplacian }
+
+func (laplacianMetric) Measure(in MetricInput) MetricResult {
+	v := calculateLaplacianVariance(in.Region)
+	return MetricResult{Value: v, Score: saturatingScore(v, laplacianHalfScore)}
+}
+
+const (
+	// tenengradHalfScore はTenengrad分散が50点となる値（正規化済み128px画像での経験値）
+	tenengradHalfScore = 500.0
+	// laplacianHalfScore はラプラシアン分散が50点となる値（シャープ化再検出の閾値と同じ）
+	laplacianHalfScore = 100.0
+)
+
+// saturatingScore は上限のない非負の値を0〜100点に変換します。
+// half で50点となり、値が大きくなるほど100点に漸近します。
+func saturatingScore(v, half float64) float64 {
+	if v <= 0 || half <= 0 {
+		return 0
+	}
+	return math.Round(100*v/(v+half)*10) / 10
+}
+
+// builtinMetrics はDetectorの設定から組み込みの指標を生成します。
+func builtinMetrics(cfg config) []SharpnessMetric {
+	return []SharpnessMetric{
+		edgeDecayMetric{
+			blurKernelSize: cfg.edgeDecayBlurKernelSize,
+			blurSigma:      cfg.edgeDecayBlurSigma,
+			midpoint:       cfg.sigmoidMidpoint,
+			steepness:      cfg.sigmoidSteepness,
+		},
+		tenengradMetric{},
+		laplacianMetric{},
+		frequencyMetric{cutoff: cfg.frequencyCutoff},
+		perceptualMetric{},
+	}
+}
+
+// MetricNames はDetectorに登録されている鮮明度指標の名前を昇順で返します。
+func (d *Detector) MetricNames() []string {
+	names := make([]string, 0, len(d.metrics))
+	for name := range d.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupMetrics は名前のリストを登録済みの指標に解決します。
+// 重複した名前は1つにまとめ、未登録の名前があればErrUnknownMetricを返します。
+func (d *Detector) lookupMetrics(names []string) ([]SharpnessMetric, error) {
+	var metrics []SharpnessMetric
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		m, ok := d.metrics[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q (利用可能: %s)", ErrUnknownMetric, name, strings.Join(d.MetricNames(), ", "))
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// measureMetrics は指定された指標を計算し、名前をキーとしたマップで返します。
+// 指標が指定されていない場合はnilを返します（JSONでは省略されます）。
+func measureMetrics(metrics []SharpnessMetric, in MetricInput) map[string]MetricResult {
+	if len(metrics) == 0 {
+		return nil
+	}
+	results := make(map[string]MetricResult, len(metrics))
+	for _, m := range metrics {
+		r := m.Measure(in)
+		results[m.Name()] = MetricResult{
+			Value: math.Round(r.Value*10000) / 10000,
+			Score: math.Round(r.Score*10) / 10,
+		}
+	}
+	return results
+}
@@ -0,0 +1,97 @@
+package facedetector
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// constantMetric は常に同じ値を返すテスト用の指標です。
+type constantMetric struct {
+	name  string
+	value float64
+}
+
+func (m constantMetric) Name() string { return m.name }
+
+func (m constantMetric) Measure(in MetricInput) MetricResult {
+	return MetricResult{Value: m.value, Score: m.value}
+}
+
+func TestDetector_MetricNames(t *testing.T) {
+	d := New(WithCustomMetrics(constantMetric{name: "constant", value: 42}))
+
+	want := []string{"constant", MetricEdgeDecay, MetricFrequency, MetricLaplacian, MetricPerceptual, MetricTenengrad}
+	if got := d.MetricNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MetricNames() = %v, want %v", got, want)
+	}
+}
+
+func TestCalculateSharpness_WithMetrics(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/test.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	d := New(WithCustomMetrics(constantMetric{name: "constant", value: 42}))
+
+	// 指標を指定しない場合は省略される
+	result, err := d.CalculateSharpness(imageData)
+	if err != nil {
+		t.Fatalf("CalculateSharpness failed: %v", err)
+	}
+	if result.Metrics != nil {
+		t.Errorf("Expected no metrics, got %v", result.Metrics)
+	}
+
+	result, err = d.CalculateSharpness(imageData, WithMetrics(MetricEdgeDecay, MetricTenengrad, "constant"))
+	if err != nil {
+		t.Fatalf("CalculateSharpness failed: %v", err)
+	}
+	if len(result.Metrics) != 3 {
+		t.Fatalf("Expected 3 metrics, got %v", result.Metrics)
+	}
+
+	// edge_decay はNormalizedScoreと同じ計算
+	if got := result.Metrics[MetricEdgeDecay]; got.Score != result.NormalizedScore || got.Value != result.EdgeDecayRatio {
+		t.Errorf("edge_decay = %+v, want score %.1f value %.4f", got, result.NormalizedScore, result.EdgeDecayRatio)
+	}
+	if got := result.Metrics["constant"]; got.Value != 42 || got.Score != 42 {
+		t.Errorf("constant = %+v, want 42", got)
+	}
+	for name, m := range result.Metrics {
+		if m.Score < 0 || m.Score > 100 {
+			t.Errorf("%s score %.1f out of range [0, 100]", name, m.Score)
+		}
+	}
+}
+
+func TestCalculateSharpness_UnknownMetric(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/test.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	_, err = CalculateSharpness(imageData, WithMetrics("no_such_metric"))
+	if !errors.Is(err, ErrUnknownMetric) {
+		t.Errorf("CalculateSharpness() error = %v, want ErrUnknownMetric", err)
+	}
+}
+
+func TestSaturatingScore(t *testing.T) {
+	tests := []struct {
+		v, half float64
+		want    float64
+	}{
+		{0, 100, 0},
+		{-5, 100, 0},
+		{100, 100, 50},
+		{300, 100, 75},
+	}
+	for _, tt := range tests {
+		if got := saturatingScore(tt.v, tt.half); got != tt.want {
+			t.Errorf("saturatingScore(%v, %v) = %v, want %v", tt.v, tt.half, got, tt.want)
+		}
+	}
+}
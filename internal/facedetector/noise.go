@@ -0,0 +1,154 @@
+package facedetector
+
+import "math"
+
+// ============================================================================
+// ノイズレベルの推定とノイズ補正
+// ============================================================================
+
+const (
+	// noiseEdgePercentile はノイズ推定から除外するエッジ画素の割合（勾配強度の上位10%）
+	noiseEdgePercentile = 0.9
+	// noiseGradientBins はパーセンタイル計算用の勾配強度のヒストグラムのビン数（Sobel勾配の最大値 約1443）
+	noiseGradientBins = 1500
+	// noiseMaxSamples はノイズ推定に使用する画素数の上限（超える場合は一定間隔の格子点の画素のみを使用）
+	noiseMaxSamples = 1 << 18
+	// noiseSigmaColorFactor は適応的ノイズ除去でバイラテラルフィルタの色空間のσに掛ける倍率
+	noiseSigmaColorFactor = 3.0
+	// noiseDiameterStep はノイズのσがこの値増えるごとにフィルタの直径を2広げる
+	noiseDiameterStep = 8.0
+	// noiseMaxExtraDiameter は適応的ノイズ除去で広げるフィルタの直径の上限
+	noiseMaxExtraDiameter = 4
+	// noiseCompensationStart / noiseCompensationFull はエッジ減衰率のノイズ補正を始めるσと、
+	// 補正済みの値のみを使用するσ（その間は線形に混合し、スコアが不連続に変わらないようにする）
+	noiseCompensationStart = 1.0
+	noiseCompensationFull  = 4.0
+)
+
+// estimateNoiseSigma は画像のノイズの標準偏差（輝度値）を推定します。
+//
+// Immerkær (1996) の高速ノイズ推定法を使用します。2つのラプラシアンの差のマスクは
+// 滑らかな輝度変化に反応しないため、残る応答はほぼノイズによるものです。
+// エッジの応答を除くため、Sobel勾配強度の上位10%の画素は平坦でない領域として除外します。
+// 画素単位の細かなテクスチャはノイズと区別できないため、ノイズとして推定されます。
+// 大きな画像では、画素数がnoiseMaxSamples以下になる間隔の格子点の画素のみで推定します
+// （各画素の3×3の近傍は元の解像度のまま使用するため、縮小と異なりノイズは平均化されません）。
+func estimateNoiseSigma(gray [][]float64) float64 {
+	h := len(gray)
+	if h < 3 {
+		return 0
+	}
+	w := len(gray[0])
+	if w < 3 {
+		return 0
+	}
+
+	step := 1
+	for ((w-3)/step+1)*((h-3)/step+1) > noiseMaxSamples {
+		step++
+	}
+
+	// 勾配強度のパーセンタイルは1刻みのヒストグラムで求める（大きな画像でもソートしない）
+	n := ((w-3)/step + 1) * ((h-3)/step + 1)
+	gradients := make([]float64, 0, n)
+	responses := make([]float64, 0, n)
+	histogram := make([]int, noiseGradientBins)
+	for y := 1; y < h-1; y += step {
+		for x := 1; x < w-1; x += step {
+			gx := (gray[y-1][x+1] + 2*gray[y][x+1] + gray[y+1][x+1]) -
+				(gray[y-1][x-1] + 2*gray[y][x-1] + gray[y+1][x-1])
+			gy := (gray[y+1][x-1] + 2*gray[y+1][x] + gray[y+1][x+1]) -
+				(gray[y-1][x-1] + 2*gray[y-1][x] + gray[y-1][x+1])
+			g := math.Hypot(gx, gy)
+			gradients = append(gradients, g)
+			histogram[min(int(g), noiseGradientBins-1)]++
+
+			// マスク [1 -2 1; -2 4 -2; 1 -2 1]
+			r := (gray[y-1][x-1] + gray[y-1][x+1] + gray[y+1][x-1] + gray[y+1][x+1]) -
+				2*(gray[y-1][x]+gray[y][x-1]+gray[y][x+1]+gray[y+1][x]) +
+				4*gray[y][x]
+			responses = append(responses, math.Abs(r))
+		}
+	}
+
+	threshold := float64(noiseGradientBins)
+	for bin, cumulative := 0, 0; bin < noiseGradientBins; bin++ {
+		cumulative += histogram[bin]
+		if float64(cumulative) >= float64(n)*noiseEdgePercentile {
+			threshold = float64(bin + 1)
+			break
+		}
+	}
+
+	sum := 0.0
+	count := 0
+	for i, g := range gradients {
+		if g >= threshold {
+			continue
+		}
+		sum += responses[i]
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return math.Sqrt(math.Pi/2) * sum / (6 * float64(count))
+}
+
+// adaptiveBilateralParams はノイズレベルに応じてバイラテラルフィルタの直径と色空間のσを強めます。
+// ノイズが少ない場合は設定値のまま（細かなテクスチャを残す）です。
+func adaptiveBilateralParams(noiseSigma float64, diameter int, sigmaColor float64) (int, float64) {
+	extra := min(noiseMaxExtraDiameter, 2*int(noiseSigma/noiseDiameterStep))
+	return diameter + extra, math.Max(sigmaColor, noiseSigmaColorFactor*noiseSigma)
+}
+
+// noiseCompensatedEdgeDecay はノイズの影響を補正したエッジ減衰率を計算します。
+//
+// ノイズは高周波成分のため、ぼかすとほぼ消えます。バイラテラルフィルタはなだらかな輝度変化の上の
+// ノイズを除去しきれないため、補正しないとノイズの多いボケ画像の減衰率が高くなります。
+// ノイズが多い場合は、ノイズ除去前の正規化済み画像で、ノイズ（分散σ²のホワイトノイズ）が
+// 元画像とぼかし後の画像のエッジエネルギーに与える寄与を差し引いた減衰率を使用します。
+// 線形フィルタのみを通すため、ノイズの寄与はフィルタ係数から正確に計算できます。
+func noiseCompensatedEdgeDecay(normalized, denoised [][]float64, noiseSigma float64, blurKernelSize int, blurSigma float64) float64 {
+	ratio := calculateEdgeDecayRatio(denoised, blurKernelSize, blurSigma)
+
+	w := (noiseSigma - noiseCompensationStart) / (noiseCompensationFull - noiseCompensationStart)
+	if w <= 0 {
+		return ratio
+	}
+	w = math.Min(1, w)
+
+	variance := noiseSigma * noiseSigma
+	origEnergy := calculateEdgeEnergy(normalized) - variance*laplacianNoiseGain(0, 0)
+	compensated := 0.0
+	if origEnergy >= 1.0 {
+		blurredEnergy := calculateEdgeEnergy(applyGaussianBlur2D(normalized, blurKernelSize, blurSigma)) -
+			variance*laplacianNoiseGain(blurKernelSize, blurSigma)
+		compensated = math.Max(0, math.Min(1, 1-math.Max(0, blurredEnergy)/origEnergy))
+	}
+	return (1-w)*ratio + w*compensated
+}
+
+// laplacianNoiseGain は分散1のホワイトノイズに、ガウシアンブラー（blurKernelSize が0の場合はなし）と
+// ラプラシアンを順に適用したときのエネルギー（合成したフィルタ係数の二乗和）を計算します。
+func laplacianNoiseGain(blurKernelSize int, blurSigma float64) float64 {
+	// インパルス応答がはみ出さない大きさの画像で合成フィルタの係数を求める
+	size := blurKernelSize + 5
+	impulse := make([][]float64, size)
+	for y := range impulse {
+		impulse[y] = make([]float64, size)
+	}
+	impulse[size/2][size/2] = 1
+	if blurKernelSize > 0 {
+		impulse = applyGaussianBlur2D(impulse, blurKernelSize, blurSigma)
+	}
+
+	sum := 0.0
+	for y := 1; y < size-1; y++ {
+		for x := 1; x < size-1; x++ {
+			lap := impulse[y][x]*(-4) + impulse[y-1][x] + impulse[y+1][x] + impulse[y][x-1] + impulse[y][x+1]
+			sum += lap * lap
+		}
+	}
+	return sum
+}
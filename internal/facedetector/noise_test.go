@@ -0,0 +1,159 @@
+package facedetector
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// addGaussianNoise は画像に標準偏差 sigma のガウスノイズを加えた画像を返します。
+func addGaussianNoise(img [][]float64, sigma float64) [][]float64 {
+	r := rand.New(rand.NewSource(7))
+	out := make([][]float64, len(img))
+	for y := range img {
+		out[y] = make([]float64, len(img[y]))
+		for x := range img[y] {
+			out[y][x] = img[y][x] + r.NormFloat64()*sigma
+		}
+	}
+	return out
+}
+
+// grayPNG は輝度値の2D配列をグレースケールのPNGにエンコードします。
+func grayPNG(t *testing.T, gray [][]float64) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, len(gray[0]), len(gray)))
+	for y := range gray {
+		for x := range gray[y] {
+			img.SetGray(x, y, color.Gray{Y: uint8(math.Max(0, math.Min(255, math.Round(gray[y][x]))))})
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestEstimateNoiseSigma(t *testing.T) {
+	base := stepEdgeImage(256, 256, 16)
+	for _, sigma := range []float64{3, 5, 10} {
+		got := estimateNoiseSigma(addGaussianNoise(base, sigma))
+		if math.Abs(got-sigma) > sigma*0.1 {
+			t.Errorf("estimateNoiseSigma(σ=%.0f) = %.2f, want within 10%%", sigma, got)
+		}
+	}
+
+	if got := estimateNoiseSigma(base); got > 0.1 {
+		t.Errorf("Expected ~0 for noise-free step edges, got %.2f", got)
+	}
+}
+
+func TestEstimateNoiseSigma_LargeImage(t *testing.T) {
+	// 上限を超える画像は格子点の画素のみで推定しても、ノイズは平均化されない
+	got := estimateNoiseSigma(addGaussianNoise(stepEdgeImage(1024, 1024, 16), 5))
+	if math.Abs(got-5) > 0.5 {
+		t.Errorf("estimateNoiseSigma(σ=5, 1024x1024) = %.2f, want within 10%%", got)
+	}
+}
+
+func TestLaplacianNoiseGain(t *testing.T) {
+	if got := laplacianNoiseGain(0, 0); math.Abs(got-20) > 1e-9 {
+		t.Errorf("laplacianNoiseGain(0, 0) = %.4f, want 20", got)
+	}
+	if got := laplacianNoiseGain(5, 2.0); got <= 0 || got >= 1 {
+		t.Errorf("Expected blurred gain in (0, 1), got %.4f", got)
+	}
+}
+
+func TestAdaptiveBilateralParams(t *testing.T) {
+	if d, sc := adaptiveBilateralParams(1, 5, 50); d != 5 || sc != 50 {
+		t.Errorf("Low noise should keep defaults, got d=%d sigmaColor=%.1f", d, sc)
+	}
+	if d, sc := adaptiveBilateralParams(20, 5, 50); d != 9 || sc != 60 {
+		t.Errorf("High noise: got d=%d sigmaColor=%.1f, want d=9 sigmaColor=60", d, sc)
+	}
+}
+
+func TestNoiseCompensatedEdgeDecay_NoisySoftVsNoisySharp(t *testing.T) {
+	sharp := addGaussianNoise(stepEdgeImage(256, 256, 16), 5)
+	soft := addGaussianNoise(applyGaussianBlur2D(stepEdgeImage(256, 256, 16), 13, 3.0), 5)
+
+	score := func(d *Detector, gray [][]float64) float64 {
+		normalized, denoised, noiseSigma := d.normalizeAndDenoise(gray, d.cfg.sharpnessNormalizeSize)
+		ratio := noiseCompensatedEdgeDecay(normalized, denoised, noiseSigma, d.cfg.edgeDecayBlurKernelSize, d.cfg.edgeDecayBlurSigma)
+		return decayRatioToScore(ratio, d.cfg.sigmoidMidpoint, d.cfg.sigmoidSteepness)
+	}
+
+	adaptive := New()
+	fixed := New(WithNoiseAdaptive(false))
+	sharpScore, softScore, softFixed := score(adaptive, sharp), score(adaptive, soft), score(fixed, soft)
+	t.Logf("sharp=%.1f soft=%.1f soft(fixed)=%.1f", sharpScore, softScore, softFixed)
+
+	// ノイズのあるボケ画像は、ノイズで水増しされたスコアにならない
+	if softScore >= softFixed {
+		t.Errorf("Expected compensated soft score (%.1f) < uncompensated (%.1f)", softScore, softFixed)
+	}
+	if sharpScore-softScore < 20 {
+		t.Errorf("Expected noisy sharp (%.1f) to score well above noisy soft (%.1f)", sharpScore, softScore)
+	}
+}
+
+func TestCalculateSharpness_NoiseSigma(t *testing.T) {
+	base := stepEdgeImage(128, 128, 16)
+
+	clean, err := CalculateSharpness(grayPNG(t, base))
+	if err != nil {
+		t.Fatalf("CalculateSharpness failed: %v", err)
+	}
+	noisy, err := CalculateSharpness(grayPNG(t, addGaussianNoise(base, 8)))
+	if err != nil {
+		t.Fatalf("CalculateSharpness failed: %v", err)
+	}
+
+	t.Logf("raw: clean=%.2f noisy=%.2f, analyzed: clean=%.2f noisy=%.2f",
+		clean.RawNoiseSigma, noisy.RawNoiseSigma, clean.NoiseSigma, noisy.NoiseSigma)
+	if clean.RawNoiseSigma > 1 {
+		t.Errorf("Expected low raw noise sigma for clean image, got %.2f", clean.RawNoiseSigma)
+	}
+	if math.Abs(noisy.RawNoiseSigma-8) > 1.5 {
+		t.Errorf("Expected raw noise sigma ~8, got %.2f", noisy.RawNoiseSigma)
+	}
+
+	// NoiseSigmaはスコアの計算に使用した正規化後の画像の値
+	if noisy.NoiseSigma <= clean.NoiseSigma {
+		t.Errorf("Expected higher noise sigma for the noisy image: clean=%.2f noisy=%.2f", clean.NoiseSigma, noisy.NoiseSigma)
+	}
+
+	disabled, err := New(WithNoiseAdaptive(false)).CalculateSharpness(grayPNG(t, addGaussianNoise(base, 8)))
+	if err != nil {
+		t.Fatalf("CalculateSharpness failed: %v", err)
+	}
+	if disabled.NoiseSigma != 0 || disabled.RawNoiseSigma == 0 {
+		t.Errorf("Expected NoiseSigma 0 and a raw estimate without noise adaptation, got %.2f / %.2f", disabled.NoiseSigma, disabled.RawNoiseSigma)
+	}
+}
+
+func TestCalculateNormalizedSharpness_NoiseSigmaUsed(t *testing.T) {
+	// 元の解像度（256px）と正規化後（128px）でノイズの大きさが異なる画像でも、
+	// NoiseSigmaはノイズ除去とスコアの補正に使用した値を返す
+	gray := addGaussianNoise(stepEdgeImage(256, 256, 16), 8)
+	d := New()
+	an, err := d.newAnalysis(nil)
+	if err != nil {
+		t.Fatalf("newAnalysis failed: %v", err)
+	}
+
+	result := d.calculateNormalizedSharpness(gray, 256, 256, an)
+	_, _, used := d.normalizeAndDenoise(gray, d.cfg.sharpnessNormalizeSize)
+	if want := math.Round(used*100) / 100; result.NoiseSigma != want {
+		t.Errorf("NoiseSigma = %.2f, want %.2f", result.NoiseSigma, want)
+	}
+	if want := math.Round(estimateNoiseSigma(gray)*100) / 100; result.RawNoiseSigma != want {
+		t.Errorf("RawNoiseSigma = %.2f, want %.2f", result.RawNoiseSigma, want)
+	}
+}
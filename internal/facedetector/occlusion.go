@@ -0,0 +1,184 @@
+package facedetector
+
+import (
+	"image"
+	"math"
+)
+
+// ============================================================================
+// 顔の遮蔽（マスク・サングラス・手）の検出
+// ============================================================================
+
+// 特徴点が無い場合に、顔矩形に対する平均的な位置から推定する鼻と頬の中心と大きさ
+// （顔矩形の幅・高さに対する割合）
+var (
+	estimatedNose       = [4]float64{0.5, 0.58, 0.2, 0.2}
+	estimatedLeftCheek  = [4]float64{0.25, 0.62, 0.18, 0.2}
+	estimatedRightCheek = [4]float64{0.75, 0.62, 0.18, 0.2}
+)
+
+// occlusionMaxSaturationSamples は顔の彩度の平均を求めるのに使用する画素数の上限（超える場合は間引く）
+const occlusionMaxSaturationSamples = 1 << 14
+
+// ZoneOcclusion は顔の部位1つの遮蔽の判定結果です。
+type ZoneOcclusion struct {
+	// BoundingBox は部位の矩形（元画像の座標系）。
+	BoundingBox BoundingBox `json:"bounding_box"`
+
+	// Source は部位の矩形を求めた方法。
+	Source RegionSource `json:"source"`
+
+	// SkinRatio は部位の中で肌色（検出時の偽陽性フィルタと同じHSVの範囲）の画素の割合（0.0〜1.0）。
+	SkinRatio float64 `json:"skin_ratio"`
+
+	// Occluded はSkinRatioが下限（WithOcclusionSkinRatio）未満で、部位が隠れているとみなすかどうか。
+	Occluded bool `json:"occluded"`
+}
+
+// OcclusionZones は顔の部位ごとの遮蔽の判定結果です。部位の矩形が小さすぎる場合は省略されます。
+// 左右は画像上の左右です（回転した画像から見つかった顔では、顔を正立させた画像上の左右）。
+type OcclusionZones struct {
+	LeftEye    *ZoneOcclusion `json:"left_eye,omitempty"`
+	RightEye   *ZoneOcclusion `json:"right_eye,omitempty"`
+	Nose       *ZoneOcclusion `json:"nose,omitempty"`
+	Mouth      *ZoneOcclusion `json:"mouth,omitempty"`
+	LeftCheek  *ZoneOcclusion `json:"left_cheek,omitempty"`
+	RightCheek *ZoneOcclusion `json:"right_cheek,omitempty"`
+}
+
+// Occlusion は顔の遮蔽の判定結果です。
+// マスクや手で覆われた顔は検出され鮮明と判定されることがあるため、本人確認で差し戻すために使用します。
+type Occlusion struct {
+	// Occluded はいずれかの部位が隠れているかどうか。
+	Occluded bool `json:"occluded"`
+
+	// EyesVisible は両目の部位が隠れていないかどうか（サングラスなどの検出）。
+	EyesVisible bool `json:"eyes_visible"`
+
+	// MouthVisible は口の部位が隠れていないかどうか（マスクなどの検出）。
+	MouthVisible bool `json:"mouth_visible"`
+
+	// Zones は部位ごとの判定結果。
+	// モノクロや彩度の低い画像で肌色を判定できない場合はnilで、Occluded・EyesVisible・MouthVisibleはいずれもfalseです。
+	Zones *OcclusionZones `json:"zones,omitempty"`
+}
+
+// analyzeOcclusion は顔の部位ごとの肌色の割合から遮蔽を判定します。
+// 目・口の矩形は部位ごとの鮮明度と同じく特徴点・目のHaar Cascade・推定の順に求め、
+// 鼻と頬は特徴点があれば特徴点から、なければ顔矩形に対する平均的な位置から推定します。
+// 顔の中心領域の平均の彩度が肌色の下限（skinSatMin）未満の場合は、どの部位も肌色にならないため判定しません。
+func (d *Detector) analyzeOcclusion(img image.Image, face Face, eyes eyeDetection) Occlusion {
+	center := shrinkRect(clipRect(face.Rect, img.Bounds()), d.cfg.faceCenterRatio)
+	if meanSaturation(img, center) < skinSatMin {
+		return Occlusion{}
+	}
+
+	leftEye, rightEye, mouth := locateFaceRegions(face, eyes)
+	nose, leftCheek, rightCheek := locateOcclusionZones(face)
+
+	zones := &OcclusionZones{
+		LeftEye:    d.zoneOcclusion(img, leftEye),
+		RightEye:   d.zoneOcclusion(img, rightEye),
+		Nose:       d.zoneOcclusion(img, nose),
+		Mouth:      d.zoneOcclusion(img, mouth),
+		LeftCheek:  d.zoneOcclusion(img, leftCheek),
+		RightCheek: d.zoneOcclusion(img, rightCheek),
+	}
+
+	occluded := func(zs ...*ZoneOcclusion) bool {
+		for _, z := range zs {
+			if z != nil && z.Occluded {
+				return true
+			}
+		}
+		return false
+	}
+	return Occlusion{
+		Occluded:     occluded(zones.LeftEye, zones.RightEye, zones.Nose, zones.Mouth, zones.LeftCheek, zones.RightCheek),
+		EyesVisible:  !occluded(zones.LeftEye, zones.RightEye),
+		MouthVisible: !occluded(zones.Mouth),
+		Zones:        zones,
+	}
+}
+
+// locateOcclusionZones は画像上の鼻・左頬・右頬の矩形を求めます。
+// 頬は外側の目尻と同じ側の口角の中間を中心に、目の幅の正方形とします。
+func locateOcclusionZones(face Face) (nose, leftCheek, rightCheek locatedRegion) {
+	if face.Landmarks.Valid() {
+		lm := face.Landmarks
+		cheek := func(eyeCorner, mouthCorner, innerCorner LandmarkPoint) image.Rectangle {
+			cx, cy := (eyeCorner.X+mouthCorner.X)/2, (eyeCorner.Y+mouthCorner.Y)/2
+			half := math.Hypot(innerCorner.X-eyeCorner.X, innerCorner.Y-eyeCorner.Y) / 2
+			return image.Rect(int(math.Round(cx-half)), int(math.Round(cy-half)), int(math.Round(cx+half)), int(math.Round(cy+half)))
+		}
+		return locatedRegion{landmarkBounds(lm.Nose()), RegionFromLandmarks},
+			locatedRegion{cheek(lm[36], lm[48], lm[39]), RegionFromLandmarks},
+			locatedRegion{cheek(lm[45], lm[54], lm[42]), RegionFromLandmarks}
+	}
+	return locatedRegion{estimatedRegion(face.Rect, estimatedNose), RegionEstimated},
+		locatedRegion{estimatedRegion(face.Rect, estimatedLeftCheek), RegionEstimated},
+		locatedRegion{estimatedRegion(face.Rect, estimatedRightCheek), RegionEstimated}
+}
+
+// zoneOcclusion は部位の矩形の肌色の割合を計算します。矩形が小さすぎる場合はnilを返します。
+func (d *Detector) zoneOcclusion(img image.Image, region locatedRegion) *ZoneOcclusion {
+	rect := clipRect(region.rect, img.Bounds())
+	if rect.Dx() < regionMinSize || rect.Dy() < regionMinSize {
+		return nil
+	}
+
+	ratio := imageSkinRatio(img, rect)
+	return &ZoneOcclusion{
+		BoundingBox: newBoundingBox(rect),
+		Source:      region.source,
+		SkinRatio:   math.Round(ratio*1000) / 1000,
+		Occluded:    ratio < d.cfg.occlusionSkinMinRatio,
+	}
+}
+
+// imageSkinRatio は画像の矩形をOpenCVのMatに変換し、isSkinColorと同じ肌色の判定（skinRatio）で
+// 肌色の画素の割合を返します。
+func imageSkinRatio(img image.Image, rect image.Rectangle) float64 {
+	var zone image.Image
+	if sub, ok := img.(subImager); ok {
+		zone = sub.SubImage(rect)
+	} else {
+		zone = toRGBA(img).SubImage(rect.Sub(img.Bounds().Min))
+	}
+
+	mat, err := imageToMat(zone)
+	if err != nil {
+		return 0
+	}
+	defer mat.Close()
+	if mat.Empty() {
+		return 0
+	}
+	return skinRatio(mat, image.Rect(0, 0, mat.Cols(), mat.Rows()))
+}
+
+// meanSaturation は画像の矩形のHSVの彩度の平均（OpenCVと同じ0〜255）を返します。
+// 大きな矩形では一定間隔で間引いた画素で計算します。矩形が空の場合は0を返します。
+func meanSaturation(img image.Image, rect image.Rectangle) float64 {
+	if rect.Empty() {
+		return 0
+	}
+	step := 1
+	for (rect.Dx()/step)*(rect.Dy()/step) > occlusionMaxSaturationSamples {
+		step++
+	}
+
+	sum := 0.0
+	count := 0
+	for y := rect.Min.Y; y < rect.Max.Y; y += step {
+		for x := rect.Min.X; x < rect.Max.X; x += step {
+			r, g, b, _ := img.At(x, y).RGBA()
+			hi := max(r, g, b)
+			if hi > 0 {
+				sum += 255 * float64(hi-min(r, g, b)) / float64(hi)
+			}
+			count++
+		}
+	}
+	return sum / float64(count)
+}
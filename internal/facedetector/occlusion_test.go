@@ -0,0 +1,152 @@
+package facedetector
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// skinFace は灰色の背景の中の顔矩形を肌色で塗りつぶした画像を返します。
+func skinFace(w, h int, faceRect image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{128, 128, 128, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, faceRect, image.NewUniform(color.RGBA{210, 160, 130, 255}), image.Point{}, draw.Src)
+	return img
+}
+
+func TestImageSkinRatio(t *testing.T) {
+	tests := []struct {
+		name    string
+		r, g, b uint8
+		want    bool
+	}{
+		{"light skin", 230, 190, 170, true},
+		{"medium skin", 200, 140, 100, true},
+		{"dark skin", 110, 70, 50, true},
+		{"black", 20, 20, 20, false},
+		{"white", 240, 240, 240, false},
+		{"blue mask", 120, 170, 220, false},
+		{"green", 60, 160, 80, false},
+	}
+	d := New()
+	for _, tt := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{tt.r, tt.g, tt.b, 255}), image.Point{}, draw.Src)
+
+		// 原点が左上でない部分画像でも、矩形の中だけを判定する
+		want := 0.0
+		if tt.want {
+			want = 1
+		}
+		if got := imageSkinRatio(img, image.Rect(10, 10, 30, 30)); got != want {
+			t.Errorf("%s: imageSkinRatio(%d, %d, %d) = %v, want %v", tt.name, tt.r, tt.g, tt.b, got, want)
+		}
+
+		// 検出時の偽陽性フィルタと同じ判定になる
+		mat, err := imageToMat(img)
+		if err != nil {
+			t.Fatalf("imageToMat failed: %v", err)
+		}
+		if got := d.isSkinColor(mat, img.Bounds()); got != tt.want {
+			t.Errorf("%s: isSkinColor = %v, want %v", tt.name, got, tt.want)
+		}
+		mat.Close()
+	}
+}
+
+func TestAnalyzeOcclusion(t *testing.T) {
+	rect := image.Rect(20, 20, 220, 220)
+	d := New()
+
+	for _, withLandmarks := range []bool{false, true} {
+		face := Face{Rect: rect}
+		if withLandmarks {
+			face.Landmarks = syntheticLandmarks(rect)
+		}
+
+		// 隠れていない顔
+		o := d.analyzeOcclusion(skinFace(240, 240, rect), face, eyeDetection{})
+		if o.Occluded || !o.EyesVisible || !o.MouthVisible {
+			t.Errorf("landmarks=%v: expected an unoccluded face, got %+v", withLandmarks, o)
+		}
+		zones := []*ZoneOcclusion{o.Zones.LeftEye, o.Zones.RightEye, o.Zones.Nose, o.Zones.Mouth, o.Zones.LeftCheek, o.Zones.RightCheek}
+		for i, z := range zones {
+			if z == nil {
+				t.Fatalf("landmarks=%v: zone %d is missing", withLandmarks, i)
+			}
+		}
+
+		// 顔の下半分を水色のマスクで覆う
+		masked := skinFace(240, 240, rect)
+		draw.Draw(masked, image.Rect(rect.Min.X, rect.Min.Y+rect.Dy()/2, rect.Max.X, rect.Max.Y),
+			image.NewUniform(color.RGBA{150, 200, 230, 255}), image.Point{}, draw.Src)
+		o = d.analyzeOcclusion(masked, face, eyeDetection{})
+		if !o.Occluded || o.MouthVisible || !o.EyesVisible {
+			t.Errorf("landmarks=%v: expected the mouth to be covered, got occluded=%v eyes=%v mouth=%v",
+				withLandmarks, o.Occluded, o.EyesVisible, o.MouthVisible)
+		}
+		if o.Zones.Mouth.SkinRatio != 0 {
+			t.Errorf("landmarks=%v: mouth skin ratio = %.3f, want 0", withLandmarks, o.Zones.Mouth.SkinRatio)
+		}
+
+		// 目の高さを黒いサングラスで覆う
+		sunglasses := skinFace(240, 240, rect)
+		draw.Draw(sunglasses, image.Rect(rect.Min.X, rect.Min.Y+rect.Dy()/4, rect.Max.X, rect.Min.Y+rect.Dy()/2),
+			image.NewUniform(color.RGBA{15, 15, 20, 255}), image.Point{}, draw.Src)
+		o = d.analyzeOcclusion(sunglasses, face, eyeDetection{})
+		if !o.Occluded || o.EyesVisible || !o.MouthVisible {
+			t.Errorf("landmarks=%v: expected the eyes to be covered, got occluded=%v eyes=%v mouth=%v",
+				withLandmarks, o.Occluded, o.EyesVisible, o.MouthVisible)
+		}
+	}
+}
+
+func TestAnalyzeOcclusion_Grayscale(t *testing.T) {
+	rect := image.Rect(20, 20, 220, 220)
+	face := Face{Rect: rect, Landmarks: syntheticLandmarks(rect)}
+
+	// モノクロ画像では肌色を判定できないため、隠れているとはみなさない
+	gray := image.NewGray(image.Rect(0, 0, 240, 240))
+	draw.Draw(gray, gray.Bounds(), skinFace(240, 240, rect), image.Point{}, draw.Src)
+	o := New().analyzeOcclusion(gray, face, eyeDetection{})
+	if o.Occluded || o.Zones != nil {
+		t.Errorf("Expected occlusion to be undetermined for a grayscale face, got %+v", o)
+	}
+
+	if got := meanSaturation(skinFace(240, 240, rect), rect); got < skinSatMin {
+		t.Errorf("meanSaturation(skin) = %.1f, want at least %d", got, skinSatMin)
+	}
+	if got := meanSaturation(gray, rect); got != 0 {
+		t.Errorf("meanSaturation(gray) = %.1f, want 0", got)
+	}
+}
+
+func TestAnalyzeOcclusion_SkinRatioOption(t *testing.T) {
+	rect := image.Rect(0, 0, 200, 200)
+	img := skinFace(200, 200, rect)
+
+	// 口の矩形の左半分だけを覆う
+	mouth := estimatedRegion(rect, estimatedMouth)
+	draw.Draw(img, image.Rect(mouth.Min.X, mouth.Min.Y, (mouth.Min.X+mouth.Max.X)/2, mouth.Max.Y),
+		image.NewUniform(color.RGBA{255, 255, 255, 255}), image.Point{}, draw.Src)
+
+	if o := New().analyzeOcclusion(img, Face{Rect: rect}, eyeDetection{}); o.Occluded {
+		t.Errorf("Expected a half-covered mouth to pass the default ratio, got %+v", o.Zones.Mouth)
+	}
+	if o := New(WithOcclusionSkinRatio(0.6)).analyzeOcclusion(img, Face{Rect: rect}, eyeDetection{}); o.MouthVisible {
+		t.Errorf("Expected a half-covered mouth to be occluded with ratio 0.6, got %+v", o.Zones.Mouth)
+	}
+}
+
+func TestSummarizeFacesSharpness_Occluded(t *testing.T) {
+	faces := []FaceSharpness{
+		{Occlusion: Occlusion{Occluded: true}},
+		{Occlusion: Occlusion{EyesVisible: true, MouthVisible: true}},
+	}
+
+	result := summarizeFacesSharpness(faces, 50)
+	if result.OccludedCount != 1 || !result.AnyOccluded {
+		t.Errorf("OccludedCount = %d, AnyOccluded = %v, want 1 / true", result.OccludedCount, result.AnyOccluded)
+	}
+}
@@ -0,0 +1,556 @@
+package facedetector
+
+// ============================================================================
+// 設定とオプション
+// ============================================================================
+
+// config はDetectorの検出・鮮明度計算パラメータを保持します。
+// 値はNew()でdefaultConfig()から初期化され、Optionで上書きされます。
+type config struct {
+	// DNN モデルファイルのパス（空の場合は検索パスから自動解決）
+	dnnProtoPath string
+	dnnModelPath string
+
+	// DNN検出の信頼度閾値
+	// 商用環境では 0.5 を標準とし、多段階検出で閾値を下げて補完する
+	dnnConfidenceHigh float32 // 高信頼度（単独で採用）
+	dnnConfidenceLow  float32 // 低信頼度（交差検証用）
+
+	// NMS（Non-Maximum Suppression）のIoU閾値
+	nmsIOUThreshold float64
+
+	// 肌色検出: 肌色ピクセルの最低割合
+	skinColorMinRatio float64
+
+	// アスペクト比の許容範囲（顔として妥当な範囲）
+	aspectRatioMin float64
+	aspectRatioMax float64
+
+	// 顔矩形の最小サイズ（ピクセル）
+	minFaceSize int
+
+	// CLAHE パラメータ
+	claheClipLimit float64
+	claheTileSize  int
+
+	// ガンマ補正パラメータ
+	gammaForDark   float64 // 暗い画像用（明るくする）
+	gammaForBright float64 // 明るすぎる画像用（暗くする）
+
+	// 画像の明るさ判定閾値
+	darkThreshold   float64 // この値以下なら「暗い」と判定
+	brightThreshold float64 // この値以上なら「明るすぎる」と判定
+
+	// 露出の判定で白飛び・黒つぶれとみなす画素の割合の上限（%）
+	exposureClipPercent float64
+
+	// 逆光とみなす顔の周囲と顔の輝度の比
+	backlightRatio float64
+
+	// 照明が均一（even）とみなす照明の均一性スコアの下限
+	lightingUniformityThreshold float64
+
+	// Haar Cascade分類器のファイルパスリスト（先頭から順に試行）
+	cascadeFiles []string
+
+	// 目のHaar Cascade分類器のファイルパスリスト（特徴点が無い場合の目の位置と開閉の検出用）
+	eyeCascadeFiles []string
+
+	// 目が開いているとみなす目のアスペクト比（EAR）の下限
+	eyeAspectRatioThreshold float64
+
+	// 正面を向いているとみなすヨー・ピッチ・ロールの許容範囲（度）
+	poseYawTolerance   float64
+	posePitchTolerance float64
+	poseRollTolerance  float64
+
+	// 部位が隠れていないとみなす肌色の画素の割合の下限
+	occlusionSkinMinRatio float64
+
+	// 顔が見つからない場合に画像を90・180・270度回転して再検出するかどうか
+	rotationRetry bool
+
+	// 顔検出バックエンドのチェーン（nilの場合はDNN + Haar Cascadeを構成）
+	backends []FaceDetector
+
+	// デフォルトのチェーンにHaar Cascadeのフォールバックを含めるかどうか
+	cascadeFallback bool
+
+	// 鮮明度計算の基準サイズ（ピクセル）
+	// カメラの画素数に依存しないよう、この大きさに統一してから計算する
+	sharpnessNormalizeSize int
+
+	// エッジ減衰率計算用のガウシアンブラーのカーネルサイズとσ値
+	edgeDecayBlurKernelSize int
+	edgeDecayBlurSigma      float64
+
+	// バイラテラルフィルタのパラメータ
+	bilateralD          int     // フィルタの直径
+	bilateralSigmaColor float64 // 色空間のσ
+	bilateralSigmaSpace float64 // 座標空間のσ
+
+	// 推定したノイズレベルに応じてバイラテラルフィルタを強め、スコアをノイズ補正するかどうか
+	noiseAdaptive bool
+
+	// 顔中心マスクの比率（顔矩形に対する中心領域の割合）
+	faceCenterRatio float64
+
+	// スコアマッピング用パラメータ（シグモイド関数）
+	sigmoidMidpoint  float64 // 減衰率がこの値で約50点
+	sigmoidSteepness float64 // カーブの急峻さ
+
+	// ブレ判定閾値（NormalizedScoreがこの値未満の顔を「ブレあり」とみなす）
+	blurThreshold float64
+
+	// 高周波エネルギー比のカットオフ周波数（ナイキスト周波数に対する割合）
+	frequencyCutoff float64
+
+	// NormalizedScoreの算出に使用する鮮明度指標の名前
+	scoreBasis string
+
+	// 手ブレとみなす構造テンソルのコヒーレンスの閾値（これ未満はピンボケ）
+	motionCoherenceThreshold float64
+
+	// 鮮明度ヒートマップのタイルの列数・行数
+	heatmapCols int
+	heatmapRows int
+
+	// 組み込みの指標に追加で登録する鮮明度指標（同名の場合は置き換え）
+	customMetrics []SharpnessMetric
+
+	// 顔の特徴点を検出するかどうかと、LBF Facemarkモデルのパス（空の場合は検索パスから自動解決）
+	landmarks         bool
+	landmarkModelPath string
+}
+
+// defaultCascadeFiles はHaar Cascade分類器のデフォルトのファイルパスリスト
+var defaultCascadeFiles = []string{
+	"cascade/haarcascade_frontalface_alt2.xml",
+	"/usr/share/opencv4/haarcascades/haarcascade_frontalface_default.xml",
+	"/usr/share/opencv4/haarcascades/haarcascade_frontalface_alt.xml",
+	"/usr/share/opencv4/haarcascades/haarcascade_frontalface_alt2.xml",
+	"/usr/share/opencv4/haarcascades/haarcascade_profileface.xml",
+	"/usr/local/share/opencv4/haarcascades/haarcascade_frontalface_default.xml",
+	"/usr/local/share/opencv4/haarcascades/haarcascade_frontalface_alt2.xml",
+}
+
+// defaultConfig は実運用でチューニング済みのデフォルト設定を返します。
+func defaultConfig() config {
+	return config{
+		dnnConfidenceHigh: 0.5,
+		dnnConfidenceLow:  0.25,
+		nmsIOUThreshold:   0.3,
+		skinColorMinRatio: 0.10,
+		aspectRatioMin:    0.5,
+		aspectRatioMax:    1.8,
+		minFaceSize:       20,
+		claheClipLimit:    3.0,
+		claheTileSize:     8,
+		gammaForDark:      1.8,
+		gammaForBright:    0.6,
+		darkThreshold:     80.0,
+		brightThreshold:   180.0,
+		cascadeFiles:      append([]string(nil), defaultCascadeFiles...),
+		eyeCascadeFiles:   append([]string(nil), defaultEyeCascadeFiles...),
+		cascadeFallback:   true,
+
+		sharpnessNormalizeSize:  128,
+		edgeDecayBlurKernelSize: 5,
+		edgeDecayBlurSigma:      2.0,
+		bilateralD:              5,
+		bilateralSigmaColor:     50.0,
+		bilateralSigmaSpace:     50.0,
+		noiseAdaptive:           true,
+		faceCenterRatio:         0.6,
+		sigmoidMidpoint:         0.45,
+		sigmoidSteepness:        10.0,
+		blurThreshold:           50.0,
+		frequencyCutoff:         0.25,
+		scoreBasis:              MetricEdgeDecay,
+
+		motionCoherenceThreshold: 0.4,
+		heatmapCols:              8,
+		heatmapRows:              8,
+		exposureClipPercent:      5.0,
+		backlightRatio:           2.0,
+
+		lightingUniformityThreshold: 60.0,
+
+		landmarks:               true,
+		eyeAspectRatioThreshold: 0.2,
+		poseYawTolerance:        15.0,
+		posePitchTolerance:      15.0,
+		poseRollTolerance:       10.0,
+		occlusionSkinMinRatio:   0.3,
+	}
+}
+
+// Option はDetectorの設定を変更する関数です。
+type Option func(*config)
+
+// WithDNNModelFiles はDNNモデル（prototxt / caffemodel）のパスを明示的に指定します。
+// 指定しない場合は dnnModelSearchPaths() の候補から自動的に検索します。
+func WithDNNModelFiles(protoPath, modelPath string) Option {
+	return func(c *config) {
+		c.dnnProtoPath = protoPath
+		c.dnnModelPath = modelPath
+	}
+}
+
+// WithDNNConfidence はDNN検出の信頼度閾値（高・低）を設定します。
+// high 以上の検出は偽陽性フィルタを緩和し、low 以上の検出を候補として扱います。
+func WithDNNConfidence(high, low float32) Option {
+	return func(c *config) {
+		c.dnnConfidenceHigh = high
+		c.dnnConfidenceLow = low
+	}
+}
+
+// WithNMSIOUThreshold はNMSで重複とみなすIoU閾値を設定します。
+func WithNMSIOUThreshold(threshold float64) Option {
+	return func(c *config) {
+		c.nmsIOUThreshold = threshold
+	}
+}
+
+// WithSkinColorMinRatio は肌色フィルタで要求する肌色ピクセルの最低割合を設定します。
+func WithSkinColorMinRatio(ratio float64) Option {
+	return func(c *config) {
+		c.skinColorMinRatio = ratio
+	}
+}
+
+// WithAspectRatioRange は顔矩形として許容するアスペクト比（幅/高さ）の範囲を設定します。
+func WithAspectRatioRange(min, max float64) Option {
+	return func(c *config) {
+		c.aspectRatioMin = min
+		c.aspectRatioMax = max
+	}
+}
+
+// WithMinFaceSize は顔矩形の最小サイズ（ピクセル）を設定します。
+func WithMinFaceSize(size int) Option {
+	return func(c *config) {
+		c.minFaceSize = size
+	}
+}
+
+// WithCLAHE は前処理で使用するCLAHEのクリップ上限とタイルサイズを設定します。
+func WithCLAHE(clipLimit float64, tileSize int) Option {
+	return func(c *config) {
+		c.claheClipLimit = clipLimit
+		c.claheTileSize = tileSize
+	}
+}
+
+// WithGamma は暗い画像・明るすぎる画像に適用するガンマ値を設定します。
+func WithGamma(forDark, forBright float64) Option {
+	return func(c *config) {
+		c.gammaForDark = forDark
+		c.gammaForBright = forBright
+	}
+}
+
+// WithBrightnessThresholds はガンマ補正を行う平均輝度の閾値（暗い・明るい）を設定します。
+func WithBrightnessThresholds(dark, bright float64) Option {
+	return func(c *config) {
+		c.darkThreshold = dark
+		c.brightThreshold = bright
+	}
+}
+
+// WithExposureClipPercent は露出の判定で、白飛び・黒つぶれの画素がこの割合（%）以上の場合に
+// 露出過多・露出不足とみなす上限を設定します。
+func WithExposureClipPercent(percent float64) Option {
+	return func(c *config) {
+		c.exposureClipPercent = percent
+	}
+}
+
+// WithBacklightRatio は顔の周囲が顔のこの倍率以上明るい場合に逆光とみなす閾値を設定します。
+func WithBacklightRatio(ratio float64) Option {
+	return func(c *config) {
+		c.backlightRatio = ratio
+	}
+}
+
+// WithLightingUniformityThreshold は顔の照明を均一（even）とみなす均一性スコア（0〜100）の下限を設定します。
+func WithLightingUniformityThreshold(threshold float64) Option {
+	return func(c *config) {
+		c.lightingUniformityThreshold = threshold
+	}
+}
+
+// WithCascadeFiles はHaar Cascade分類器のファイルパスリストを置き換えます。
+// 先頭から順に試行し、顔が見つかった時点で以降の分類器は使用しません。
+func WithCascadeFiles(files ...string) Option {
+	return func(c *config) {
+		c.cascadeFiles = append([]string(nil), files...)
+	}
+}
+
+// WithEyeCascadeFiles は目のHaar Cascade分類器のファイルパスリストを置き換えます。
+// 顔の特徴点が無い場合に、部位ごとの鮮明度を計算する目の位置と目の開閉の検出に使用します。
+func WithEyeCascadeFiles(files ...string) Option {
+	return func(c *config) {
+		c.eyeCascadeFiles = append([]string(nil), files...)
+	}
+}
+
+// WithEyeAspectRatioThreshold は目が開いているとみなす目のアスペクト比（EAR）の下限を設定します。
+// 特徴点で目の開閉を判定する場合に使用します（デフォルト: 0.2）。
+func WithEyeAspectRatioThreshold(threshold float64) Option {
+	return func(c *config) {
+		c.eyeAspectRatioThreshold = threshold
+	}
+}
+
+// WithPoseTolerance は正面を向いているとみなすヨー・ピッチ・ロールの許容範囲（度）を設定します
+// （デフォルト: ヨー15度、ピッチ15度、ロール10度）。
+func WithPoseTolerance(yaw, pitch, roll float64) Option {
+	return func(c *config) {
+		c.poseYawTolerance = yaw
+		c.posePitchTolerance = pitch
+		c.poseRollTolerance = roll
+	}
+}
+
+// WithOcclusionSkinRatio は顔の部位が隠れていないとみなす肌色の画素の割合の下限を設定します
+// （デフォルト: 0.3）。髭や濃い化粧で遮蔽と誤判定される場合は下げます。
+func WithOcclusionSkinRatio(ratio float64) Option {
+	return func(c *config) {
+		c.occlusionSkinMinRatio = ratio
+	}
+}
+
+// WithBackends は顔検出バックエンドのチェーンを置き換えます。
+// 先頭から順に試行し、最初に顔を検出したバックエンドの結果を採用します。
+// 独自の検出器を追加する場合は、NewDNNDetector / NewHaarDetector と組み合わせて指定します。
+func WithBackends(backends ...FaceDetector) Option {
+	return func(c *config) {
+		c.backends = append([]FaceDetector(nil), backends...)
+	}
+}
+
+// WithCascadeFallback はデフォルトのチェーンでHaar Cascadeのフォールバックを使うかどうかを設定します。
+// レイテンシを優先する場合はfalseにしてDNNのみで検出します。WithBackendsを指定した場合は無視されます。
+func WithCascadeFallback(enabled bool) Option {
+	return func(c *config) {
+		c.cascadeFallback = enabled
+	}
+}
+
+// WithRotationRetry は顔が見つからない場合に、画像を時計回りに90・180・270度回転して
+// 再検出するかどうかを設定します。デフォルトは無効です（見つからない画像の処理時間が最大4倍になるため）。
+// 見つかった顔の座標は元の画像の座標系に戻し、回転した角度をFace.Rotationに設定します。
+func WithRotationRetry(enabled bool) Option {
+	return func(c *config) {
+		c.rotationRetry = enabled
+	}
+}
+
+// WithSharpnessNormalizeSize は鮮明度計算前に揃える基準サイズ（ピクセル）を設定します。
+func WithSharpnessNormalizeSize(size int) Option {
+	return func(c *config) {
+		c.sharpnessNormalizeSize = size
+	}
+}
+
+// WithEdgeDecayBlur はエッジ減衰率計算に使用するガウシアンブラーのカーネルサイズとσ値を設定します。
+func WithEdgeDecayBlur(kernelSize int, sigma float64) Option {
+	return func(c *config) {
+		c.edgeDecayBlurKernelSize = kernelSize
+		c.edgeDecayBlurSigma = sigma
+	}
+}
+
+// WithBilateral はノイズ除去用バイラテラルフィルタのパラメータを設定します。
+func WithBilateral(d int, sigmaColor, sigmaSpace float64) Option {
+	return func(c *config) {
+		c.bilateralD = d
+		c.bilateralSigmaColor = sigmaColor
+		c.bilateralSigmaSpace = sigmaSpace
+	}
+}
+
+// WithNoiseAdaptive はノイズレベルに応じた適応的ノイズ除去とスコアのノイズ補正を有効にするかどうかを設定します。
+// デフォルトは有効です。無効にするとWithBilateralの設定値で固定のノイズ除去を行います。
+func WithNoiseAdaptive(enabled bool) Option {
+	return func(c *config) {
+		c.noiseAdaptive = enabled
+	}
+}
+
+// WithFaceCenterRatio は顔矩形のうち鮮明度評価に使う中心領域の割合を設定します。
+func WithFaceCenterRatio(ratio float64) Option {
+	return func(c *config) {
+		c.faceCenterRatio = ratio
+	}
+}
+
+// WithSigmoid はエッジ減衰率を0〜100点に変換するシグモイド関数の中点と急峻さを設定します。
+func WithSigmoid(midpoint, steepness float64) Option {
+	return func(c *config) {
+		c.sigmoidMidpoint = midpoint
+		c.sigmoidSteepness = steepness
+	}
+}
+
+// WithBlurThreshold は顔を「ブレあり」とみなすNormalizedScoreの閾値を設定します。
+func WithBlurThreshold(threshold float64) Option {
+	return func(c *config) {
+		c.blurThreshold = threshold
+	}
+}
+
+// WithFrequencyCutoff は高周波エネルギー比の計算で「高周波」とみなすカットオフ周波数を
+// ナイキスト周波数に対する割合（0.0〜1.0）で設定します。
+func WithFrequencyCutoff(cutoff float64) Option {
+	return func(c *config) {
+		c.frequencyCutoff = cutoff
+	}
+}
+
+// WithDefaultScoreBasis はNormalizedScoreの算出に使用する鮮明度指標をDetectorの既定として設定します。
+// デフォルトは MetricEdgeDecay です。解析呼び出しごとに WithScoreBasis で変更できます。
+func WithDefaultScoreBasis(name string) Option {
+	return func(c *config) {
+		c.scoreBasis = name
+	}
+}
+
+// WithMotionCoherenceThreshold はブレを手ブレ（motion）と判定する構造テンソルのコヒーレンスの閾値
+// （0.0〜1.0）を設定します。勾配の向きの偏りがこの値未満のブレはピンボケ（defocus）と判定します。
+func WithMotionCoherenceThreshold(threshold float64) Option {
+	return func(c *config) {
+		c.motionCoherenceThreshold = threshold
+	}
+}
+
+// WithHeatmapGrid は鮮明度ヒートマップのタイルの列数・行数を設定します。
+func WithHeatmapGrid(cols, rows int) Option {
+	return func(c *config) {
+		c.heatmapCols = cols
+		c.heatmapRows = rows
+	}
+}
+
+// WithLandmarkModel はLBF Facemarkモデル（lbfmodel.yaml）のパスを明示的に指定します。
+// 指定しない場合はDNNモデルと同じ検索パスから自動的に検索します。
+func WithLandmarkModel(path string) Option {
+	return func(c *config) {
+		c.landmarkModelPath = path
+	}
+}
+
+// WithLandmarks は検出した顔の特徴点（68点）を推定するかどうかを設定します。
+// デフォルトは有効です（モデルファイルが見つからない場合は推定しません）。
+func WithLandmarks(enabled bool) Option {
+	return func(c *config) {
+		c.landmarks = enabled
+	}
+}
+
+// WithCustomMetrics は鮮明度指標をDetectorに登録します。
+// 組み込みの指標と同じ名前の場合は置き換えます。登録した指標はWithMetricsで名前を指定して使用します。
+func WithCustomMetrics(metrics ...SharpnessMetric) Option {
+	return func(c *config) {
+		c.customMetrics = append(c.customMetrics, metrics...)
+	}
+}
+
+// ============================================================================
+// 解析呼び出しごとのオプション
+// ============================================================================
+
+// analysisConfig は1回の解析呼び出しに対する設定を保持します。
+type analysisConfig struct {
+	// SharpnessResult.Metricsに出力する鮮明度指標の名前
+	metrics []string
+
+	// NormalizedScoreの算出に使用する鮮明度指標の名前（空の場合はDetectorの既定）
+	scoreBasis string
+
+	// 顔のNormalizedScoreを目の領域で算出するかどうか
+	eyeRegionScore bool
+
+	// 正面を向いていない顔を鮮明度の評価から除外するかどうか
+	frontalOnly bool
+}
+
+// AnalysisOption はCalculateFaceSharpnessなどの解析呼び出し1回分の設定を変更する関数です。
+// Detectorの設定（Option）と異なり、リクエストごとに指定できます。
+type AnalysisOption func(*analysisConfig)
+
+// WithMetrics は計算してSharpnessResult.Metricsに並べて出力する鮮明度指標を名前で指定します。
+// 未登録の名前を指定した場合、解析はErrUnknownMetricを返します。
+func WithMetrics(names ...string) AnalysisOption {
+	return func(c *analysisConfig) {
+		c.metrics = append(c.metrics, names...)
+	}
+}
+
+// WithScoreBasis はこの呼び出しでNormalizedScoreの算出に使用する鮮明度指標を名前で指定します。
+// 境界付近の顔を別の指標で再評価する場合などに使用します。
+func WithScoreBasis(name string) AnalysisOption {
+	return func(c *analysisConfig) {
+		c.scoreBasis = name
+	}
+}
+
+// WithEyeRegionScore は顔のNormalizedScoreとブレの種類を、顔の中心領域ではなく目の領域
+// （スコアの低い方の目）で判定します。顎や頬よりも目の鮮明さが重要な本人確認などで使用します。
+func WithEyeRegionScore() AnalysisOption {
+	return func(c *analysisConfig) {
+		c.eyeRegionScore = true
+	}
+}
+
+// WithFrontalOnly は頭部姿勢が許容範囲（WithPoseTolerance）外の顔を鮮明度の評価の前に除外します。
+// 横顔などを正面の顔と同じ基準で評価しないよう、本人確認の撮影で使用します。
+// 姿勢の推定には顔の特徴点が必要なため、LBF Facemarkモデルが無い場合（WithLandmarks(false)を含む）は
+// ErrModelUnavailableを返し、特徴点を推定できなかった顔は正面とみなさずに除外します。
+// CalculateFaceSharpness・CalculateAllFacesSharpness・AssessFaceQuality（各Context版を含む）で有効で、
+// 全ての顔が除外された場合はErrNotFrontalを返します。
+func WithFrontalOnly() AnalysisOption {
+	return func(c *analysisConfig) {
+		c.frontalOnly = true
+	}
+}
+
+// analysis は解析呼び出しごとの設定を解決した結果です。
+type analysis struct {
+	metrics        []SharpnessMetric
+	scoreBasis     SharpnessMetric
+	eyeRegionScore bool
+	frontalOnly    bool
+
+	// normalizeSize は鮮明度計算の正規化後の大きさ（0の場合はWithSharpnessNormalizeSizeの値）
+	normalizeSize int
+
+	// region は目・口などの部位の計算かどうか（部位ではCPBDや元の解像度のノイズなど診断用の値を計算しない）
+	region bool
+}
+
+// newAnalysis はAnalysisOptionを適用し、指標名などを解決します。
+func (d *Detector) newAnalysis(opts []AnalysisOption) (*analysis, error) {
+	ac := analysisConfig{scoreBasis: d.cfg.scoreBasis}
+	for _, opt := range opts {
+		opt(&ac)
+	}
+
+	metrics, err := d.lookupMetrics(ac.metrics)
+	if err != nil {
+		return nil, err
+	}
+	basis, err := d.lookupMetrics([]string{ac.scoreBasis})
+	if err != nil {
+		return nil, err
+	}
+	return &analysis{
+		metrics:        metrics,
+		scoreBasis:     basis[0],
+		eyeRegionScore: ac.eyeRegionScore,
+		frontalOnly:    ac.frontalOnly,
+	}, nil
+}
@@ -0,0 +1,229 @@
+package facedetector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================================
+// EXIFの向き（Orientation）を適用したデコード
+// ============================================================================
+
+// Orientation はEXIFのOrientationタグの値（1〜8）です。
+// スマートフォンのJPEGは画素を横向きのまま保存し、表示時の回転をこのタグで指定します。
+type Orientation int
+
+const (
+	// OrientationNormal は回転・反転なし（EXIFが無い画像やJPEG以外の画像を含む）
+	OrientationNormal Orientation = 1
+	// OrientationFlipHorizontal は左右反転
+	OrientationFlipHorizontal Orientation = 2
+	// OrientationRotate180 は180度回転
+	OrientationRotate180 Orientation = 3
+	// OrientationFlipVertical は上下反転
+	OrientationFlipVertical Orientation = 4
+	// OrientationTranspose は左上から右下への対角線での反転
+	OrientationTranspose Orientation = 5
+	// OrientationRotate90 は時計回りに90度回転して表示する画像
+	OrientationRotate90 Orientation = 6
+	// OrientationTransverse は右上から左下への対角線での反転
+	OrientationTransverse Orientation = 7
+	// OrientationRotate270 は時計回りに270度（反時計回りに90度）回転して表示する画像
+	OrientationRotate270 Orientation = 8
+)
+
+// exifOrientationTag はEXIF（TIFFのIFD0）のOrientationタグの番号
+const exifOrientationTag = 0x0112
+
+// ReadOrientation は画像データのEXIFからOrientationを読み取ります。
+// JPEG以外の画像、EXIFやタグが無い画像、不正な値の場合はOrientationNormalを返します。
+func ReadOrientation(imageData []byte) Orientation {
+	if len(imageData) < 4 || imageData[0] != 0xFF || imageData[1] != 0xD8 {
+		return OrientationNormal
+	}
+
+	// SOIの後のマーカーを順に読み、APP1（Exif）を探す
+	pos := 2
+	for pos+4 <= len(imageData) {
+		if imageData[pos] != 0xFF {
+			return OrientationNormal
+		}
+		marker := imageData[pos+1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 || marker == 0xFF {
+			pos++
+			continue
+		}
+		// SOS以降は画像データのためEXIFは無い
+		if marker == 0xDA || marker == 0xD9 {
+			return OrientationNormal
+		}
+		size := int(binary.BigEndian.Uint16(imageData[pos+2:]))
+		if size < 2 || pos+2+size > len(imageData) {
+			return OrientationNormal
+		}
+		segment := imageData[pos+4 : pos+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + size
+	}
+	return OrientationNormal
+}
+
+// exifOrientation はTIFF形式のEXIFデータのIFD0からOrientationタグを読み取ります。
+func exifOrientation(tiff []byte) Orientation {
+	if len(tiff) < 8 {
+		return OrientationNormal
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return OrientationNormal
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return OrientationNormal
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		// 型はSHORTで、値はエントリの値フィールドの先頭2バイト
+		o := Orientation(order.Uint16(tiff[entry+8:]))
+		if o < OrientationNormal || o > OrientationRotate270 {
+			return OrientationNormal
+		}
+		return o
+	}
+	return OrientationNormal
+}
+
+// transformed は画像の回転・反転が必要な向きかを返します。
+func (o Orientation) transformed() bool {
+	return o > OrientationNormal && o <= OrientationRotate270
+}
+
+// swapsAxes は向きの補正で幅と高さが入れ替わるかを返します。
+func (o Orientation) swapsAxes() bool {
+	return o >= OrientationTranspose && o <= OrientationRotate270
+}
+
+// orientImage は画像にEXIFの向きを適用し、表示される向きの画像を返します。
+// OrientationNormalの場合は元の画像をそのまま返します。
+func orientImage(img image.Image, o Orientation) image.Image {
+	if !o.transformed() {
+		return img
+	}
+
+	src := toRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if o.swapsAxes() {
+		dw, dh = h, w
+	}
+
+	// 出力の (x, y) に対応する元画像の座標は sx = ax·x + bx·y + cx、sy = ay·x + by·y + cy
+	var ax, bx, cx, ay, by, cy int
+	switch o {
+	case OrientationFlipHorizontal:
+		ax, cx, by = -1, w-1, 1
+	case OrientationRotate180:
+		ax, cx, by, cy = -1, w-1, -1, h-1
+	case OrientationFlipVertical:
+		ax, by, cy = 1, -1, h-1
+	case OrientationTranspose:
+		bx, ay = 1, 1
+	case OrientationRotate90:
+		bx, ay, cy = 1, -1, h-1
+	case OrientationTransverse:
+		bx, cx, ay, cy = -1, w-1, -1, h-1
+	case OrientationRotate270:
+		bx, cx, ay = -1, w-1, 1
+	}
+
+	// 画素を4バイト単位で直接コピーする
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		row := dst.Pix[y*dst.Stride : y*dst.Stride+dw*4]
+		for x := 0; x < dw; x++ {
+			sx, sy := ax*x+bx*y+cx, ay*x+by*y+cy
+			i := sy*src.Stride + sx*4
+			copy(row[x*4:x*4+4], src.Pix[i:i+4])
+		}
+	}
+	return dst
+}
+
+// toRGBA は画像を原点を左上とする*image.RGBAに変換します。既に同じ形式の場合はそのまま返します。
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// imageToMat は画像をOpenCVのBGRのMatに変換します。呼び出し側でClose()する必要があります。
+func imageToMat(img image.Image) (gocv.Mat, error) {
+	rgba := toRGBA(img)
+	w, h := rgba.Rect.Dx(), rgba.Rect.Dy()
+	data := make([]byte, 0, w*h*3)
+	for y := 0; y < h; y++ {
+		row := rgba.Pix[y*rgba.Stride : y*rgba.Stride+w*4]
+		for x := 0; x < len(row); x += 4 {
+			data = append(data, row[x+2], row[x+1], row[x])
+		}
+	}
+	return gocv.NewMatFromBytes(h, w, gocv.MatTypeCV8UC3, data)
+}
+
+// decodeImage は画像データをGo標準ライブラリでデコードし、EXIFの向きを適用します（顔検出を行わない解析用）。
+func decodeImage(imageData []byte) (image.Image, Orientation, error) {
+	if len(imageData) == 0 {
+		return nil, OrientationNormal, ErrEmptyImage
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, OrientationNormal, &DecodeError{Decoder: "image", Err: err}
+	}
+	o := ReadOrientation(imageData)
+	return orientImage(img, o), o, nil
+}
+
+// decodeOriented は画像データをGo標準ライブラリで一度だけデコードしてEXIFの向きを適用し、
+// 結果の描画・切り抜き・鮮明度の計算用の画像と、同じ画素から変換した検出用のMatを返します。
+// 両方の座標系は常に一致します。Matは呼び出し側でClose()する必要があります。
+func decodeOriented(imageData []byte) (image.Image, gocv.Mat, Orientation, error) {
+	img, o, err := decodeImage(imageData)
+	if err != nil {
+		return nil, gocv.Mat{}, o, err
+	}
+
+	mat, err := imageToMat(img)
+	if err != nil {
+		return nil, gocv.Mat{}, o, &DecodeError{Decoder: "opencv", Err: err}
+	}
+	if mat.Empty() {
+		mat.Close()
+		return nil, gocv.Mat{}, o, &DecodeError{Decoder: "opencv", Err: errors.New("変換結果が空です")}
+	}
+	return img, mat, o, nil
+}
@@ -0,0 +1,257 @@
+package facedetector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"strings"
+	"testing"
+)
+
+// withEXIFOrientation はJPEGのSOIの直後に、Orientationタグだけを持つEXIF（APP1）を挿入します。
+func withEXIFOrientation(t *testing.T, jpegData []byte, o Orientation, order binary.ByteOrder) []byte {
+	t.Helper()
+	if len(jpegData) < 2 || jpegData[0] != 0xFF || jpegData[1] != 0xD8 {
+		t.Fatal("Not a JPEG")
+	}
+
+	var tiff bytes.Buffer
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	binary.Write(&tiff, order, uint16(42))
+	binary.Write(&tiff, order, uint32(8))
+	binary.Write(&tiff, order, uint16(1))
+	binary.Write(&tiff, order, uint16(exifOrientationTag))
+	binary.Write(&tiff, order, uint16(3)) // SHORT
+	binary.Write(&tiff, order, uint32(1))
+	binary.Write(&tiff, order, uint16(o))
+	binary.Write(&tiff, order, uint16(0))
+	binary.Write(&tiff, order, uint32(0))
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(jpegData[2:])
+	return out.Bytes()
+}
+
+// encodeJPEG は画像をJPEGにエンコードします。
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadOrientation(t *testing.T) {
+	plain := encodeJPEG(t, image.NewGray(image.Rect(0, 0, 16, 8)))
+
+	for o := OrientationNormal; o <= OrientationRotate270; o++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			if got := ReadOrientation(withEXIFOrientation(t, plain, o, order)); got != o {
+				t.Errorf("ReadOrientation (%v) = %d, want %d", order, got, o)
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no exif", plain},
+		{"png", uniformPNG(t, 8, 8)},
+		{"invalid value", withEXIFOrientation(t, plain, 9, binary.BigEndian)},
+		{"truncated", withEXIFOrientation(t, plain, OrientationRotate90, binary.BigEndian)[:20]},
+		{"empty", nil},
+	}
+	for _, tt := range tests {
+		if got := ReadOrientation(tt.data); got != OrientationNormal {
+			t.Errorf("%s: ReadOrientation = %d, want %d", tt.name, got, OrientationNormal)
+		}
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	// 3x2の画像の左上を赤、右上を青にして、向きの適用後の位置を確認する
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	src.Set(0, 0, red)
+	src.Set(2, 0, blue)
+
+	tests := []struct {
+		o         Orientation
+		size      image.Point
+		red, blue image.Point
+	}{
+		{OrientationNormal, image.Pt(3, 2), image.Pt(0, 0), image.Pt(2, 0)},
+		{OrientationFlipHorizontal, image.Pt(3, 2), image.Pt(2, 0), image.Pt(0, 0)},
+		{OrientationRotate180, image.Pt(3, 2), image.Pt(2, 1), image.Pt(0, 1)},
+		{OrientationFlipVertical, image.Pt(3, 2), image.Pt(0, 1), image.Pt(2, 1)},
+		{OrientationTranspose, image.Pt(2, 3), image.Pt(0, 0), image.Pt(0, 2)},
+		{OrientationRotate90, image.Pt(2, 3), image.Pt(1, 0), image.Pt(1, 2)},
+		{OrientationTransverse, image.Pt(2, 3), image.Pt(1, 2), image.Pt(1, 0)},
+		{OrientationRotate270, image.Pt(2, 3), image.Pt(0, 2), image.Pt(0, 0)},
+	}
+	for _, tt := range tests {
+		got := orientImage(src, tt.o)
+		if size := got.Bounds().Size(); size != tt.size {
+			t.Errorf("orientation %d: size = %v, want %v", tt.o, size, tt.size)
+			continue
+		}
+		if c := color.RGBAModel.Convert(got.At(tt.red.X, tt.red.Y)); c != red {
+			t.Errorf("orientation %d: expected red at %v, got %v", tt.o, tt.red, c)
+		}
+		if c := color.RGBAModel.Convert(got.At(tt.blue.X, tt.blue.Y)); c != blue {
+			t.Errorf("orientation %d: expected blue at %v, got %v", tt.o, tt.blue, c)
+		}
+	}
+}
+
+func TestOrientImage_SubImage(t *testing.T) {
+	// 原点が左上でない画像でも、画像の範囲の左上を基準に向きを適用する
+	full := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	red := color.RGBA{255, 0, 0, 255}
+	full.Set(4, 2, red)
+	sub := full.SubImage(image.Rect(4, 2, 7, 4))
+
+	got := orientImage(sub, OrientationRotate90)
+	if size := got.Bounds().Size(); size != image.Pt(2, 3) {
+		t.Fatalf("size = %v, want 2x3", size)
+	}
+	if c := color.RGBAModel.Convert(got.At(1, 0)); c != red {
+		t.Errorf("expected red at (1, 0), got %v", c)
+	}
+}
+
+func TestImageToMat(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.RGBA{0, 0, 255, 255})
+
+	mat, err := imageToMat(img)
+	if err != nil {
+		t.Fatalf("imageToMat failed: %v", err)
+	}
+	defer mat.Close()
+	if mat.Rows() != 1 || mat.Cols() != 2 {
+		t.Fatalf("size = %dx%d, want 2x1", mat.Cols(), mat.Rows())
+	}
+	// OpenCVの画素の並びはBGR
+	if got, want := mat.ToBytes(), []byte{0, 0, 255, 255, 0, 0}; !bytes.Equal(got, want) {
+		t.Errorf("bytes = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeOriented(t *testing.T) {
+	data := withEXIFOrientation(t, encodeJPEG(t, image.NewGray(image.Rect(0, 0, 64, 32))), OrientationRotate270, binary.BigEndian)
+
+	img, mat, o, err := decodeOriented(data)
+	if err != nil {
+		t.Fatalf("decodeOriented failed: %v", err)
+	}
+	defer mat.Close()
+	if o != OrientationRotate270 {
+		t.Errorf("orientation = %d, want %d", o, OrientationRotate270)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(32, 64) || mat.Cols() != 32 || mat.Rows() != 64 {
+		t.Errorf("image %v and Mat %dx%d, want both 32x64 after rotation", size, mat.Cols(), mat.Rows())
+	}
+}
+
+func TestDecodeImage_Orientation(t *testing.T) {
+	data := withEXIFOrientation(t, encodeJPEG(t, image.NewGray(image.Rect(0, 0, 64, 32))), OrientationRotate90, binary.LittleEndian)
+
+	img, o, err := decodeImage(data)
+	if err != nil {
+		t.Fatalf("decodeImage failed: %v", err)
+	}
+	if o != OrientationRotate90 {
+		t.Errorf("orientation = %d, want %d", o, OrientationRotate90)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(32, 64) {
+		t.Errorf("size = %v, want 32x64 after rotation", size)
+	}
+}
+
+func TestCalculateSharpness_Orientation(t *testing.T) {
+	data := withEXIFOrientation(t, encodeJPEG(t, image.NewGray(image.Rect(0, 0, 64, 32))), OrientationRotate90, binary.LittleEndian)
+	d := New()
+
+	result, err := d.CalculateSharpness(data)
+	if err != nil {
+		t.Fatalf("CalculateSharpness failed: %v", err)
+	}
+	if result.Orientation != OrientationRotate90 || result.OriginalWidth != 32 || result.OriginalHeight != 64 {
+		t.Errorf("Orientation = %d, size = %dx%d, want %d and 32x64",
+			result.Orientation, result.OriginalWidth, result.OriginalHeight, OrientationRotate90)
+	}
+
+	heatmap, err := d.CalculateSharpnessHeatmap(data)
+	if err != nil {
+		t.Fatalf("CalculateSharpnessHeatmap failed: %v", err)
+	}
+	if heatmap.Orientation != OrientationRotate90 || heatmap.Width != 32 || heatmap.Height != 64 {
+		t.Errorf("Orientation = %d, size = %dx%d, want %d and 32x64",
+			heatmap.Orientation, heatmap.Width, heatmap.Height, OrientationRotate90)
+	}
+}
+
+func TestFaceSharpnessResult_OrientationJSON(t *testing.T) {
+	// 顔ごとの結果には向きを出力せず、FaceSharpnessResultの向きを1回だけ出力する
+	data, err := json.Marshal(FaceSharpness{})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(data), `"orientation"`) {
+		t.Errorf("Expected no orientation in a per-face result: %s", data)
+	}
+
+	data, err = json.Marshal(FaceSharpnessResult{Orientation: OrientationRotate270})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if n := strings.Count(string(data), `"orientation":8`); n != 1 {
+		t.Errorf("Expected orientation 8 once, got %d in %s", n, data)
+	}
+}
+
+func TestCalculateFaceSharpness_Orientation(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/face.jpg")
+	if err != nil {
+		t.Skipf("Test image not available: %v", err)
+	}
+	upright, err := CalculateFaceSharpness(imageData)
+	if err != nil {
+		t.Fatalf("CalculateFaceSharpness failed: %v", err)
+	}
+
+	// 画素を反時計回りに90度回転して保存し、表示時に時計回りに90度回転するEXIFを付けた
+	// スマートフォンのJPEGと同じ形式の画像では、顔の位置が元の画像と一致する
+	img, _, err := decodeImage(imageData)
+	if err != nil {
+		t.Fatalf("decodeImage failed: %v", err)
+	}
+	rotated := withEXIFOrientation(t, encodeJPEG(t, orientImage(img, OrientationRotate270)), OrientationRotate90, binary.BigEndian)
+
+	result, err := CalculateFaceSharpness(rotated)
+	if err != nil {
+		t.Fatalf("CalculateFaceSharpness on the rotated JPEG failed: %v", err)
+	}
+	if result.Orientation != OrientationRotate90 {
+		t.Errorf("Orientation = %d, want %d", result.Orientation, OrientationRotate90)
+	}
+	if iou := calculateIoU(result.Face.Rect, upright.Face.Rect); iou < 0.7 {
+		t.Errorf("Face %v does not match the upright face %v (IoU %.2f)", result.Face.Rect, upright.Face.Rect, iou)
+	}
+}
@@ -0,0 +1,117 @@
+package facedetector
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// ============================================================================
+// 頭部姿勢（ヨー・ピッチ・ロール）の推定
+// ============================================================================
+
+// poseModelPoints は頭部姿勢の推定に使用する特徴点の番号と、一般的な顔の3Dモデル上の座標です。
+// 座標系は鼻先を原点に、x: 画像上の右、y: 上、z: カメラ側です（単位は任意）。
+var poseModelPoints = []struct {
+	index   int
+	x, y, z float64
+}{
+	{30, 0, 0, 0},          // 鼻先
+	{8, 0, -330, -65},      // 顎先
+	{36, -225, 170, -135},  // 画像上の左目の外側の目尻
+	{45, 225, 170, -135},   // 画像上の右目の外側の目尻
+	{48, -150, -150, -125}, // 画像上の左の口角
+	{54, 150, -150, -125},  // 画像上の右の口角
+}
+
+// HeadPose は顔の向き（度）です。
+// 正面を向いている顔はいずれも0に近くなります。回転した画像から見つかった顔では、顔を正立させた画像での値です。
+type HeadPose struct {
+	// Yaw は左右の向き（正: 画像上の右を向いている）。
+	Yaw float64 `json:"yaw"`
+
+	// Pitch は上下の向き（正: 上を向いている）。
+	Pitch float64 `json:"pitch"`
+
+	// Roll は顔の傾き（正: 画像上で反時計回りに傾いている）。
+	Roll float64 `json:"roll"`
+
+	// Frontal はヨー・ピッチ・ロールがすべて許容範囲（WithPoseTolerance）内かどうか。
+	Frontal bool `json:"frontal"`
+}
+
+// estimateHeadPose は特徴点から頭部姿勢を推定します。size は特徴点を検出した画像の大きさです。
+// 特徴点が無い場合はnilを返します。
+func (d *Detector) estimateHeadPose(landmarks Landmarks, size image.Point) *HeadPose {
+	yaw, pitch, roll, ok := solveHeadPose(landmarks, size)
+	if !ok {
+		return nil
+	}
+	round := func(v float64) float64 { return math.Round(v*10) / 10 }
+	return &HeadPose{
+		Yaw:   round(yaw),
+		Pitch: round(pitch),
+		Roll:  round(roll),
+		Frontal: math.Abs(yaw) <= d.cfg.poseYawTolerance &&
+			math.Abs(pitch) <= d.cfg.posePitchTolerance &&
+			math.Abs(roll) <= d.cfg.poseRollTolerance,
+	}
+}
+
+// faceHeadPose は大きさ size の画像で見つかった顔の頭部姿勢を推定します。
+// 回転した画像から見つかった顔（Face.Rotationが0以外）は、画像をRotationだけ回転して顔が正立した状態で推定します。
+func (d *Detector) faceHeadPose(face Face, size image.Point) *HeadPose {
+	if degrees := face.Rotation; degrees != 0 {
+		face = rotateFace(face, degrees, size.X, size.Y)
+		size = rotateSize(size, degrees)
+	}
+	return d.estimateHeadPose(face.Landmarks, size)
+}
+
+// isFrontal は顔が正面を向いているかを返します。特徴点が無く姿勢を推定できない顔は正面とみなしません。
+func (d *Detector) isFrontal(face Face, size image.Point) bool {
+	pose := d.faceHeadPose(face, size)
+	return pose != nil && pose.Frontal
+}
+
+// checkFrontalOnly はWithFrontalOnlyを指定した場合に、頭部姿勢の推定に必要な特徴点のモデルが利用できるかを確認します。
+// モデルが無いと全ての顔の姿勢を推定できないため、ErrModelUnavailableを返します。
+func (d *Detector) checkFrontalOnly(an *analysis) error {
+	if an.frontalOnly && (d.landmarker == nil || !d.landmarker.Available()) {
+		return fmt.Errorf("%w: WithFrontalOnlyには顔の特徴点のモデル（%s）が必要です", ErrModelUnavailable, landmarkModelFileName)
+	}
+	return nil
+}
+
+// solveHeadPose は特徴点と3Dモデルの対応から cv::solvePnP で回転を求め、ヨー・ピッチ・ロール（度）に分解します。
+// カメラは焦点距離を画像の幅、光学中心を画像の中心とした歪みの無いピンホールカメラとみなします。
+func solveHeadPose(landmarks Landmarks, size image.Point) (yaw, pitch, roll float64, ok bool) {
+	if !landmarks.Valid() || size.X <= 0 || size.Y <= 0 {
+		return 0, 0, 0, false
+	}
+
+	// 3Dモデルの座標系（y: 上、z: カメラ側）をOpenCVのカメラ座標系（y: 下、z: 奥）に合わせる
+	var model [][3]float64
+	var points [][2]float64
+	for _, p := range poseModelPoints {
+		lp := landmarks[p.index]
+		model = append(model, [3]float64{p.x, -p.y, -p.z})
+		points = append(points, [2]float64{lp.X, lp.Y})
+	}
+	rot, ok := solvePnP(model, points, float64(size.X), float64(size.X)/2, float64(size.Y)/2)
+	if !ok {
+		return 0, 0, 0, false
+	}
+
+	// 回転行列をモデルの座標系に戻す（F·R·F、F = diag(1, -1, -1)）
+	r1 := [3]float64{rot[0][0], -rot[0][1], -rot[0][2]}
+	r2 := [3]float64{-rot[1][0], rot[1][1], rot[1][2]}
+	r3 := [3]float64{-rot[2][0], rot[2][1], rot[2][2]}
+
+	// R = Rz(roll)·Ry(yaw)·Rx(-pitch) として分解（Rxの正の回転は下向きのため符号を反転）
+	toDeg := 180 / math.Pi
+	yaw = math.Asin(math.Max(-1, math.Min(1, -r3[0]))) * toDeg
+	pitch = -math.Atan2(r3[1], r3[2]) * toDeg
+	roll = math.Atan2(r2[0], r1[0]) * toDeg
+	return yaw, pitch, roll, true
+}
@@ -0,0 +1,133 @@
+package facedetector
+
+import (
+	"errors"
+	"image"
+	"math"
+	"testing"
+)
+
+// posedLandmarks は大きさ size の画像にある顔矩形 rect の正面顔の特徴点のうち、頭部姿勢の推定に使う6点を
+// 3Dモデルを指定の向き（度）に回転して透視投影した位置に置き換えた特徴点を返します。
+// カメラは焦点距離を画像の幅、光学中心を画像の中心としたピンホールカメラで、
+// 顔の3Dモデルの幅（約600）が矩形の幅に写る距離に顔を置きます。
+func posedLandmarks(rect image.Rectangle, size image.Point, yaw, pitch, roll float64) Landmarks {
+	lm := syntheticLandmarks(rect)
+	toRad := math.Pi / 180
+	sy, cy := math.Sincos(yaw * toRad)
+	sp, cp := math.Sincos(-pitch * toRad)
+	sr, cr := math.Sincos(roll * toRad)
+
+	f := float64(size.X)
+	ox, oy := float64(size.X)/2, float64(size.Y)/2
+	distance := f * 600 / float64(rect.Dx())
+	center := rect.Min.Add(rect.Max).Div(2)
+	// 鼻先が矩形の中心に写るよう、顔をカメラ座標系（y: 上、z: カメラ側）で平行移動する
+	tx := (float64(center.X) - ox) * distance / f
+	ty := (oy - float64(center.Y)) * distance / f
+	for _, p := range poseModelPoints {
+		// Rx(-pitch) → Ry(yaw) → Rz(roll) の順に回転
+		x, y, z := p.x, p.y*cp-p.z*sp, p.y*sp+p.z*cp
+		x, z = x*cy+z*sy, -x*sy+z*cy
+		x, y = x*cr-y*sr, x*sr+y*cr
+		depth := distance - z
+		lm[p.index] = LandmarkPoint{X: ox + f*(x+tx)/depth, Y: oy - f*(y+ty)/depth}
+	}
+	return lm
+}
+
+func TestSolveHeadPose(t *testing.T) {
+	size := image.Pt(400, 300)
+	tests := []struct {
+		name             string
+		rect             image.Rectangle
+		yaw, pitch, roll float64
+	}{
+		{"frontal", image.Rect(100, 50, 300, 250), 0, 0, 0},
+		{"yaw right", image.Rect(100, 50, 300, 250), 30, 0, 0},
+		{"yaw left", image.Rect(100, 50, 300, 250), -30, 0, 0},
+		{"pitch up", image.Rect(100, 50, 300, 250), 0, 20, 0},
+		{"pitch down", image.Rect(100, 50, 300, 250), 0, -20, 0},
+		{"roll", image.Rect(100, 50, 300, 250), 0, 0, 15},
+		{"combined", image.Rect(100, 50, 300, 250), 25, -10, 8},
+		// 画像の端にある大きな顔は透視投影の歪みが大きく、正射影の近似では向きを誤る
+		{"off-center frontal", image.Rect(0, 0, 240, 240), 0, 0, 0},
+		{"off-center combined", image.Rect(160, 60, 400, 300), -20, 10, -5},
+	}
+	for _, tt := range tests {
+		yaw, pitch, roll, ok := solveHeadPose(posedLandmarks(tt.rect, size, tt.yaw, tt.pitch, tt.roll), size)
+		if !ok {
+			t.Errorf("%s: solveHeadPose failed", tt.name)
+			continue
+		}
+		if math.Abs(yaw-tt.yaw) > 0.5 || math.Abs(pitch-tt.pitch) > 0.5 || math.Abs(roll-tt.roll) > 0.5 {
+			t.Errorf("%s: solveHeadPose = (%.1f, %.1f, %.1f), want (%.1f, %.1f, %.1f)", tt.name, yaw, pitch, roll, tt.yaw, tt.pitch, tt.roll)
+		}
+	}
+
+	if _, _, _, ok := solveHeadPose(nil, size); ok {
+		t.Error("Expected failure without landmarks")
+	}
+}
+
+func TestEstimateHeadPose(t *testing.T) {
+	rect := image.Rect(0, 0, 200, 200)
+	size := rect.Size()
+	d := New()
+
+	// 平均的な配置の正面顔
+	pose := d.estimateHeadPose(syntheticLandmarks(rect), size)
+	if pose == nil || !pose.Frontal {
+		t.Fatalf("Expected a frontal pose for synthetic landmarks, got %+v", pose)
+	}
+	t.Logf("synthetic pose: %+v", *pose)
+
+	turned := d.estimateHeadPose(posedLandmarks(rect, size, 25, 0, 0), size)
+	if turned == nil || turned.Frontal || turned.Yaw < 24 || turned.Yaw > 26 {
+		t.Errorf("Expected a non-frontal pose with yaw 25, got %+v", turned)
+	}
+	if p := New(WithPoseTolerance(30, 15, 10)).estimateHeadPose(posedLandmarks(rect, size, 25, 0, 0), size); p == nil || !p.Frontal {
+		t.Errorf("Expected yaw 25 to be frontal with a 30 degree tolerance, got %+v", p)
+	}
+
+	if pose := d.estimateHeadPose(nil, size); pose != nil {
+		t.Errorf("Expected nil pose without landmarks, got %+v", pose)
+	}
+}
+
+func TestIsFrontal(t *testing.T) {
+	rect := image.Rect(0, 0, 200, 200)
+	size := rect.Size()
+	d := New()
+
+	tests := []struct {
+		name string
+		face Face
+		want bool
+	}{
+		{"frontal", Face{Rect: rect, Landmarks: posedLandmarks(rect, size, 5, 0, 0)}, true},
+		{"turned", Face{Rect: rect, Landmarks: posedLandmarks(rect, size, -40, 0, 0)}, false},
+		{"tilted", Face{Rect: rect, Landmarks: posedLandmarks(rect, size, 0, 0, 20)}, false},
+		{"no landmarks", Face{Rect: rect, Source: SourceCascade}, false},
+		{"profile cascade without landmarks", Face{Rect: rect, Source: SourceProfileCascade}, false},
+		{"profile cascade with frontal landmarks", Face{Rect: rect, Source: SourceProfileCascade, Landmarks: posedLandmarks(rect, size, 5, 0, 0)}, true},
+	}
+	for _, tt := range tests {
+		if got := d.isFrontal(tt.face, size); got != tt.want {
+			t.Errorf("%s: isFrontal = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFrontalOnly(t *testing.T) {
+	frontalOnly := &analysis{frontalOnly: true}
+	if err := New(WithLandmarks(false)).checkFrontalOnly(frontalOnly); !errors.Is(err, ErrModelUnavailable) {
+		t.Errorf("checkFrontalOnly without landmarks = %v, want ErrModelUnavailable", err)
+	}
+	if err := New(WithLandmarkModel("testdata/missing.yaml")).checkFrontalOnly(frontalOnly); !errors.Is(err, ErrModelUnavailable) {
+		t.Errorf("checkFrontalOnly with a missing model = %v, want ErrModelUnavailable", err)
+	}
+	if err := New(WithLandmarks(false)).checkFrontalOnly(&analysis{}); err != nil {
+		t.Errorf("checkFrontalOnly without WithFrontalOnly = %v, want nil", err)
+	}
+}
@@ -0,0 +1,311 @@
+package facedetector
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// ============================================================================
+// 顔画像の総合品質スコア
+// ============================================================================
+
+// 品質スコアの各要素の重み（合計1.0）。算出できない要素は除外し、残りの重みで正規化します。
+// ただし姿勢は推定できなくても除外せず、控えめなスコアを付けます（qualityPoseUnavailableDeviation）。
+const (
+	qualityWeightSharpness        = 0.30
+	qualityWeightExposure         = 0.15
+	qualityWeightPose             = 0.15
+	qualityWeightFaceSize         = 0.10
+	qualityWeightInterEyeDistance = 0.10
+	qualityWeightOcclusion        = 0.10
+	qualityWeightLighting         = 0.10
+)
+
+const (
+	// qualityFaceSizeGood は顔のサイズのスコアを100点とする顔矩形の短辺（ピクセル）
+	qualityFaceSizeGood = 200.0
+
+	// 目の間隔のスコアを0点・100点とする両目の中心間の距離（ピクセル）。
+	// 100点の値はICAOの証明写真の推奨（90ピクセル以上）に合わせています
+	qualityInterEyeMin  = 30.0
+	qualityInterEyeGood = 90.0
+
+	// 姿勢を推定できない顔に仮定する正面からのずれ（許容範囲に対する割合）。
+	// 横顔のCascadeで見つかった顔は許容範囲の2倍（0点）、それ以外は許容範囲の端（50点）とし、
+	// 正面を向いているか確認できない顔が正面の顔と同じスコアにならないようにします
+	qualityPoseUnavailableDeviation        = 1.0
+	qualityProfilePoseUnavailableDeviation = 2.0
+)
+
+// QualityComponent は品質スコアの1つの要素です。
+type QualityComponent struct {
+	// Score は要素のスコア（0〜100点）。
+	Score float64 `json:"score"`
+
+	// Weight は総合スコアにおける重み（算出できない要素を除いて正規化した値）。
+	Weight float64 `json:"weight"`
+
+	// Value はスコアの元になった測定値（要素ごとの単位はQualityBreakdownを参照）。
+	Value float64 `json:"value"`
+}
+
+// QualityBreakdown は品質スコアの要素ごとの内訳です。算出できない要素はnilで、JSONでは省略されます。
+type QualityBreakdown struct {
+	// Sharpness はエッジ減衰率による鮮明度（Value: エッジ減衰率）。
+	Sharpness *QualityComponent `json:"sharpness,omitempty"`
+
+	// Exposure は顔の露出（Value: 顔の中心領域の平均輝度）。
+	Exposure *QualityComponent `json:"exposure,omitempty"`
+
+	// Pose は正面からのずれ（Value: ヨー・ピッチ・ロールの許容範囲に対する割合の最大値）。
+	// 特徴点が無く姿勢を推定できない場合は仮定したずれによるスコアで、FaceQuality.PoseUnavailableがtrueになります。
+	Pose *QualityComponent `json:"pose,omitempty"`
+
+	// FaceSize は顔の大きさ（Value: 顔矩形の短辺のピクセル数）。
+	FaceSize *QualityComponent `json:"face_size,omitempty"`
+
+	// InterEyeDistance は両目の間隔（Value: 両目の中心間の距離のピクセル数）。
+	// 両目の位置を特徴点か目のHaar Cascadeで求められなかった場合はnil。
+	InterEyeDistance *QualityComponent `json:"inter_eye_distance,omitempty"`
+
+	// Occlusion は遮蔽（Value: 隠れていない部位の割合）。
+	Occlusion *QualityComponent `json:"occlusion,omitempty"`
+
+	// Lighting は照明の均一性（Value: lighting_uniformity）。
+	Lighting *QualityComponent `json:"lighting,omitempty"`
+}
+
+// FaceQuality は1つの顔の総合品質スコアと内訳です。
+type FaceQuality struct {
+	// Index はDetectFacesの結果における顔の位置（0始まり）。
+	Index int `json:"index"`
+
+	// Face は検出された顔の矩形・信頼度・検出器の種類。
+	Face Face `json:"face"`
+
+	// QualityScore は要素ごとのスコアの重み付き平均（0〜100点）。
+	QualityScore float64 `json:"quality_score"`
+
+	// Components は要素ごとのスコアの内訳。
+	Components QualityBreakdown `json:"components"`
+
+	// PoseUnavailable は頭部姿勢を推定できず、姿勢のスコアが仮定したずれによる値であることを示します。
+	PoseUnavailable bool `json:"pose_unavailable,omitempty"`
+}
+
+// FaceQualityResult はAssessFaceQualityの結果です。
+// 最も品質スコアの高い顔の結果と、検出された顔の数を含みます。
+type FaceQualityResult struct {
+	FaceQuality
+
+	// FaceCount は画像内で検出された顔の数。
+	FaceCount int `json:"face_count"`
+
+	// Orientation は解析前に適用したEXIFの向き（1は回転なし）。顔の座標は適用後の画像の座標系です。
+	Orientation Orientation `json:"orientation"`
+}
+
+// AssessFaceQuality は画像内の顔の鮮明度・露出・姿勢・大きさ・目の間隔・遮蔽・照明を
+// 1つの品質スコア（0〜100点）にまとめ、最も品質の高い顔の結果を返します。
+// 本人確認の撮影で「鮮明さ」だけでなく顔画像として使えるかを判定するために使用します。
+func (d *Detector) AssessFaceQuality(imageData []byte, opts ...AnalysisOption) (FaceQualityResult, error) {
+	return d.AssessFaceQualityContext(context.Background(), imageData, opts...)
+}
+
+// AssessFaceQualityContext はctxのキャンセルに対応したAssessFaceQualityです。
+func (d *Detector) AssessFaceQualityContext(ctx context.Context, imageData []byte, opts ...AnalysisOption) (FaceQualityResult, error) {
+	an, err := d.newAnalysis(opts)
+	if err != nil {
+		return FaceQualityResult{}, err
+	}
+	if err := d.checkFrontalOnly(an); err != nil {
+		return FaceQualityResult{}, err
+	}
+
+	img, faces, orientation, err := d.detectFaces(ctx, imageData)
+	if err != nil {
+		return FaceQualityResult{}, err
+	}
+
+	if len(faces) == 0 {
+		return FaceQualityResult{}, ErrNoFace
+	}
+
+	gray := convertToGrayscale(img)
+
+	var best FaceQuality
+	found := false
+	for i, face := range faces {
+		if err := ctx.Err(); err != nil {
+			return FaceQualityResult{}, err
+		}
+		if an.frontalOnly && !d.isFrontal(face, img.Bounds().Size()) {
+			continue
+		}
+
+		frame := uprightFaceFrame(img, gray, face)
+		fs := d.analyzeFaceSharpness(frame, i, an)
+		fs.Lighting = d.analyzeLighting(frame.gray, frame.face.Rect)
+		q := d.assessQuality(fs)
+		if !found || q.QualityScore > best.QualityScore {
+			best, found = q, true
+		}
+	}
+
+	if !found {
+		return FaceQualityResult{}, ErrNotFrontal
+	}
+	return FaceQualityResult{FaceQuality: best, FaceCount: len(faces), Orientation: orientation}, nil
+}
+
+// assessQuality は1つの顔の分析結果から要素ごとのスコアと総合品質スコアを計算します。
+func (d *Detector) assessQuality(fs FaceSharpness) FaceQuality {
+	var b QualityBreakdown
+
+	// 鮮明度: score_basisによらずエッジ減衰率のスコア
+	b.Sharpness = &QualityComponent{
+		Score: decayRatioToScore(fs.EdgeDecayRatio, d.cfg.sigmoidMidpoint, d.cfg.sigmoidSteepness),
+		Value: fs.EdgeDecayRatio,
+	}
+
+	// 露出: 平均輝度が暗い・明るい閾値の間なら100点、範囲外は0・255に向けて直線的に減点し、
+	// 白飛び・黒つぶれの割合が上限の2倍に達すると0点
+	brightness := fs.Exposure.MeanBrightness
+	exposureScore := 1.0
+	if brightness < d.cfg.darkThreshold {
+		exposureScore = brightness / d.cfg.darkThreshold
+	} else if brightness > d.cfg.brightThreshold {
+		exposureScore = (255 - brightness) / (255 - d.cfg.brightThreshold)
+	}
+	clip := fs.Exposure.ShadowClipPercent + fs.Exposure.HighlightClipPercent
+	exposureScore *= 1 - math.Min(1, clip/(2*d.cfg.exposureClipPercent))
+	b.Exposure = &QualityComponent{Score: 100 * exposureScore, Value: brightness}
+
+	// 姿勢: 許容範囲の端で50点、許容範囲の2倍で0点。
+	// 推定できない場合は重みを他の要素に振り分けず、横顔なら0点、それ以外は50点とする
+	deviation := qualityPoseUnavailableDeviation
+	if fs.Face.Source == SourceProfileCascade {
+		deviation = qualityProfilePoseUnavailableDeviation
+	}
+	if fs.Pose != nil {
+		deviation = math.Max(math.Abs(fs.Pose.Yaw)/d.cfg.poseYawTolerance,
+			math.Max(math.Abs(fs.Pose.Pitch)/d.cfg.posePitchTolerance, math.Abs(fs.Pose.Roll)/d.cfg.poseRollTolerance))
+	}
+	b.Pose = &QualityComponent{Score: 100 * (1 - math.Min(1, deviation/2)), Value: deviation}
+
+	// 顔の大きさ: 検出の最小サイズで0点、qualityFaceSizeGood以上で100点
+	size := float64(fs.Face.Rect.Dx())
+	if h := float64(fs.Face.Rect.Dy()); h < size {
+		size = h
+	}
+	b.FaceSize = &QualityComponent{Score: linearScore(size, float64(d.cfg.minFaceSize), qualityFaceSizeGood), Value: size}
+
+	// 目の間隔: 部位の鮮明度と同じ方法で求めた両目の矩形の中心間の距離。
+	// 顔矩形からの推定位置は顔の大きさに比例するだけで顔の大きさの要素と重複するため使わない
+	if measuredEye(fs.Regions.LeftEye) && measuredEye(fs.Regions.RightEye) {
+		distance := rectCenterDistance(fs.Regions.LeftEye.BoundingBox.rect(), fs.Regions.RightEye.BoundingBox.rect())
+		b.InterEyeDistance = &QualityComponent{Score: linearScore(distance, qualityInterEyeMin, qualityInterEyeGood), Value: distance}
+	}
+
+	// 遮蔽: 隠れていない部位の割合（彩度が低く判定できない場合は省略）
+	zones, visible := 0, 0
+	if oz := fs.Occlusion.Zones; oz != nil {
+		for _, z := range []*ZoneOcclusion{oz.LeftEye, oz.RightEye, oz.Nose, oz.Mouth, oz.LeftCheek, oz.RightCheek} {
+			if z == nil {
+				continue
+			}
+			zones++
+			if !z.Occluded {
+				visible++
+			}
+		}
+	}
+	if zones > 0 {
+		ratio := float64(visible) / float64(zones)
+		b.Occlusion = &QualityComponent{Score: 100 * ratio, Value: ratio}
+	}
+
+	// 照明: 照明の均一性スコアをそのまま使用
+	b.Lighting = &QualityComponent{Score: fs.Lighting.Uniformity, Value: fs.Lighting.Uniformity}
+
+	return FaceQuality{
+		Index:           fs.Index,
+		Face:            fs.Face,
+		QualityScore:    combineQuality(&b),
+		Components:      b,
+		PoseUnavailable: fs.Pose == nil,
+	}
+}
+
+// combineQuality は算出できた要素の重みを合計1に正規化して各要素に設定し、重み付き平均を返します。
+// スコアと測定値は表示用に丸めます。
+func combineQuality(b *QualityBreakdown) float64 {
+	components := []struct {
+		c      *QualityComponent
+		weight float64
+	}{
+		{b.Sharpness, qualityWeightSharpness},
+		{b.Exposure, qualityWeightExposure},
+		{b.Pose, qualityWeightPose},
+		{b.FaceSize, qualityWeightFaceSize},
+		{b.InterEyeDistance, qualityWeightInterEyeDistance},
+		{b.Occlusion, qualityWeightOcclusion},
+		{b.Lighting, qualityWeightLighting},
+	}
+
+	total := 0.0
+	for _, c := range components {
+		if c.c != nil {
+			total += c.weight
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+
+	score := 0.0
+	for _, c := range components {
+		if c.c == nil {
+			continue
+		}
+		c.c.Score = math.Round(math.Max(0, math.Min(100, c.c.Score))*10) / 10
+		c.c.Weight = math.Round(c.weight/total*1000) / 1000
+		c.c.Value = math.Round(c.c.Value*1000) / 1000
+		score += c.c.Score * c.weight / total
+	}
+	return math.Round(score*10) / 10
+}
+
+// linearScore は値がlow以下で0点、high以上で100点、その間は直線的に補間したスコアを返します。
+func linearScore(value, low, high float64) float64 {
+	if high <= low {
+		if value >= high {
+			return 100
+		}
+		return 0
+	}
+	return 100 * math.Max(0, math.Min(1, (value-low)/(high-low)))
+}
+
+// measuredEye は目の矩形が特徴点か目のHaar Cascadeで求めたものかを返します。
+func measuredEye(r *RegionSharpness) bool {
+	return r != nil && (r.Source == RegionFromLandmarks || r.Source == RegionFromCascade)
+}
+
+// rectCenterDistance は2つの矩形の中心間の距離を返します。
+func rectCenterDistance(a, b image.Rectangle) float64 {
+	ax, ay := float64(a.Min.X+a.Max.X)/2, float64(a.Min.Y+a.Max.Y)/2
+	bx, by := float64(b.Min.X+b.Max.X)/2, float64(b.Min.Y+b.Max.Y)/2
+	return math.Hypot(ax-bx, ay-by)
+}
+
+// AssessFaceQuality はデフォルト設定のDetectorで顔画像の総合品質スコアを計算します。
+func AssessFaceQuality(imageData []byte, opts ...AnalysisOption) (FaceQualityResult, error) {
+	return getDefaultDetector().AssessFaceQuality(imageData, opts...)
+}
+
+// AssessFaceQualityContext はctxのキャンセルに対応したAssessFaceQualityです。
+func AssessFaceQualityContext(ctx context.Context, imageData []byte, opts ...AnalysisOption) (FaceQualityResult, error) {
+	return getDefaultDetector().AssessFaceQualityContext(ctx, imageData, opts...)
+}
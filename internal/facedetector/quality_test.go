@@ -0,0 +1,181 @@
+package facedetector
+
+import (
+	"errors"
+	"image"
+	"math"
+	"os"
+	"testing"
+)
+
+// goodFaceSharpness は全ての要素で満点になる顔の分析結果を返します。
+func goodFaceSharpness() FaceSharpness {
+	zone := &ZoneOcclusion{SkinRatio: 0.9}
+	fs := FaceSharpness{
+		Face:      Face{Rect: image.Rect(0, 0, 240, 240)},
+		Pose:      &HeadPose{Frontal: true},
+		Lighting:  Lighting{Uniformity: 100},
+		Occlusion: Occlusion{EyesVisible: true, MouthVisible: true, Zones: &OcclusionZones{LeftEye: zone, RightEye: zone, Mouth: zone}},
+		Regions: FaceRegions{
+			LeftEye:  &RegionSharpness{BoundingBox: newBoundingBox(image.Rect(40, 80, 90, 110)), Source: RegionFromLandmarks},
+			RightEye: &RegionSharpness{BoundingBox: newBoundingBox(image.Rect(150, 80, 200, 110)), Source: RegionFromLandmarks},
+		},
+	}
+	fs.EdgeDecayRatio = 1.0
+	fs.Exposure = Exposure{MeanBrightness: 128}
+	return fs
+}
+
+func TestAssessQuality(t *testing.T) {
+	d := New()
+
+	q := d.assessQuality(goodFaceSharpness())
+	if q.QualityScore < 99 {
+		t.Errorf("QualityScore = %.1f, want about 100 for a good face", q.QualityScore)
+	}
+	if c := q.Components.InterEyeDistance; c == nil || c.Value != 110 || c.Score != 100 {
+		t.Errorf("InterEyeDistance = %+v, want value 110 and score 100", c)
+	}
+
+	// 要素ごとに1つずつ悪くすると、その要素のスコアだけが下がる
+	tests := []struct {
+		name      string
+		modify    func(fs *FaceSharpness)
+		component func(b QualityBreakdown) *QualityComponent
+		want      float64
+	}{
+		{"dark", func(fs *FaceSharpness) { fs.Exposure.MeanBrightness = 40 },
+			func(b QualityBreakdown) *QualityComponent { return b.Exposure }, 50},
+		{"clipped", func(fs *FaceSharpness) { fs.Exposure.HighlightClipPercent = 5 },
+			func(b QualityBreakdown) *QualityComponent { return b.Exposure }, 50},
+		{"turned", func(fs *FaceSharpness) { fs.Pose = &HeadPose{Yaw: 15} },
+			func(b QualityBreakdown) *QualityComponent { return b.Pose }, 50},
+		{"small", func(fs *FaceSharpness) { fs.Face.Rect = image.Rect(0, 0, 110, 300) },
+			func(b QualityBreakdown) *QualityComponent { return b.FaceSize }, 50},
+		{"occluded", func(fs *FaceSharpness) { fs.Occlusion.Zones.Mouth = &ZoneOcclusion{Occluded: true} },
+			func(b QualityBreakdown) *QualityComponent { return b.Occlusion }, 66.7},
+		{"uneven", func(fs *FaceSharpness) { fs.Lighting.Uniformity = 40 },
+			func(b QualityBreakdown) *QualityComponent { return b.Lighting }, 40},
+	}
+	for _, tt := range tests {
+		fs := goodFaceSharpness()
+		tt.modify(&fs)
+		q := d.assessQuality(fs)
+		if c := tt.component(q.Components); c == nil || c.Score != tt.want {
+			t.Errorf("%s: component = %+v, want score %.1f", tt.name, c, tt.want)
+		}
+		if q.QualityScore >= 99 {
+			t.Errorf("%s: QualityScore = %.1f, want it to drop", tt.name, q.QualityScore)
+		}
+	}
+}
+
+func TestAssessQuality_MissingComponents(t *testing.T) {
+	fs := goodFaceSharpness()
+	fs.Pose = nil
+	fs.Regions = FaceRegions{}
+	fs.Lighting.Uniformity = 0
+
+	q := New().assessQuality(fs)
+	if q.Components.InterEyeDistance != nil {
+		t.Fatalf("Expected inter-eye distance to be omitted, got %+v", q.Components)
+	}
+
+	// 姿勢を推定できない顔は除外せず、控えめなスコアで残りの要素と同じ重みを持つ
+	if c := q.Components.Pose; c == nil || c.Score != 50 || !q.PoseUnavailable {
+		t.Errorf("Pose = %+v (unavailable=%v), want score 50 flagged as unavailable", c, q.PoseUnavailable)
+	}
+	noPose := goodFaceSharpness()
+	noPose.Pose = nil
+	if score := New().assessQuality(noPose).QualityScore; score > 93 {
+		t.Errorf("QualityScore = %.1f without a pose, want about 92.5 (half of the pose weight lost)", score)
+	}
+	profile := fs
+	profile.Face.Source = SourceProfileCascade
+	if c := New().assessQuality(profile).Components.Pose; c == nil || c.Score != 0 {
+		t.Errorf("Pose = %+v, want score 0 for a profile cascade face without a pose", c)
+	}
+	if New().assessQuality(goodFaceSharpness()).PoseUnavailable {
+		t.Error("Expected PoseUnavailable to be false when the pose was estimated")
+	}
+
+	// 顔矩形から推定した目の位置では目の間隔を算出しない
+	estimated := goodFaceSharpness()
+	estimated.Regions.LeftEye.Source = RegionEstimated
+	if c := New().assessQuality(estimated).Components.InterEyeDistance; c != nil {
+		t.Errorf("Expected inter-eye distance to be omitted for estimated eye regions, got %+v", c)
+	}
+	undetermined := goodFaceSharpness()
+	undetermined.Occlusion = Occlusion{}
+	if c := New().assessQuality(undetermined).Components.Occlusion; c != nil {
+		t.Errorf("Expected occlusion to be omitted when it could not be determined, got %+v", c)
+	}
+	cascade := goodFaceSharpness()
+	cascade.Regions.LeftEye.Source = RegionFromCascade
+	cascade.Regions.RightEye.Source = RegionFromCascade
+	if c := New().assessQuality(cascade).Components.InterEyeDistance; c == nil {
+		t.Error("Expected inter-eye distance for cascade eye regions")
+	}
+
+	// 残りの要素の重みの合計が1になり、総合スコアはその重みによる平均
+	b := q.Components
+	weights, want := 0.0, 0.0
+	for _, c := range []*QualityComponent{b.Sharpness, b.Exposure, b.Pose, b.FaceSize, b.Occlusion, b.Lighting} {
+		weights += c.Weight
+		want += c.Score * c.Weight
+	}
+	if math.Abs(weights-1) > 0.01 {
+		t.Errorf("Sum of weights = %.3f, want 1", weights)
+	}
+	if b.Lighting.Score != 0 || b.Lighting.Weight < 0.11 || b.Lighting.Weight > 0.112 {
+		t.Errorf("Lighting = %+v, want score 0 with weight 0.1 / 0.9", b.Lighting)
+	}
+	if math.Abs(q.QualityScore-want) > 0.2 {
+		t.Errorf("QualityScore = %.1f, want %.1f", q.QualityScore, want)
+	}
+}
+
+func TestLinearScore(t *testing.T) {
+	tests := []struct {
+		value, low, high, want float64
+	}{
+		{10, 20, 200, 0},
+		{110, 20, 200, 50},
+		{300, 20, 200, 100},
+		{50, 50, 50, 100},
+	}
+	for _, tt := range tests {
+		if got := linearScore(tt.value, tt.low, tt.high); got != tt.want {
+			t.Errorf("linearScore(%v, %v, %v) = %v, want %v", tt.value, tt.low, tt.high, got, tt.want)
+		}
+	}
+}
+
+func TestAssessFaceQuality(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/face.jpg")
+	if err != nil {
+		t.Skipf("Test image not available: %v", err)
+	}
+
+	result, err := AssessFaceQuality(imageData)
+	if err != nil {
+		t.Fatalf("AssessFaceQuality failed: %v", err)
+	}
+	t.Logf("quality=%.1f components=%+v", result.QualityScore, result.Components)
+
+	if result.QualityScore <= 0 || result.QualityScore > 100 {
+		t.Errorf("QualityScore = %.1f, want within (0, 100]", result.QualityScore)
+	}
+	if result.Components.Sharpness == nil || result.Components.FaceSize == nil {
+		t.Errorf("Expected sharpness and face size components, got %+v", result.Components)
+	}
+	if result.FaceCount == 0 {
+		t.Error("Expected at least one face")
+	}
+}
+
+func TestAssessFaceQuality_NoFace(t *testing.T) {
+	if _, err := AssessFaceQuality(uniformPNG(t, 200, 200)); !errors.Is(err, ErrNoFace) {
+		t.Errorf("Expected ErrNoFace, got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package facedetector
+
+import (
+	"image"
+	"math"
+)
+
+// ============================================================================
+// 顔の部位（目・口）ごとの鮮明度
+// ============================================================================
+
+const (
+	// regionMinSize は部位の鮮明度を計算する矩形の最小の幅・高さ（ピクセル）
+	regionMinSize = 8
+
+	// 特徴点から部位の矩形を作る際の大きさ。目は目尻から目尻までの幅に対する半幅・半高さ、
+	// 口は唇を囲む矩形に加えるマージンの割合
+	landmarkEyeHalfWidth  = 0.8
+	landmarkEyeHalfHeight = 0.5
+	landmarkMouthMargin   = 0.2
+)
+
+// 特徴点も目のHaar Cascadeも使えない場合に、顔矩形に対する平均的な位置から推定する部位の
+// 中心と大きさ（顔矩形の幅・高さに対する割合）
+var (
+	estimatedLeftEye  = [4]float64{0.3, 0.38, 0.26, 0.16}
+	estimatedRightEye = [4]float64{0.7, 0.38, 0.26, 0.16}
+	estimatedMouth    = [4]float64{0.5, 0.8, 0.4, 0.2}
+)
+
+// RegionSource は部位の矩形を求めた方法です。
+type RegionSource string
+
+const (
+	// RegionFromLandmarks は顔の特徴点（68点）から求めた矩形
+	RegionFromLandmarks RegionSource = "landmarks"
+	// RegionFromCascade は目のHaar Cascadeで検出した矩形
+	RegionFromCascade RegionSource = "cascade"
+	// RegionEstimated は顔矩形に対する平均的な位置から推定した矩形
+	RegionEstimated RegionSource = "estimated"
+)
+
+// ScoreRegion はNormalizedScoreの算出に使用した顔の領域です。
+type ScoreRegion string
+
+const (
+	// ScoreRegionFaceCenter は顔の中心領域（デフォルト）
+	ScoreRegionFaceCenter ScoreRegion = "face_center"
+	// ScoreRegionEyes は目の領域（スコアの低い方の目）
+	ScoreRegionEyes ScoreRegion = "eyes"
+)
+
+// RegionSharpness は顔の部位1つの鮮明度です。
+type RegionSharpness struct {
+	// BoundingBox は部位の矩形（元画像の座標系）。
+	BoundingBox BoundingBox `json:"bounding_box"`
+
+	// Source は部位の矩形を求めた方法。
+	Source RegionSource `json:"source"`
+
+	// NormalizedScore は部位の正規化された鮮明度スコア（0〜100点）。
+	NormalizedScore float64 `json:"normalized_score"`
+
+	// EdgeDecayRatio は部位のエッジ減衰率（0.0〜1.0）。
+	EdgeDecayRatio float64 `json:"edge_decay_ratio"`
+
+	// BlurType は部位のブレの種類。
+	BlurType BlurType `json:"blur_type"`
+
+	// result は目の領域で判定する場合に顔の結果へ反映する、部位の鮮明度の計算結果
+	result SharpnessResult
+}
+
+// FaceRegions は顔の部位ごとの鮮明度です。部位の矩形が小さすぎる場合は省略されます。
+// 左右は画像上の左右です（回転した画像から見つかった顔では、顔を正立させた画像上の左右）。
+type FaceRegions struct {
+	LeftEye  *RegionSharpness `json:"left_eye,omitempty"`
+	RightEye *RegionSharpness `json:"right_eye,omitempty"`
+	Mouth    *RegionSharpness `json:"mouth,omitempty"`
+}
+
+// locatedRegion は顔の部位の矩形と、その求め方です。
+type locatedRegion struct {
+	rect   image.Rectangle
+	source RegionSource
+}
+
+// analyzeFaceSharpness は1つの顔の中心領域の鮮明度と、部位ごとの鮮明度・目の開閉・頭部姿勢・遮蔽を計算します。
+// 目の領域で判定する場合は、スコアの低い方の目の結果でNormalizedScoreとブレの種類を置き換えます。
+// frame は uprightFaceFrame で求めた、顔が正立した画像です。部位と遮蔽の矩形は元の画像の座標系に戻して返します。
+func (d *Detector) analyzeFaceSharpness(frame faceFrame, index int, an *analysis) FaceSharpness {
+	img, gray, face := frame.img, frame.gray, frame.face
+
+	// 特徴点が無い場合は、目の位置と開閉の両方に目のHaar Cascadeの検出結果を使う
+	var eyes eyeDetection
+	if !face.Landmarks.Valid() {
+		eyes = d.detectEyes(gray, face.Rect)
+	}
+
+	fs := FaceSharpness{
+		Index:           index,
+		Face:            frame.original,
+		SharpnessResult: d.faceSharpness(img, face, an),
+		Regions:         d.analyzeFaceRegions(gray, face, eyes, an),
+		ScoreRegion:     ScoreRegionFaceCenter,
+		Eyes:            d.analyzeEyeOpenness(face, eyes),
+		Pose:            d.faceHeadPose(frame.original, frame.imageSize),
+		Occlusion:       d.analyzeOcclusion(img, face, eyes),
+	}
+	if an.eyeRegionScore {
+		applyEyeRegionScore(&fs)
+	}
+
+	// 部位と遮蔽の矩形を元の画像の座標系に戻す
+	for _, r := range []*RegionSharpness{fs.Regions.LeftEye, fs.Regions.RightEye, fs.Regions.Mouth} {
+		if r != nil {
+			r.BoundingBox = frame.imageBox(r.BoundingBox)
+		}
+	}
+	if z := fs.Occlusion.Zones; z != nil {
+		for _, zone := range []*ZoneOcclusion{z.LeftEye, z.RightEye, z.Nose, z.Mouth, z.LeftCheek, z.RightCheek} {
+			if zone != nil {
+				zone.BoundingBox = frame.imageBox(zone.BoundingBox)
+			}
+		}
+	}
+	return fs
+}
+
+// analyzeFaceRegions は目・口の矩形を求め、それぞれ正規化鮮明度パイプラインで鮮明度を計算します。
+// eyes は特徴点が無い場合の目のHaar Cascadeの検出結果です。
+func (d *Detector) analyzeFaceRegions(gray [][]float64, face Face, eyes eyeDetection, an *analysis) FaceRegions {
+	bounds := image.Rect(0, 0, 0, 0)
+	if len(gray) > 0 {
+		bounds = image.Rect(0, 0, len(gray[0]), len(gray))
+	}
+
+	// 部位ごとに追加の指標は計算しない
+	regionAnalysis := &analysis{scoreBasis: an.scoreBasis, region: true}
+
+	leftEye, rightEye, mouth := locateFaceRegions(face, eyes)
+	faceRect := clipRect(face.Rect, bounds)
+	return FaceRegions{
+		LeftEye:  d.regionSharpness(gray, bounds, faceRect, leftEye, regionAnalysis),
+		RightEye: d.regionSharpness(gray, bounds, faceRect, rightEye, regionAnalysis),
+		Mouth:    d.regionSharpness(gray, bounds, faceRect, mouth, regionAnalysis),
+	}
+}
+
+// regionSharpness は部位の矩形の鮮明度を計算します。矩形が小さすぎる場合はnilを返します。
+// faceRect は部位を含む顔の矩形で、正規化後の大きさの基準に使用します。
+func (d *Detector) regionSharpness(gray [][]float64, bounds, faceRect image.Rectangle, region locatedRegion, an *analysis) *RegionSharpness {
+	rect := clipRect(region.rect, bounds)
+	if rect.Dx() < regionMinSize || rect.Dy() < regionMinSize {
+		return nil
+	}
+
+	regionAnalysis := *an
+	regionAnalysis.normalizeSize = d.regionNormalizeSize(rect, faceRect)
+	result := d.calculateNormalizedSharpness(subRegion(gray, rect), rect.Dx(), rect.Dy(), &regionAnalysis)
+	return &RegionSharpness{
+		BoundingBox:     newBoundingBox(rect),
+		Source:          region.source,
+		NormalizedScore: result.NormalizedScore,
+		EdgeDecayRatio:  result.EdgeDecayRatio,
+		BlurType:        result.BlurType,
+		result:          result,
+	}
+}
+
+// regionNormalizeSize は部位の鮮明度計算の正規化後の大きさを返します。
+// 部位を基準サイズまで拡大すると補間でエッジが鈍り、顔の中心領域よりスコアが低く出るため、
+// 顔の中心領域（faceCenterRatio）を基準サイズに揃えるのと同じ倍率で部位を拡大・縮小します。
+func (d *Detector) regionNormalizeSize(rect, faceRect image.Rectangle) int {
+	size := float64(d.cfg.sharpnessNormalizeSize)
+	center := d.cfg.faceCenterRatio * math.Sqrt(float64(faceRect.Dx()*faceRect.Dy()))
+	if center <= 0 {
+		return d.cfg.sharpnessNormalizeSize
+	}
+	target := int(math.Round(math.Sqrt(float64(rect.Dx()*rect.Dy())) * size / center))
+	return max(regionMinSize, min(d.cfg.sharpnessNormalizeSize, target))
+}
+
+// applyEyeRegionScore は目の領域の結果で顔のNormalizedScoreとブレの種類を置き換えます。
+// 本人確認では両目が鮮明である必要があるため、スコアの低い方の目を採用します。
+// 目の領域がない場合は顔の中心領域の結果のままにします。
+func applyEyeRegionScore(fs *FaceSharpness) {
+	var worst *RegionSharpness
+	for _, eye := range []*RegionSharpness{fs.Regions.LeftEye, fs.Regions.RightEye} {
+		if eye != nil && (worst == nil || eye.NormalizedScore < worst.NormalizedScore) {
+			worst = eye
+		}
+	}
+	if worst == nil {
+		return
+	}
+
+	fs.NormalizedScore = worst.result.NormalizedScore
+	fs.BlurType = worst.result.BlurType
+	fs.MotionAngleDeg = worst.result.MotionAngleDeg
+	fs.MotionLengthPx = worst.result.MotionLengthPx
+	fs.ScoreRegion = ScoreRegionEyes
+}
+
+// locateFaceRegions は画像上の左目・右目・口の矩形を求めます。
+// 特徴点があれば特徴点から、なければ目は目のHaar Cascadeの検出結果 eyes を使い、
+// 見つからない部位は顔矩形に対する平均的な位置から推定します。
+func locateFaceRegions(face Face, eyes eyeDetection) (leftEye, rightEye, mouth locatedRegion) {
+	if face.Landmarks.Valid() {
+		return locatedRegion{eyeRegionFromLandmarks(face.Landmarks.LeftEye()), RegionFromLandmarks},
+			locatedRegion{eyeRegionFromLandmarks(face.Landmarks.RightEye()), RegionFromLandmarks},
+			locatedRegion{addMargin(landmarkBounds(face.Landmarks.Mouth()), landmarkMouthMargin), RegionFromLandmarks}
+	}
+
+	leftEye = locatedRegion{estimatedRegion(face.Rect, estimatedLeftEye), RegionEstimated}
+	rightEye = locatedRegion{estimatedRegion(face.Rect, estimatedRightEye), RegionEstimated}
+	mouth = locatedRegion{estimatedRegion(face.Rect, estimatedMouth), RegionEstimated}
+
+	if !eyes.left.Empty() {
+		leftEye = locatedRegion{eyes.left, RegionFromCascade}
+	}
+	if !eyes.right.Empty() {
+		rightEye = locatedRegion{eyes.right, RegionFromCascade}
+	}
+	return leftEye, rightEye, mouth
+}
+
+// eyeRegionFromLandmarks は目の6点から、目尻から目尻までの幅を基準にまぶたとまつ毛を含む矩形を求めます。
+func eyeRegionFromLandmarks(eye []LandmarkPoint) image.Rectangle {
+	if len(eye) != 6 {
+		return image.Rectangle{}
+	}
+	c := landmarkCenter(eye)
+	width := math.Hypot(eye[3].X-eye[0].X, eye[3].Y-eye[0].Y)
+	hw, hh := width*landmarkEyeHalfWidth, width*landmarkEyeHalfHeight
+	return image.Rect(int(math.Round(c.X-hw)), int(math.Round(c.Y-hh)), int(math.Round(c.X+hw)), int(math.Round(c.Y+hh)))
+}
+
+// estimatedRegion は顔矩形に対する中心と大きさの割合 (cx, cy, w, h) から部位の矩形を求めます。
+func estimatedRegion(faceRect image.Rectangle, r [4]float64) image.Rectangle {
+	fw, fh := float64(faceRect.Dx()), float64(faceRect.Dy())
+	cx := float64(faceRect.Min.X) + r[0]*fw
+	cy := float64(faceRect.Min.Y) + r[1]*fh
+	hw, hh := r[2]*fw/2, r[3]*fh/2
+	return image.Rect(int(math.Round(cx-hw)), int(math.Round(cy-hh)), int(math.Round(cx+hw)), int(math.Round(cy+hh)))
+}
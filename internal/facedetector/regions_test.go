@@ -0,0 +1,237 @@
+package facedetector
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// texturedFace は背景の中の顔矩形に、目の周りだけ鮮明なテクスチャを、それ以外の顔の領域に
+// 3x3の平均化を24回かけてぼかしたテクスチャを置いた画像を返します。
+func texturedFace(w, h int, faceRect image.Rectangle, eyes ...image.Rectangle) [][]float64 {
+	rng := rand.New(rand.NewSource(3))
+	sharp := constantGray(w, h, 120)
+	for y := faceRect.Min.Y; y < faceRect.Max.Y; y++ {
+		for x := faceRect.Min.X; x < faceRect.Max.X; x++ {
+			sharp[y][x] = 60 + 120*rng.Float64()
+		}
+	}
+
+	blurred := sharp
+	for i := 0; i < 24; i++ {
+		next := constantGray(w, h, 120)
+		for y := 1; y < h-1; y++ {
+			for x := 1; x < w-1; x++ {
+				sum := 0.0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						sum += blurred[y+dy][x+dx]
+					}
+				}
+				next[y][x] = sum / 9
+			}
+		}
+		blurred = next
+	}
+
+	for _, eye := range eyes {
+		for y := eye.Min.Y; y < eye.Max.Y; y++ {
+			copy(blurred[y][eye.Min.X:eye.Max.X], sharp[y][eye.Min.X:eye.Max.X])
+		}
+	}
+	return blurred
+}
+
+func TestLocateFaceRegions_Landmarks(t *testing.T) {
+	rect := image.Rect(100, 50, 300, 250)
+	face := Face{Rect: rect, Landmarks: syntheticLandmarks(rect)}
+
+	leftEye, rightEye, mouth := locateFaceRegions(face, eyeDetection{})
+	for name, r := range map[string]locatedRegion{"left_eye": leftEye, "right_eye": rightEye, "mouth": mouth} {
+		if r.source != RegionFromLandmarks {
+			t.Errorf("%s source = %q, want %q", name, r.source, RegionFromLandmarks)
+		}
+	}
+
+	// 左目の中心は顔矩形の (0.3, 0.38)、目尻から目尻までの幅は顔の幅の16%
+	if got, want := leftEye.rect, image.Rect(134, 110, 186, 142); got != want {
+		t.Errorf("left eye = %v, want %v", got, want)
+	}
+	if rightEye.rect.Min.X <= leftEye.rect.Max.X {
+		t.Errorf("Expected right eye %v to be right of left eye %v", rightEye.rect, leftEye.rect)
+	}
+	if c := mouth.rect.Min.Add(mouth.rect.Max).Div(2); c.X != 200 || c.Y < 209 || c.Y > 211 {
+		t.Errorf("mouth center = %v, want about (200,210)", c)
+	}
+}
+
+func TestLocateFaceRegions_Estimated(t *testing.T) {
+	// 特徴点も目のカスケードの検出も無い場合は顔矩形の比率から推定する
+	rect := image.Rect(0, 0, 200, 200)
+	leftEye, rightEye, mouth := locateFaceRegions(Face{Rect: rect}, eyeDetection{})
+
+	if leftEye.source != RegionEstimated || rightEye.source != RegionEstimated || mouth.source != RegionEstimated {
+		t.Fatalf("Expected estimated regions, got %q / %q / %q", leftEye.source, rightEye.source, mouth.source)
+	}
+	if got, want := leftEye.rect, image.Rect(34, 60, 86, 92); got != want {
+		t.Errorf("left eye = %v, want %v", got, want)
+	}
+	if got, want := mouth.rect, image.Rect(60, 140, 140, 180); got != want {
+		t.Errorf("mouth = %v, want %v", got, want)
+	}
+}
+
+func TestLocateFaceRegions_Cascade(t *testing.T) {
+	// カスケードで見つかった目だけカスケードの矩形を使い、見つからない目は推定する
+	eye := image.Rect(40, 60, 80, 90)
+	leftEye, rightEye, _ := locateFaceRegions(Face{Rect: image.Rect(0, 0, 200, 200)}, eyeDetection{left: eye, ran: true})
+
+	if leftEye.source != RegionFromCascade || leftEye.rect != eye {
+		t.Errorf("left eye = %v (%q), want %v from cascade", leftEye.rect, leftEye.source, eye)
+	}
+	if rightEye.source != RegionEstimated {
+		t.Errorf("right eye source = %q, want %q", rightEye.source, RegionEstimated)
+	}
+}
+
+func TestAnalyzeFaceRegions_SharpEyes(t *testing.T) {
+	rect := image.Rect(20, 20, 220, 220)
+	face := Face{Rect: rect, Landmarks: syntheticLandmarks(rect)}
+	leftEye := eyeRegionFromLandmarks(face.Landmarks.LeftEye())
+	rightEye := eyeRegionFromLandmarks(face.Landmarks.RightEye())
+	gray := texturedFace(240, 240, rect, leftEye, rightEye)
+
+	d := New()
+	an, err := d.newAnalysis(nil)
+	if err != nil {
+		t.Fatalf("newAnalysis failed: %v", err)
+	}
+	regions := d.analyzeFaceRegions(gray, face, eyeDetection{}, an)
+	if regions.LeftEye == nil || regions.RightEye == nil || regions.Mouth == nil {
+		t.Fatalf("Expected all regions, got %+v", regions)
+	}
+	t.Logf("left=%.1f right=%.1f mouth=%.1f", regions.LeftEye.NormalizedScore, regions.RightEye.NormalizedScore, regions.Mouth.NormalizedScore)
+
+	if regions.LeftEye.BoundingBox.rect() != leftEye {
+		t.Errorf("left eye box = %+v, want %v", regions.LeftEye.BoundingBox, leftEye)
+	}
+	for name, eye := range map[string]*RegionSharpness{"left": regions.LeftEye, "right": regions.RightEye} {
+		if eye.NormalizedScore < regions.Mouth.NormalizedScore+20 {
+			t.Errorf("Expected %s eye (%.1f) to be much sharper than the mouth (%.1f)", name, eye.NormalizedScore, regions.Mouth.NormalizedScore)
+		}
+	}
+}
+
+func TestAnalyzeFaceRegions_MatchesFaceCenter(t *testing.T) {
+	// 顔全体が同じテクスチャの場合、目の領域のスコアは顔の中心領域のスコアに近い
+	// （目の領域を基準サイズまで拡大すると、補間でエッジが鈍ってスコアが大きく下がる）
+	rect := image.Rect(20, 20, 420, 420)
+	face := Face{Rect: rect, Landmarks: syntheticLandmarks(rect)}
+	gray := texturedFace(440, 440, rect)
+	d := New()
+	an, err := d.newAnalysis(nil)
+	if err != nil {
+		t.Fatalf("newAnalysis failed: %v", err)
+	}
+
+	center := d.calculateNormalizedSharpness(extractFaceCenterRegion(subRegion(gray, rect), d.cfg.faceCenterRatio), rect.Dx(), rect.Dy(), an)
+	regions := d.analyzeFaceRegions(gray, face, eyeDetection{}, an)
+	if regions.LeftEye == nil || regions.RightEye == nil {
+		t.Fatalf("Expected both eye regions, got %+v", regions)
+	}
+	t.Logf("center=%.1f left=%.1f right=%.1f", center.NormalizedScore, regions.LeftEye.NormalizedScore, regions.RightEye.NormalizedScore)
+
+	for side, eye := range map[string]*RegionSharpness{"left": regions.LeftEye, "right": regions.RightEye} {
+		if math.Abs(eye.NormalizedScore-center.NormalizedScore) > 20 {
+			t.Errorf("%s eye score %.1f differs from the face center score %.1f", side, eye.NormalizedScore, center.NormalizedScore)
+		}
+	}
+}
+
+func TestRegionSharpness_TooSmall(t *testing.T) {
+	d := New()
+	an, _ := d.newAnalysis(nil)
+	gray := constantGray(100, 100, 128)
+	if got := d.regionSharpness(gray, image.Rect(0, 0, 100, 100), image.Rect(0, 0, 100, 100), locatedRegion{image.Rect(95, 95, 110, 110), RegionEstimated}, an); got != nil {
+		t.Errorf("Expected nil for a region clipped below %d px, got %+v", regionMinSize, got)
+	}
+}
+
+func TestRegionNormalizeSize(t *testing.T) {
+	d := New()
+	tests := []struct {
+		name       string
+		rect, face image.Rectangle
+		want       int
+	}{
+		// 顔の中心領域（240px）を128pxに縮小するのと同じ倍率で縮小する
+		{"large face", image.Rect(0, 0, 80, 40), image.Rect(0, 0, 400, 400), 30},
+		// 顔の中心領域（60px）を128pxに拡大するのと同じ倍率で拡大する
+		{"small face", image.Rect(0, 0, 20, 10), image.Rect(0, 0, 100, 100), 30},
+		{"clamped to the base size", image.Rect(0, 0, 200, 200), image.Rect(0, 0, 200, 200), 128},
+		{"clamped to the minimum", image.Rect(0, 0, 8, 8), image.Rect(0, 0, 1000, 1000), regionMinSize},
+		{"empty face", image.Rect(0, 0, 20, 10), image.Rectangle{}, 128},
+	}
+	for _, tt := range tests {
+		if got := d.regionNormalizeSize(tt.rect, tt.face); got != tt.want {
+			t.Errorf("%s: regionNormalizeSize = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyEyeRegionScore(t *testing.T) {
+	fs := FaceSharpness{ScoreRegion: ScoreRegionFaceCenter}
+	fs.NormalizedScore = 90
+	fs.Regions = FaceRegions{
+		LeftEye:  &RegionSharpness{NormalizedScore: 85, result: SharpnessResult{NormalizedScore: 85, BlurType: BlurTypeNone}},
+		RightEye: &RegionSharpness{NormalizedScore: 35, result: SharpnessResult{NormalizedScore: 35, BlurType: BlurTypeMotion, MotionAngleDeg: 30}},
+	}
+
+	applyEyeRegionScore(&fs)
+	if fs.NormalizedScore != 35 || fs.BlurType != BlurTypeMotion || fs.MotionAngleDeg != 30 || fs.ScoreRegion != ScoreRegionEyes {
+		t.Errorf("Expected the blurrier eye to decide the result, got score=%.1f blur=%q region=%q", fs.NormalizedScore, fs.BlurType, fs.ScoreRegion)
+	}
+
+	// 目の領域がない場合は顔の中心領域のまま
+	noEyes := FaceSharpness{ScoreRegion: ScoreRegionFaceCenter}
+	noEyes.NormalizedScore = 70
+	applyEyeRegionScore(&noEyes)
+	if noEyes.NormalizedScore != 70 || noEyes.ScoreRegion != ScoreRegionFaceCenter {
+		t.Errorf("Expected face center result without eyes, got %+v", noEyes)
+	}
+}
+
+func TestCalculateFaceSharpness_Regions(t *testing.T) {
+	imageData, err := os.ReadFile("testdata/face.jpg")
+	if err != nil {
+		t.Skipf("Test image not available: %v", err)
+	}
+
+	result, err := CalculateFaceSharpness(imageData)
+	if err != nil {
+		t.Fatalf("CalculateFaceSharpness failed: %v", err)
+	}
+	if result.ScoreRegion != ScoreRegionFaceCenter {
+		t.Errorf("ScoreRegion = %q, want %q", result.ScoreRegion, ScoreRegionFaceCenter)
+	}
+	if result.Regions.LeftEye == nil || result.Regions.RightEye == nil {
+		t.Fatalf("Expected both eye regions, got %+v", result.Regions)
+	}
+
+	eyes, err := CalculateFaceSharpness(imageData, WithEyeRegionScore())
+	if err != nil {
+		t.Fatalf("CalculateFaceSharpness with eye region failed: %v", err)
+	}
+	if eyes.ScoreRegion != ScoreRegionEyes {
+		t.Errorf("ScoreRegion = %q, want %q", eyes.ScoreRegion, ScoreRegionEyes)
+	}
+	want := eyes.Regions.LeftEye.NormalizedScore
+	if eyes.Regions.RightEye.NormalizedScore < want {
+		want = eyes.Regions.RightEye.NormalizedScore
+	}
+	if eyes.NormalizedScore != want {
+		t.Errorf("NormalizedScore = %.1f, want the lower eye score %.1f", eyes.NormalizedScore, want)
+	}
+}
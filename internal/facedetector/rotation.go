@@ -0,0 +1,242 @@
+package facedetector
+
+import (
+	"context"
+	"errors"
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================================
+// 回転した画像での再検出
+// ============================================================================
+
+// retryRotations は顔が見つからない場合に試行する回転角度（時計回り、度）。
+// 横向きの写真が多いため、90度・270度を180度より先に試行します。
+var retryRotations = []int{90, 270, 180}
+
+// detectRotated は画像を回転して順に顔検出を再試行し、最初に顔が見つかった回転での結果を
+// 元の画像の座標系に戻して返します。特徴点は正立した画像で推定してから座標を戻します。
+// どの回転でも見つからない場合は空のスライスを、最小サイズ未満の顔しか見つからない場合はErrFaceTooSmallを返します。
+func (d *Detector) detectRotated(ctx context.Context, mat gocv.Mat) ([]Face, error) {
+	w, h := mat.Cols(), mat.Rows()
+	tooSmall := false
+	for _, degrees := range retryRotations {
+		rotated := rotateMat(mat, degrees)
+		faces, err := d.detectInMat(ctx, rotated)
+		rotated.Close()
+		if errors.Is(err, ErrFaceTooSmall) {
+			tooSmall = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(faces) == 0 {
+			continue
+		}
+
+		for i := range faces {
+			faces[i] = unrotateFace(faces[i], degrees, w, h)
+		}
+		return faces, nil
+	}
+	if tooSmall {
+		return nil, ErrFaceTooSmall
+	}
+	return []Face{}, nil
+}
+
+// rotateMat はMatを時計回りにdegrees度（90・180・270）回転した新しいMatを返します。
+// 戻り値は呼び出し側でClose()する必要があります。
+func rotateMat(mat gocv.Mat, degrees int) gocv.Mat {
+	dst := gocv.NewMat()
+	switch degrees {
+	case 90:
+		gocv.Rotate(mat, &dst, gocv.Rotate90Clockwise)
+	case 180:
+		gocv.Rotate(mat, &dst, gocv.Rotate180Clockwise)
+	case 270:
+		gocv.Rotate(mat, &dst, gocv.Rotate90CounterClockwise)
+	default:
+		mat.CopyTo(&dst)
+	}
+	return dst
+}
+
+// unrotateFace は時計回りにdegrees度回転した画像（回転前の大きさはw×h）で検出した顔の矩形と特徴点を
+// 回転前の画像の座標系に戻し、Rotationを設定します。
+func unrotateFace(face Face, degrees, w, h int) Face {
+	face.Rect = unrotateRect(face.Rect, degrees, w, h)
+	if len(face.Landmarks) > 0 {
+		landmarks := make(Landmarks, len(face.Landmarks))
+		for i, p := range face.Landmarks {
+			landmarks[i] = unrotatePoint(p, degrees, w, h)
+		}
+		face.Landmarks = landmarks
+	}
+	face.Rotation = degrees
+	return face
+}
+
+// unrotateRect は回転した画像の矩形を回転前の画像（w×h）の座標系に戻します。
+func unrotateRect(r image.Rectangle, degrees, w, h int) image.Rectangle {
+	switch degrees {
+	case 90:
+		return image.Rect(r.Min.Y, h-r.Max.X, r.Max.Y, h-r.Min.X)
+	case 180:
+		return image.Rect(w-r.Max.X, h-r.Max.Y, w-r.Min.X, h-r.Min.Y)
+	case 270:
+		return image.Rect(w-r.Max.Y, r.Min.X, w-r.Min.Y, r.Max.X)
+	}
+	return r
+}
+
+// unrotatePoint は回転した画像の画素の座標を回転前の画像（w×h）の座標系に戻します。
+func unrotatePoint(p LandmarkPoint, degrees, w, h int) LandmarkPoint {
+	switch degrees {
+	case 90:
+		return newLandmarkPoint(p.Y, float64(h-1)-p.X)
+	case 180:
+		return newLandmarkPoint(float64(w-1)-p.X, float64(h-1)-p.Y)
+	case 270:
+		return newLandmarkPoint(float64(w-1)-p.Y, p.X)
+	}
+	return p
+}
+
+// rotateFace はunrotateFaceの逆で、元の画像（w×h）の座標系の顔の矩形と特徴点を、
+// 画像を時計回りにdegrees度回転した座標系に移します。Rotationは0になります。
+func rotateFace(face Face, degrees, w, h int) Face {
+	face.Rect = rotateRect(face.Rect, degrees, w, h)
+	if len(face.Landmarks) > 0 {
+		landmarks := make(Landmarks, len(face.Landmarks))
+		for i, p := range face.Landmarks {
+			landmarks[i] = rotatePoint(p, degrees, w, h)
+		}
+		face.Landmarks = landmarks
+	}
+	face.Rotation = 0
+	return face
+}
+
+// rotateRect は元の画像（w×h）の矩形を、画像を時計回りにdegrees度回転した座標系に移します。
+func rotateRect(r image.Rectangle, degrees, w, h int) image.Rectangle {
+	switch degrees {
+	case 90:
+		return image.Rect(h-r.Max.Y, r.Min.X, h-r.Min.Y, r.Max.X)
+	case 180:
+		return image.Rect(w-r.Max.X, h-r.Max.Y, w-r.Min.X, h-r.Min.Y)
+	case 270:
+		return image.Rect(r.Min.Y, w-r.Max.X, r.Max.Y, w-r.Min.X)
+	}
+	return r
+}
+
+// rotatePoint は元の画像（w×h）の画素の座標を、画像を時計回りにdegrees度回転した座標系に移します。
+func rotatePoint(p LandmarkPoint, degrees, w, h int) LandmarkPoint {
+	switch degrees {
+	case 90:
+		return newLandmarkPoint(float64(h-1)-p.Y, p.X)
+	case 180:
+		return newLandmarkPoint(float64(w-1)-p.X, float64(h-1)-p.Y)
+	case 270:
+		return newLandmarkPoint(p.Y, float64(w-1)-p.X)
+	}
+	return p
+}
+
+// rotateSize は画像を時計回りにdegrees度回転した後の大きさを返します。
+func rotateSize(size image.Point, degrees int) image.Point {
+	if degrees == 90 || degrees == 270 {
+		return image.Pt(size.Y, size.X)
+	}
+	return size
+}
+
+// rotationOrientation は時計回りの回転角度と同じ回転を表すEXIFの向きを返します。
+func rotationOrientation(degrees int) Orientation {
+	switch degrees {
+	case 90:
+		return OrientationRotate90
+	case 180:
+		return OrientationRotate180
+	case 270:
+		return OrientationRotate270
+	}
+	return OrientationNormal
+}
+
+// ============================================================================
+// 回転した顔の正立した切り抜きでの解析
+// ============================================================================
+
+// faceFrame は1つの顔の解析に使う画像とグレースケール画像です。
+// 回転した画像から見つかった顔（Face.Rotationが0以外）では、部位の位置・目のHaar Cascade・遮蔽・照明が
+// 正立した顔を前提とするため、顔の周囲を切り抜いてRotationだけ時計回りに回転した画像になります。
+type faceFrame struct {
+	img  image.Image
+	gray [][]float64
+
+	// face はこの画像の座標系での顔、original は元の画像の座標系での顔
+	face, original Face
+
+	// imageSize は元の画像の大きさ
+	imageSize image.Point
+
+	// origin は切り抜いた範囲の元の画像での左上、size は回転前の切り抜きの大きさ、degrees は回転角度
+	origin  image.Point
+	size    image.Point
+	degrees int
+}
+
+// uprightFaceFrame は顔の解析に使う画像を返します。Rotationが0の顔では画像全体をそのまま使います。
+// 切り抜きには逆光の判定に使う顔の周囲のリングを含めます。
+func uprightFaceFrame(img image.Image, gray [][]float64, face Face) faceFrame {
+	if face.Rotation == 0 {
+		return faceFrame{img: img, gray: gray, face: face, original: face, imageSize: img.Bounds().Size()}
+	}
+
+	crop := clipRect(addMargin(face.Rect, backlightRingMargin), img.Bounds())
+	var cropped image.Image
+	if sub, ok := img.(subImager); ok {
+		cropped = sub.SubImage(crop)
+	} else {
+		cropped = toRGBA(img).SubImage(crop.Sub(img.Bounds().Min))
+	}
+	rotated := orientImage(cropped, rotationOrientation(face.Rotation))
+
+	local := face
+	local.Rect = face.Rect.Sub(crop.Min)
+	if len(face.Landmarks) > 0 {
+		local.Landmarks = make(Landmarks, len(face.Landmarks))
+		for i, p := range face.Landmarks {
+			local.Landmarks[i] = newLandmarkPoint(p.X-float64(crop.Min.X), p.Y-float64(crop.Min.Y))
+		}
+	}
+
+	return faceFrame{
+		img:       rotated,
+		gray:      convertToGrayscale(rotated),
+		face:      rotateFace(local, face.Rotation, crop.Dx(), crop.Dy()),
+		original:  face,
+		imageSize: img.Bounds().Size(),
+		origin:    crop.Min,
+		size:      crop.Size(),
+		degrees:   face.Rotation,
+	}
+}
+
+// imageRect はこの画像の座標系の矩形を元の画像の座標系に戻します。
+func (f faceFrame) imageRect(r image.Rectangle) image.Rectangle {
+	if f.degrees == 0 {
+		return r
+	}
+	return unrotateRect(r, f.degrees, f.size.X, f.size.Y).Add(f.origin)
+}
+
+// imageBox はこの画像の座標系のBoundingBoxを元の画像の座標系に戻します。
+func (f faceFrame) imageBox(b BoundingBox) BoundingBox {
+	return newBoundingBox(f.imageRect(b.rect()))
+}
@@ -0,0 +1,267 @@
+package facedetector
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+// portraitDetector は縦長の画像でのみ固定の矩形を返すテスト用のバックエンドです（横向きの写真の再現用）。
+type portraitDetector struct {
+	rect image.Rectangle
+}
+
+func (p *portraitDetector) Detect(mat gocv.Mat) []Face {
+	if mat.Rows() <= mat.Cols() {
+		return nil
+	}
+	return []Face{{Rect: p.rect, Confidence: 0.9, Source: "stub"}}
+}
+
+// rotationOrientations は時計回りの回転角度と、同じ回転を表すEXIFの向き
+var rotationOrientations = map[int]Orientation{
+	90:  OrientationRotate90,
+	180: OrientationRotate180,
+	270: OrientationRotate270,
+}
+
+func TestUnrotateRect(t *testing.T) {
+	// 矩形の内側を白く塗った画像を回転し、回転後の白い領域を元の座標系に戻すと元の矩形に一致する
+	const w, h = 40, 30
+	rect := image.Rect(5, 3, 17, 11)
+	src := image.NewGray(image.Rect(0, 0, w, h))
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			src.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	for degrees, o := range rotationOrientations {
+		rotated := orientImage(src, o)
+		var found image.Rectangle
+		b := rotated.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if c := color.GrayModel.Convert(rotated.At(x, y)).(color.Gray); c.Y == 255 {
+					found = found.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+
+		if got := unrotateRect(found, degrees, w, h); got != rect {
+			t.Errorf("%d degrees: unrotateRect(%v) = %v, want %v", degrees, found, got, rect)
+		}
+	}
+
+	if got := unrotateRect(rect, 0, w, h); got != rect {
+		t.Errorf("0 degrees: unrotateRect = %v, want %v", got, rect)
+	}
+}
+
+func TestUnrotatePoint(t *testing.T) {
+	const w, h = 40, 30
+	p := image.Pt(7, 4)
+	src := image.NewGray(image.Rect(0, 0, w, h))
+	src.SetGray(p.X, p.Y, color.Gray{Y: 255})
+
+	for degrees, o := range rotationOrientations {
+		rotated := orientImage(src, o)
+		b := rotated.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if c := color.GrayModel.Convert(rotated.At(x, y)).(color.Gray); c.Y != 255 {
+					continue
+				}
+				got := unrotatePoint(LandmarkPoint{X: float64(x), Y: float64(y)}, degrees, w, h)
+				if want := (LandmarkPoint{X: float64(p.X), Y: float64(p.Y)}); got != want {
+					t.Errorf("%d degrees: unrotatePoint(%d, %d) = %v, want %v", degrees, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestUnrotateFace(t *testing.T) {
+	face := Face{
+		Rect:      image.Rect(10, 20, 60, 80),
+		Landmarks: Landmarks{{X: 30, Y: 40}},
+	}
+
+	got := unrotateFace(face, 90, 200, 100)
+	if want := image.Rect(20, 40, 80, 90); got.Rect != want {
+		t.Errorf("Rect = %v, want %v", got.Rect, want)
+	}
+	if want := (LandmarkPoint{X: 40, Y: 69}); len(got.Landmarks) != 1 || got.Landmarks[0] != want {
+		t.Errorf("Landmarks = %v, want [%v]", got.Landmarks, want)
+	}
+	if got.Rotation != 90 {
+		t.Errorf("Rotation = %d, want 90", got.Rotation)
+	}
+	// 元の特徴点は変更しない
+	if face.Landmarks[0] != (LandmarkPoint{X: 30, Y: 40}) {
+		t.Errorf("Original landmarks were modified: %v", face.Landmarks)
+	}
+}
+
+func TestRotateFace(t *testing.T) {
+	// rotateFace はunrotateFaceの逆
+	const w, h = 200, 100
+	face := Face{
+		Rect:      image.Rect(10, 20, 60, 80),
+		Landmarks: Landmarks{{X: 30, Y: 40}},
+	}
+	for degrees := range rotationOrientations {
+		size := rotateSize(image.Pt(w, h), degrees)
+		rotated := rotateFace(face, degrees, w, h)
+		if !rotated.Rect.In(image.Rect(0, 0, size.X, size.Y)) {
+			t.Errorf("%d degrees: Rect %v is outside the rotated image %v", degrees, rotated.Rect, size)
+		}
+		back := unrotateFace(rotated, degrees, w, h)
+		if back.Rect != face.Rect || back.Landmarks[0] != face.Landmarks[0] {
+			t.Errorf("%d degrees: unrotateFace(rotateFace) = %v %v, want %v %v",
+				degrees, back.Rect, back.Landmarks, face.Rect, face.Landmarks)
+		}
+		if got := rotationOrientation(degrees); got != rotationOrientations[degrees] {
+			t.Errorf("rotationOrientation(%d) = %d, want %d", degrees, got, rotationOrientations[degrees])
+		}
+	}
+}
+
+// sidewaysFace は顔の上半分を白、下半分を黒にした正立した画像を反時計回りに90度回転し、
+// 時計回りに90度回転すると正立する横向きの画像と、その画像での顔（Rotation: 90）を返します。
+func sidewaysFace(upright image.Rectangle, w, h int) (image.Image, Face) {
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := upright.Min.Y; y < upright.Max.Y; y++ {
+		for x := upright.Min.X; x < upright.Max.X; x++ {
+			if y < upright.Min.Y+upright.Dy()/2 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+	sideways := orientImage(src, OrientationRotate270)
+	return sideways, Face{Rect: unrotateRect(upright, 90, h, w), Rotation: 90}
+}
+
+func TestUprightFaceFrame(t *testing.T) {
+	upright := image.Rect(60, 40, 140, 120)
+	img, face := sidewaysFace(upright, 200, 160)
+
+	frame := uprightFaceFrame(img, convertToGrayscale(img), face)
+	if size := frame.face.Rect.Size(); size != upright.Size() {
+		t.Fatalf("face size in the frame = %v, want %v", size, upright.Size())
+	}
+	if got := frame.imageRect(frame.face.Rect); got != face.Rect {
+		t.Errorf("imageRect = %v, want %v", got, face.Rect)
+	}
+
+	// 切り抜きを正立させると、顔の上半分が白、下半分が黒になる
+	r := frame.face.Rect
+	top := frame.gray[r.Min.Y+r.Dy()/4][r.Min.X+r.Dx()/2]
+	bottom := frame.gray[r.Max.Y-r.Dy()/4][r.Min.X+r.Dx()/2]
+	if top < 250 || bottom > 5 {
+		t.Errorf("Expected a white top and a black bottom, got %.0f and %.0f", top, bottom)
+	}
+	if l := New().analyzeLighting(frame.gray, frame.face.Rect); l.Verdict == LightingEven {
+		t.Errorf("Expected the top/bottom split to be detected in the upright frame, got %+v", l)
+	}
+
+	// 回転していない顔は画像全体をそのまま使う
+	plain := uprightFaceFrame(img, frame.gray, Face{Rect: face.Rect})
+	if plain.face.Rect != face.Rect || plain.imageRect(face.Rect) != face.Rect {
+		t.Errorf("Expected the unrotated face to keep its rectangle, got %v", plain.face.Rect)
+	}
+}
+
+func TestAnalyzeFaceSharpness_Rotated(t *testing.T) {
+	// 特徴点の無い顔の目・口の推定位置は、正立させた顔の上側・下側になる
+	upright := image.Rect(60, 40, 140, 120)
+	img, face := sidewaysFace(upright, 200, 160)
+
+	d := New()
+	an, err := d.newAnalysis(nil)
+	if err != nil {
+		t.Fatalf("newAnalysis failed: %v", err)
+	}
+	fs := d.analyzeFaceSharpness(uprightFaceFrame(img, convertToGrayscale(img), face), 0, an)
+	if fs.Face.Rect != face.Rect || fs.Face.Rotation != 90 {
+		t.Errorf("Face = %v (rotation %d), want the original face %v", fs.Face.Rect, fs.Face.Rotation, face.Rect)
+	}
+	if fs.Regions.LeftEye == nil || fs.Regions.RightEye == nil || fs.Regions.Mouth == nil {
+		t.Fatalf("Expected eye and mouth regions, got %+v", fs.Regions)
+	}
+
+	// 時計回りに90度回転すると正立する顔では、顔の上側は画像上の左側にある
+	centerX := func(b BoundingBox) int { return b.X + b.Width/2 }
+	faceCenter := face.Rect.Min.X + face.Rect.Dx()/2
+	for name, r := range map[string]*RegionSharpness{"left eye": fs.Regions.LeftEye, "right eye": fs.Regions.RightEye} {
+		if !r.BoundingBox.rect().In(face.Rect) || centerX(r.BoundingBox) >= faceCenter {
+			t.Errorf("%s %+v should be in the left half of %v", name, r.BoundingBox, face.Rect)
+		}
+	}
+	if m := fs.Regions.Mouth.BoundingBox; !m.rect().In(face.Rect) || centerX(m) <= faceCenter {
+		t.Errorf("mouth %+v should be in the right half of %v", m, face.Rect)
+	}
+}
+
+func TestFaceHeadPose_Rotated(t *testing.T) {
+	// 時計回りに90度回転すると正立する顔は、ロールが約90度ではなく正面と判定される
+	size := image.Pt(300, 200)
+	upright := image.Rect(100, 60, 200, 160)
+	face := unrotateFace(Face{Rect: upright, Landmarks: posedLandmarks(upright, size, 0, 0, 0)}, 90, size.Y, size.X)
+
+	d := New()
+	if pose := d.estimateHeadPose(face.Landmarks, rotateSize(size, 90)); pose == nil || math.Abs(pose.Roll) < 80 {
+		t.Errorf("Expected a roll of about 90 degrees without the rotation, got %+v", pose)
+	}
+	pose := d.faceHeadPose(face, rotateSize(size, 90))
+	if pose == nil || !pose.Frontal || math.Abs(pose.Roll) > 1 {
+		t.Errorf("Expected a frontal upright pose, got %+v", pose)
+	}
+	if !d.isFrontal(face, rotateSize(size, 90)) {
+		t.Error("Expected the rotated face to be frontal")
+	}
+}
+
+func TestDetectFaces_RotationRetry(t *testing.T) {
+	// 横長の画像を時計回りに90度回転した縦長の画像（200x300）で顔が見つかる
+	stub := &portraitDetector{rect: image.Rect(40, 60, 140, 170)}
+	imageData := uniformPNG(t, 300, 200)
+
+	faces, err := New(WithBackends(stub)).DetectFaces(imageData)
+	if err != nil {
+		t.Fatalf("DetectFaces failed: %v", err)
+	}
+	if len(faces) != 0 {
+		t.Fatalf("Expected no faces without WithRotationRetry, got %+v", faces)
+	}
+
+	faces, err = New(WithBackends(stub), WithRotationRetry(true)).DetectFaces(imageData)
+	if err != nil {
+		t.Fatalf("DetectFaces failed: %v", err)
+	}
+	if len(faces) != 1 {
+		t.Fatalf("Expected 1 face, got %d", len(faces))
+	}
+	if faces[0].Rotation != 90 {
+		t.Errorf("Rotation = %d, want 90", faces[0].Rotation)
+	}
+	if want := unrotateRect(stub.rect, 90, 300, 200); faces[0].Rect != want {
+		t.Errorf("Rect = %v, want %v", faces[0].Rect, want)
+	}
+}
+
+func TestFace_MarshalJSON_Rotation(t *testing.T) {
+	data, err := Face{Rect: image.Rect(0, 0, 10, 10), Source: SourceDNN, Phase: PhaseRaw, Rotation: 270}.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	want := `{"bounding_box":{"x":0,"y":0,"width":10,"height":10},"confidence":0,"source":"dnn","phase":"raw","rotation":270}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON = %s, want %s", data, want)
+	}
+}
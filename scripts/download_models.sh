@@ -3,6 +3,8 @@
 #
 # OpenCV の SSD ResNet-10 モデルをダウンロードします。
 # このモデルは Haar Cascade よりも大幅に高精度な顔検出が可能です。
+# LBFMODEL_COMMIT と LBFMODEL_SHA256 を指定した場合は、あわせて顔の特徴点（68点）検出用の
+# LBF Facemark モデルをダウンロードし、チェックサムを検証します。
 #
 # 使用方法:
 #   ./scripts/download_models.sh
@@ -17,12 +19,27 @@ MODELS_DIR="${PROJECT_ROOT}/internal/facedetector/models"
 # モデルファイルのURL（OpenCV公式リポジトリ）
 CAFFEMODEL_URL="https://raw.githubusercontent.com/opencv/opencv_3rdparty/dnn_samples_face_detector_20170830/res10_300x300_ssd_iter_140000.caffemodel"
 PROTOTXT_URL="https://raw.githubusercontent.com/opencv/opencv/4.x/samples/dnn/face_detector/deploy.prototxt"
+# LBF Facemark モデルは個人リポジトリのため、コミットを固定してチェックサムで検証する
+LBFMODEL_COMMIT="${LBFMODEL_COMMIT:-}"
+LBFMODEL_URL="https://raw.githubusercontent.com/kurnianggoro/GSOC2017/${LBFMODEL_COMMIT}/data/lbfmodel.yaml"
 
 CAFFEMODEL_FILE="${MODELS_DIR}/res10_300x300_ssd_iter_140000.caffemodel"
 PROTOTXT_FILE="${MODELS_DIR}/deploy.prototxt"
+LBFMODEL_FILE="${MODELS_DIR}/lbfmodel.yaml"
 
 # チェックサム（整合性検証用）
 CAFFEMODEL_SHA256="2a56a11a57a4a295956b0660b4a3d76bbdca2206c4961cea8efe7d95c7cb2f2d"
+# lbfmodel.yaml は LBFMODEL_COMMIT の版のチェックサムを設定する（未設定の場合はダウンロードしない）
+LBFMODEL_SHA256="${LBFMODEL_SHA256:-}"
+
+# sha256 はファイルのSHA-256を出力します（sha256sum/shasum が無い場合は空）
+sha256() {
+    if command -v sha256sum &> /dev/null; then
+        sha256sum "$1" | awk '{print $1}'
+    elif command -v shasum &> /dev/null; then
+        shasum -a 256 "$1" | awk '{print $1}'
+    fi
+}
 
 echo "=== DNN顔検出モデルのダウンロード ==="
 echo ""
@@ -36,7 +53,7 @@ if [ -f "${CAFFEMODEL_FILE}" ]; then
 else
     echo "→ caffemodel をダウンロード中..."
     if command -v curl &> /dev/null; then
-        curl -L --progress-bar -o "${CAFFEMODEL_FILE}" "${CAFFEMODEL_URL}"
+        curl -fL --progress-bar -o "${CAFFEMODEL_FILE}" "${CAFFEMODEL_URL}"
     elif command -v wget &> /dev/null; then
         wget --show-progress -O "${CAFFEMODEL_FILE}" "${CAFFEMODEL_URL}"
     else
@@ -52,7 +69,7 @@ if [ -f "${PROTOTXT_FILE}" ]; then
 else
     echo "→ prototxt をダウンロード中..."
     if command -v curl &> /dev/null; then
-        curl -L --progress-bar -o "${PROTOTXT_FILE}" "${PROTOTXT_URL}"
+        curl -fL --progress-bar -o "${PROTOTXT_FILE}" "${PROTOTXT_URL}"
     elif command -v wget &> /dev/null; then
         wget --show-progress -O "${PROTOTXT_FILE}" "${PROTOTXT_URL}"
     else
@@ -62,28 +79,59 @@ else
     echo "✓ prototxt のダウンロードが完了しました"
 fi
 
+# LBF Facemark モデルのダウンロード（顔の特徴点検出用）
+if [ -f "${LBFMODEL_FILE}" ]; then
+    echo "✓ lbfmodel は既にダウンロード済みです: ${LBFMODEL_FILE}"
+elif [ -z "${LBFMODEL_COMMIT}" ] || [ -z "${LBFMODEL_SHA256}" ]; then
+    echo "⚠ LBFMODEL_COMMIT / LBFMODEL_SHA256 が未設定のため lbfmodel のダウンロードをスキップします"
+    echo "  顔の特徴点（landmarks）と、特徴点による目の開閉・頭部姿勢・品質スコアの要素は無効になります"
+else
+    echo "→ lbfmodel をダウンロード中..."
+    if command -v curl &> /dev/null; then
+        curl -fL --progress-bar -o "${LBFMODEL_FILE}" "${LBFMODEL_URL}"
+    elif command -v wget &> /dev/null; then
+        wget --show-progress -O "${LBFMODEL_FILE}" "${LBFMODEL_URL}"
+    else
+        echo "エラー: curl または wget が必要です"
+        exit 1
+    fi
+    echo "✓ lbfmodel のダウンロードが完了しました"
+fi
+
 # チェックサム検証
 echo ""
 echo "→ チェックサムの検証中..."
-if command -v sha256sum &> /dev/null; then
-    ACTUAL_SHA256=$(sha256sum "${CAFFEMODEL_FILE}" | awk '{print $1}')
+if command -v sha256sum &> /dev/null || command -v shasum &> /dev/null; then
+    ACTUAL_SHA256=$(sha256 "${CAFFEMODEL_FILE}")
     if [ "${ACTUAL_SHA256}" = "${CAFFEMODEL_SHA256}" ]; then
-        echo "✓ チェックサムが一致しました"
+        echo "✓ caffemodel のチェックサムが一致しました"
     else
-        echo "⚠ チェックサムが一致しません（ファイルが破損している可能性があります）"
+        echo "⚠ caffemodel のチェックサムが一致しません（ファイルが破損している可能性があります）"
         echo "  期待値: ${CAFFEMODEL_SHA256}"
         echo "  実際値: ${ACTUAL_SHA256}"
         echo "  モデルは使用可能ですが、再ダウンロードを推奨します"
     fi
-elif command -v shasum &> /dev/null; then
-    ACTUAL_SHA256=$(shasum -a 256 "${CAFFEMODEL_FILE}" | awk '{print $1}')
-    if [ "${ACTUAL_SHA256}" = "${CAFFEMODEL_SHA256}" ]; then
-        echo "✓ チェックサムが一致しました"
-    else
-        echo "⚠ チェックサムが一致しません"
+
+    # lbfmodel は検証できない場合に使用しない（Dockerイメージに焼き込まれるため）
+    if [ -f "${LBFMODEL_FILE}" ] && [ -n "${LBFMODEL_SHA256}" ]; then
+        ACTUAL_SHA256=$(sha256 "${LBFMODEL_FILE}")
+        if [ "${ACTUAL_SHA256}" = "${LBFMODEL_SHA256}" ]; then
+            echo "✓ lbfmodel のチェックサムが一致しました"
+        else
+            echo "エラー: lbfmodel のチェックサムが一致しません。ファイルを削除します"
+            echo "  期待値: ${LBFMODEL_SHA256}"
+            echo "  実際値: ${ACTUAL_SHA256}"
+            rm -f "${LBFMODEL_FILE}"
+            exit 1
+        fi
     fi
 else
     echo "⚠ sha256sum/shasum が見つかりません。チェックサム検証をスキップします"
+    if [ -f "${LBFMODEL_FILE}" ] && [ -n "${LBFMODEL_SHA256}" ]; then
+        echo "エラー: lbfmodel はチェックサムを検証できないため削除します"
+        rm -f "${LBFMODEL_FILE}"
+        exit 1
+    fi
 fi
 
 # ファイルサイズの確認
@@ -93,4 +141,4 @@ echo ""
 ls -lh "${MODELS_DIR}/"
 echo ""
 echo "✓ モデルのセットアップが完了しました"
-echo "  アプリケーション起動時に自動的にDNNモデルが使用されます"
+echo "  アプリケーション起動時に自動的にDNNモデルとLBF Facemarkモデルが使用されます"